@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/webdevops/fenecon-exporter/config"
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+const (
+	BackfillCommand = "backfill"
+)
+
+var (
+	backfillOpts config.BackfillOpts
+)
+
+func initBackfillCommand() {
+	_, err := argparser.AddCommand(
+		BackfillCommand,
+		"Backfill historic data",
+		"Queries the history of the summary (_sum) channels and writes OpenMetrics (for promtool tsdb create-blocks-from openmetrics)",
+		&backfillOpts,
+	)
+	if err != nil {
+		panic(err)
+	}
+	argparser.SubcommandsOptional = true
+}
+
+// runBackfill runs the backfill command and writes the metrics as OpenMetrics to stdout or the output file
+func runBackfill() error {
+	location, err := time.LoadLocation(backfillOpts.Timezone)
+	if err != nil {
+		return fmt.Errorf(`invalid timezone "%v": %w`, backfillOpts.Timezone, err)
+	}
+
+	opts := fenecon.BackfillOpts{
+		Resolution: backfillOpts.Resolution,
+		Location:   location,
+	}
+
+	opts.From, err = parseBackfillTime(backfillOpts.From, location, false)
+	if err != nil {
+		return err
+	}
+
+	opts.To = time.Now()
+	if backfillOpts.To != "" {
+		opts.To, err = parseBackfillTime(backfillOpts.To, location, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !opts.From.Before(opts.To) {
+		return fmt.Errorf(`from "%v" must be before to "%v"`, opts.From, opts.To)
+	}
+
+	target, err := resolveProbeTarget(backfillOpts.Target, backfillOpts.Module, "")
+	if err != nil {
+		return err
+	}
+
+	prober, err := newFeneconProber(context.Background(), prometheus.NewRegistry(), logger, target.module)
+	if err != nil {
+		return err
+	}
+
+	logger.Info(`starting backfill`, slog.String("target", target.name), slog.Time("from", opts.From), slog.Time("to", opts.To))
+	families, err := prober.Backfill(target.target, opts)
+	if err != nil {
+		return err
+	}
+
+	var output io.Writer = os.Stdout
+	if backfillOpts.Output != "" {
+		file, err := os.Create(backfillOpts.Output)
+		if err != nil {
+			return fmt.Errorf(`unable to create output file "%v": %w`, backfillOpts.Output, err)
+		}
+		defer file.Close() // nolint:errcheck
+		output = file
+	}
+
+	encoder := expfmt.NewEncoder(output, expfmt.NewFormat(expfmt.TypeOpenMetrics))
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return err
+		}
+	}
+
+	// writes "# EOF"
+	if closer, ok := encoder.(expfmt.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+
+	logger.Info(`finished backfill`, slog.Int("metrics", len(families)))
+	return nil
+}
+
+// parseBackfillTime parses a date (start of day or end of day if endOfDay is set) or a RFC3339 timestamp
+func parseBackfillTime(val string, location *time.Location, endOfDay bool) (time.Time, error) {
+	if ret, err := time.ParseInLocation(time.DateOnly, val, location); err == nil {
+		if endOfDay {
+			ret = ret.AddDate(0, 0, 1).Add(-time.Millisecond)
+		}
+		return ret, nil
+	}
+
+	ret, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(`unable to parse time "%v" (expected date or RFC3339 timestamp): %w`, val, err)
+	}
+	return ret, nil
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	yaml "go.yaml.in/yaml/v3"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+const (
+	DefaultModuleName = "default"
+)
+
+type (
+	Config struct {
+		Modules map[string]*Module `yaml:"modules"`
+		Targets []*Target          `yaml:"targets"`
+	}
+
+	Module struct {
+		Transport string `yaml:"transport"`
+
+		Websocket struct {
+			Port int `yaml:"port"`
+		} `yaml:"websocket"`
+
+		Auth ModuleAuth `yaml:"auth"`
+
+		Request struct {
+			Timeout          time.Duration `yaml:"timeout"`
+			Parallel         int           `yaml:"parallel"`
+			RetryCount       int           `yaml:"retries"`
+			RetryWaitTime    time.Duration `yaml:"waitTime"`
+			RetryMaxWaitTime time.Duration `yaml:"maxWaitTime"`
+		} `yaml:"request"`
+
+		// enabled query groups (eg. sum, ess, charger, meter, evcs)
+		Queries []string `yaml:"queries"`
+
+		Metrics struct {
+			// legacy or none
+			Compat string `yaml:"compat"`
+		} `yaml:"metrics"`
+	}
+
+	ModuleAuth struct {
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	}
+
+	Target struct {
+		Name   string `yaml:"name"`
+		Url    string `yaml:"url"`
+		Module string `yaml:"module,omitempty"`
+
+		// poll target in background
+		Poll bool `yaml:"poll,omitempty"`
+
+		// per target overrides
+		Auth      *ModuleAuth `yaml:"auth,omitempty"`
+		Transport string      `yaml:"transport,omitempty"`
+	}
+
+	configFile struct {
+		Modules map[string]yaml.Node `yaml:"modules"`
+		Targets []*Target            `yaml:"targets"`
+	}
+)
+
+// NewConfig builds the configuration from the cli options (without config file)
+func NewConfig(opts Opts) *Config {
+	return &Config{
+		Modules: map[string]*Module{
+			DefaultModuleName: NewModuleFromOpts(opts),
+		},
+		Targets: []*Target{},
+	}
+}
+
+// NewModuleFromOpts builds the default module from the cli options
+func NewModuleFromOpts(opts Opts) *Module {
+	module := Module{}
+	module.Transport = opts.Fenecon.Transport
+	module.Websocket.Port = opts.Fenecon.Websocket.Port
+	module.Auth.Username = opts.Fenecon.Auth.Username
+	module.Auth.Password = opts.Fenecon.Auth.Password
+	module.Request.Timeout = opts.Fenecon.Request.Timeout
+	module.Request.Parallel = opts.Fenecon.Request.Parallel
+	module.Request.RetryCount = opts.Fenecon.Request.RetryCount
+	module.Request.RetryWaitTime = opts.Fenecon.Request.RetryWaitTime
+	module.Request.RetryMaxWaitTime = opts.Fenecon.Request.RetryMaxWaitTime
+	module.Queries = slices.Clone(opts.Fenecon.Queries)
+	module.Metrics.Compat = opts.Fenecon.Metrics.Compat
+	return &module
+}
+
+// LoadConfigFile loads and validates the config file, all module settings default to the cli options
+func LoadConfigFile(path string, opts Opts) (*Config, error) {
+	// #nosec G304 -- path is passed by the user
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to read config file "%v": %w`, path, err)
+	}
+
+	file := configFile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf(`unable to parse config file "%v": %w`, path, err)
+	}
+
+	config := NewConfig(opts)
+	for name, node := range file.Modules {
+		module := NewModuleFromOpts(opts)
+		if err := decodeNode(&node, module); err != nil {
+			return nil, fmt.Errorf(`unable to parse module "%v": %w`, name, err)
+		}
+		config.Modules[name] = module
+	}
+
+	if file.Targets != nil {
+		config.Targets = file.Targets
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf(`invalid config file "%v": %w`, path, err)
+	}
+
+	return config, nil
+}
+
+// decodeNode decodes the node with unknown fields as error (Node.Decode ignores KnownFields of the file decoder)
+func decodeNode(node *yaml.Node, dest interface{}) error {
+	content, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(dest); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// Validate validates all modules and targets
+func (c *Config) Validate() error {
+	for name, module := range c.Modules {
+		if err := module.Validate(); err != nil {
+			return fmt.Errorf(`module "%v": %w`, name, err)
+		}
+	}
+
+	names := map[string]bool{}
+	for i, target := range c.Targets {
+		if target.Name == "" {
+			return fmt.Errorf(`target #%v: name is missing`, i)
+		}
+
+		if names[target.Name] {
+			return fmt.Errorf(`target "%v": duplicate name`, target.Name)
+		}
+		names[target.Name] = true
+
+		if target.Url == "" {
+			return fmt.Errorf(`target "%v": url is missing`, target.Name)
+		}
+
+		if _, err := c.Module(target.Module); err != nil {
+			return fmt.Errorf(`target "%v": %w`, target.Name, err)
+		}
+
+		if target.Transport != "" {
+			if err := validateTransport(target.Transport); err != nil {
+				return fmt.Errorf(`target "%v": %w`, target.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Module returns the module by name, empty name returns the default module
+func (c *Config) Module(name string) (*Module, error) {
+	if name == "" {
+		name = DefaultModuleName
+	}
+
+	if module, exists := c.Modules[name]; exists {
+		return module, nil
+	}
+
+	return nil, fmt.Errorf(`module "%v" not found`, name)
+}
+
+// Target returns the target by name or url, nil if not found
+func (c *Config) Target(name string) *Target {
+	for _, target := range c.Targets {
+		if target.Name == name {
+			return target
+		}
+	}
+
+	for _, target := range c.Targets {
+		if target.Url == name {
+			return target
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the module settings
+func (m *Module) Validate() error {
+	if err := validateTransport(m.Transport); err != nil {
+		return err
+	}
+
+	if m.Request.Timeout <= 0 {
+		return errors.New(`request.timeout must be greater than 0`)
+	}
+
+	if m.Request.Parallel <= 0 {
+		return errors.New(`request.parallel must be greater than 0`)
+	}
+
+	if m.Request.RetryCount < 0 {
+		return errors.New(`request.retries must not be negative`)
+	}
+
+	if m.Websocket.Port < 0 || m.Websocket.Port > 65535 {
+		return fmt.Errorf(`websocket.port "%v" is invalid`, m.Websocket.Port)
+	}
+
+	if err := fenecon.ValidateQueryGroups(m.Queries); err != nil {
+		return err
+	}
+
+	switch m.Metrics.Compat {
+	case "", fenecon.MetricsCompatLegacy, fenecon.MetricsCompatNone:
+	default:
+		return fmt.Errorf(`metrics.compat "%v" is invalid`, m.Metrics.Compat)
+	}
+
+	return nil
+}
+
+func validateTransport(val string) error {
+	switch val {
+	case "", fenecon.TransportRest, fenecon.TransportWebsocket:
+		return nil
+	default:
+		return fmt.Errorf(`unsupported transport "%v"`, val)
+	}
+}
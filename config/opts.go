@@ -16,7 +16,32 @@ type (
 			Time   bool   `long:"log.time"     env:"LOG_TIME"    description:"Show log time"`
 		}
 
+		// config file
+		Config struct {
+			File string `long:"config.file"  env:"CONFIG_FILE"  description:"Path to config file with modules and targets (reloaded on SIGHUP)"`
+		}
+
 		Fenecon struct {
+			Queries []string `long:"fenecon.query"  env:"FENECON_QUERY"  env-delim:" "  description:"Enabled query groups" default:"sum" default:"ess" default:"charger" default:"meter"` // nolint:staticcheck // multiple defaults are ok
+
+			Transport string `long:"fenecon.transport"  env:"FENECON_TRANSPORT"  description:"Transport for fetching channels (rest: REST-Api, websocket: JSON-RPC websocket)" choice:"rest" choice:"websocket" default:"rest"` // nolint:staticcheck // multiple choices are ok
+
+			Metrics struct {
+				Compat string `long:"fenecon.metrics.compat"  env:"FENECON_METRICS_COMPAT"  description:"Metrics compatibility (legacy: export legacy metrics in device units in addition to metrics in base units, none: only metrics in base units)" choice:"legacy" choice:"none" default:"legacy"` // nolint:staticcheck // multiple choices are ok
+			}
+
+			Mapping struct {
+				File string `long:"fenecon.mapping.file"  env:"FENECON_MAPPING_FILE"  description:"Path to mapping file with additional channel to metric mappings"`
+			}
+
+			Components struct {
+				CacheTtl time.Duration `long:"fenecon.components.ttl"  env:"FENECON_COMPONENTS_TTL"  description:"Cache duration of component configuration (alias and factory for fenecon_component_info) and edge info (_meta and _host for fenecon_info and host metrics), 0 disables getEdgeConfig and fetches the edge info on every probe"  default:"1h"`
+			}
+
+			Websocket struct {
+				Port int `long:"fenecon.websocket.port"  env:"FENECON_WEBSOCKET_PORT"  description:"Port of OpenEMS JSON-RPC websocket"  default:"8085"`
+			}
+
 			Request struct {
 				Timeout          time.Duration `long:"fenecon.request.timeout"       env:"FENECON_REQUEST_TIMEOUT"       description:"Request timeout"              default:"10s"`
 				Parallel         int           `long:"fenecon.request.parallel"      env:"FENECON_REQUEST_PARALLEL"      description:"Number of parallel requests"  default:"1"`
@@ -31,6 +56,105 @@ type (
 			}
 		}
 
+		// background polling
+		Poll struct {
+			Targets    []string      `long:"poll.target"    env:"POLL_TARGET"    env-delim:" "  description:"Target which is polled in background and served from cache on /metrics and /probe (eg. http://fenecon, can be specified multiple times)"`
+			Interval   time.Duration `long:"poll.interval"  env:"POLL_INTERVAL"  description:"Background polling interval"                                   default:"30s"`
+			Timeout    time.Duration `long:"poll.timeout"   env:"POLL_TIMEOUT"   description:"Background polling timeout"                                    default:"25s"`
+			StaleAfter time.Duration `long:"poll.stale"     env:"POLL_STALE"     description:"Drop cached metrics if there was no successful poll within this duration" default:"5m"`
+		}
+
+		// energy aggregation per calendar period
+		Energy struct {
+			Period struct {
+				StateFile     string        `long:"energy.period.state"           env:"ENERGY_PERIOD_STATE"           description:"Path to state file for energy per day/month/year (enables aggregation, eg. /data/energy.json)"`
+				StateInterval time.Duration `long:"energy.period.state.interval"  env:"ENERGY_PERIOD_STATE_INTERVAL"  description:"Min interval between writes of the state file (new periods are written immediately)"  default:"5m"`
+				Timezone      string        `long:"energy.period.timezone"        env:"ENERGY_PERIOD_TIMEZONE"        description:"Timezone for start of day/month/year (eg. Europe/Berlin)"  default:"Local"`
+				MaxGap        time.Duration `long:"energy.period.gap.max"         env:"ENERGY_PERIOD_GAP_MAX"         description:"Max time between two probes of a target for assigning the energy in between to a new period (energy of longer gaps, eg. downtime, is not assigned)"  default:"15m"`
+			}
+		}
+
+		// cost accounting
+		Cost struct {
+			TariffFile string        `long:"cost.tariff.file"  env:"COST_TARIFF_FILE"  description:"Path to tariff file with grid buy price and feed-in compensation (enables cost accounting, eg. /data/tariff.yaml)"`
+			StateFile  string        `long:"cost.state"        env:"COST_STATE"        description:"Path to state file for accumulated costs (eg. /data/cost.json)"`
+			Timezone   string        `long:"cost.timezone"     env:"COST_TIMEZONE"     description:"Timezone for time of day windows of the tariff (eg. Europe/Berlin)"  default:"Local"`
+			MaxGap     time.Duration `long:"cost.gap.max"      env:"COST_GAP_MAX"      description:"Max time between two probes of a target for pricing the energy in between (energy of longer gaps, eg. downtime, is not priced)"  default:"15m"`
+		}
+
+		// InfluxDB push
+		Influx struct {
+			Push struct {
+				Url      string        `long:"influx.push.url"       env:"INFLUX_PUSH_URL"       description:"InfluxDB write url for pushing metrics of background polled targets as line protocol (enables push, eg. http://influxdb:8086/api/v2/write?org=home&bucket=fenecon)"`
+				Token    string        `long:"influx.push.token"     env:"INFLUX_PUSH_TOKEN"     description:"InfluxDB API token" json:"-"`
+				Interval time.Duration `long:"influx.push.interval"  env:"INFLUX_PUSH_INTERVAL"  description:"InfluxDB push interval"  default:"30s"`
+				Timeout  time.Duration `long:"influx.push.timeout"   env:"INFLUX_PUSH_TIMEOUT"   description:"InfluxDB push timeout"   default:"10s"`
+			}
+		}
+
+		// MQTT publishing
+		Mqtt struct {
+			Url       string        `long:"mqtt.url"        env:"MQTT_URL"        description:"MQTT broker url for publishing metrics of background polled targets (enables publishing, eg. tcp://mosquitto:1883)"`
+			ClientId  string        `long:"mqtt.clientid"   env:"MQTT_CLIENTID"   description:"MQTT client id"  default:"fenecon-exporter"`
+			Username  string        `long:"mqtt.username"   env:"MQTT_USERNAME"   description:"MQTT username"`
+			Password  string        `long:"mqtt.password"   env:"MQTT_PASSWORD"   description:"MQTT password" json:"-"`
+			Topic     string        `long:"mqtt.topic"      env:"MQTT_TOPIC"      description:"MQTT topic prefix (values are published to <prefix>/<target>/<module>/<metric>, availability to <prefix>/status)"  default:"fenecon"`
+			Interval  time.Duration `long:"mqtt.interval"   env:"MQTT_INTERVAL"   description:"MQTT publish interval"  default:"30s"`
+			Timeout   time.Duration `long:"mqtt.timeout"    env:"MQTT_TIMEOUT"    description:"MQTT connect and publish timeout"  default:"10s"`
+			Discovery struct {
+				Prefix string `long:"mqtt.discovery.prefix"  env:"MQTT_DISCOVERY_PREFIX"  description:"Home Assistant MQTT discovery prefix (empty disables discovery)"  default:"homeassistant"`
+			}
+		}
+
+		// OpenTelemetry OTLP export
+		Otlp struct {
+			Endpoint string            `long:"otlp.endpoint"  env:"OTLP_ENDPOINT"  description:"OTLP endpoint for exporting metrics of background polled targets (enables export, eg. otel-collector:4317 for grpc, otel-collector:4318 for http)"`
+			Protocol string            `long:"otlp.protocol"  env:"OTLP_PROTOCOL"  description:"OTLP protocol" choice:"grpc" choice:"http" default:"grpc"` // nolint:staticcheck // multiple choices are ok
+			Insecure bool              `long:"otlp.insecure"  env:"OTLP_INSECURE"  description:"Disable TLS for OTLP export"`
+			Headers  map[string]string `long:"otlp.header"    env:"OTLP_HEADER"    env-delim:","  description:"Additional OTLP headers (eg. authorization:Bearer xyz, can be specified multiple times)" json:"-"`
+			Interval time.Duration     `long:"otlp.interval"  env:"OTLP_INTERVAL"  description:"OTLP export interval"  default:"30s"`
+			Timeout  time.Duration     `long:"otlp.timeout"   env:"OTLP_TIMEOUT"   description:"OTLP export timeout"   default:"10s"`
+		}
+
+		// Prometheus remote write
+		RemoteWrite struct {
+			Url      string            `long:"remotewrite.url"       env:"REMOTEWRITE_URL"       description:"Prometheus remote write url for pushing metrics of background polled targets (enables remote write, eg. https://prometheus/api/v1/write)"`
+			Interval time.Duration     `long:"remotewrite.interval"  env:"REMOTEWRITE_INTERVAL"  description:"Remote write interval"  default:"30s"`
+			Timeout  time.Duration     `long:"remotewrite.timeout"   env:"REMOTEWRITE_TIMEOUT"   description:"Remote write request timeout"  default:"30s"`
+			Labels   map[string]string `long:"remotewrite.label"     env:"REMOTEWRITE_LABEL"     env-delim:","  description:"External label added to all series (eg. site:home, can be specified multiple times)"`
+
+			Auth struct {
+				Username    string `long:"remotewrite.auth.username"     env:"REMOTEWRITE_AUTH_USERNAME"     description:"Username for remote write (basic auth)"`
+				Password    string `long:"remotewrite.auth.password"     env:"REMOTEWRITE_AUTH_PASSWORD"     description:"Password for remote write (basic auth)" json:"-"`
+				BearerToken string `long:"remotewrite.auth.bearertoken"  env:"REMOTEWRITE_AUTH_BEARERTOKEN"  description:"Bearer token for remote write" json:"-"`
+			}
+
+			Buffer struct {
+				Path    string `long:"remotewrite.buffer.path"     env:"REMOTEWRITE_BUFFER_PATH"     description:"Directory for buffering write requests during outages (buffered in memory if not set, eg. /data/remotewrite)"`
+				MaxSize int64  `long:"remotewrite.buffer.maxsize"  env:"REMOTEWRITE_BUFFER_MAXSIZE"  description:"Max size of buffer in MB, oldest write requests are dropped"  default:"100"`
+			}
+
+			Backoff struct {
+				Min time.Duration `long:"remotewrite.backoff.min"  env:"REMOTEWRITE_BACKOFF_MIN"  description:"Min backoff for retries of failed write requests"  default:"1s"`
+				Max time.Duration `long:"remotewrite.backoff.max"  env:"REMOTEWRITE_BACKOFF_MAX"  description:"Max backoff for retries of failed write requests"  default:"5m"`
+			}
+		}
+
+		// LAN discovery
+		Discovery struct {
+			Networks []string      `long:"discovery.network"    env:"DISCOVERY_NETWORK"    env-delim:" "  description:"Network which is scanned in background for OpenEMS systems, discovered systems are added as targets (eg. 192.168.1.0/24, can be specified multiple times)"`
+			Interval time.Duration `long:"discovery.interval"   env:"DISCOVERY_INTERVAL"   description:"Background discovery interval"  default:"1h"`
+			Poll     bool          `long:"discovery.poll"       env:"DISCOVERY_POLL"       description:"Poll discovered targets in background"`
+			RestPort int           `long:"discovery.port.rest"  env:"DISCOVERY_PORT_REST"  description:"Port of OpenEMS REST-Api"  default:"80"`
+			Timeout  time.Duration `long:"discovery.timeout"    env:"DISCOVERY_TIMEOUT"    description:"Connect and request timeout per address"  default:"2s"`
+			Parallel int           `long:"discovery.parallel"   env:"DISCOVERY_PARALLEL"   description:"Number of addresses scanned in parallel"  default:"32"`
+
+			Auth struct {
+				Username string `long:"discovery.auth.username"  env:"DISCOVERY_AUTH_USERNAME"  description:"Username for login on systems which require authentication (only sent if a password is set)"`
+				Password string `long:"discovery.auth.password"  env:"DISCOVERY_AUTH_PASSWORD"  description:"Password for login on systems which require authentication (empty: systems are only identified anonymously)" json:"-"`
+			}
+		}
+
 		// general options
 		Server struct {
 			// general options
@@ -39,6 +163,31 @@ type (
 			WriteTimeout time.Duration `long:"server.timeout.write"     env:"SERVER_TIMEOUT_WRITE"  description:"Server write timeout"  default:"60s"`
 		}
 	}
+
+	// BackfillOpts are the options of the backfill command
+	BackfillOpts struct {
+		Target     string        `long:"target"      description:"Target (name of configured target or url, eg. http://fenecon)"  required:"true"`
+		Module     string        `long:"module"      description:"Module (default: module of configured target or default module)"`
+		From       string        `long:"from"        description:"Start of backfill (date or RFC3339 timestamp, eg. 2024-10-01)"  required:"true"`
+		To         string        `long:"to"          description:"End of backfill (date (inclusive) or RFC3339 timestamp, default: now)"`
+		Resolution time.Duration `long:"resolution"  description:"Resolution of historic data (multiple of one minute)"  default:"5m"`
+		Timezone   string        `long:"timezone"    description:"Timezone for dates and historic data (eg. Europe/Berlin)"  default:"UTC"`
+		Output     string        `long:"output"      description:"Path to output file (default: stdout)"`
+	}
+
+	// DiscoverOpts are the options of the discover command
+	DiscoverOpts struct {
+		Networks []string      `long:"network"    description:"Network to scan (eg. 192.168.1.0/24 or single address, can be specified multiple times)"  required:"true"`
+		RestPort int           `long:"port.rest"  description:"Port of OpenEMS REST-Api"  default:"80"`
+		Timeout  time.Duration `long:"timeout"    description:"Connect and request timeout per address"  default:"2s"`
+		Parallel int           `long:"parallel"   description:"Number of addresses scanned in parallel"  default:"32"`
+		Format   string        `long:"format"     description:"Output format (yaml: targets for config file)" choice:"text" choice:"json" choice:"yaml" default:"text"` // nolint:staticcheck // multiple choices are ok
+
+		Auth struct {
+			Username string `long:"auth.username"  description:"Username for login on systems which require authentication (only sent if a password is set)"`
+			Password string `long:"auth.password"  description:"Password for login on systems which require authentication (empty: systems are only identified anonymously)" json:"-"`
+		}
+	}
 )
 
 func (o *Opts) GetJson() []byte {
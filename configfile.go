@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/fenecon-exporter/config"
+)
+
+var (
+	configFile     *config.Config
+	configFileLock sync.RWMutex
+
+	// loaded config without discovered targets
+	configFileLoaded *config.Config
+
+	configReloadSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fenecon_config_last_reload_successful",
+			Help: "Fenecon config file last reload successful",
+		},
+	)
+)
+
+func initConfigFile() {
+	prometheus.MustRegister(configReloadSuccess)
+
+	conf, err := loadConfigFile()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+	setConfigFile(conf)
+
+	if Opts.Config.File != "" {
+		logger.Info(`loaded config file`, slog.String("path", Opts.Config.File), slog.Int("modules", len(conf.Modules)), slog.Int("targets", len(conf.Targets)))
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGHUP)
+		go func() {
+			for range signals {
+				reloadConfigFile()
+			}
+		}()
+	}
+}
+
+func loadConfigFile() (*config.Config, error) {
+	if Opts.Config.File == "" {
+		conf := config.NewConfig(Opts)
+		return conf, conf.Validate()
+	}
+
+	return config.LoadConfigFile(Opts.Config.File, Opts)
+}
+
+func reloadConfigFile() {
+	logger.Info(`reloading config file`, slog.String("path", Opts.Config.File))
+
+	conf, err := loadConfigFile()
+	if err != nil {
+		logger.Error(`failed to reload config file, keeping previous config`, slog.Any("error", err))
+		configReloadSuccess.Set(0)
+		return
+	}
+
+	setConfigFile(conf)
+
+	if poller != nil {
+		poller.SetTargets(pollTargets())
+	}
+
+	logger.Info(`reloaded config file`, slog.Int("modules", len(conf.Modules)), slog.Int("targets", len(conf.Targets)))
+}
+
+func setConfigFile(conf *config.Config) {
+	configFileLock.Lock()
+	defer configFileLock.Unlock()
+	configFileLoaded = conf
+	configFile = withDiscoveredTargets(conf)
+	configReloadSuccess.Set(1)
+}
+
+func getConfigFile() *config.Config {
+	configFileLock.RLock()
+	defer configFileLock.RUnlock()
+	return configFile
+}
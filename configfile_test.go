@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "empty", content: "\n"},
+		{name: "modules and targets", content: "modules:\n  websocket:\n    transport: websocket\n    request:\n      timeout: 10s\ntargets:\n  - name: home\n    url: http://fenecon\n    module: websocket\n"},
+		{name: "unknown field", content: "module:\n  websocket: {}\n", err: "field module not found"},
+		{name: "unknown module field", content: "modules:\n  websocket:\n    transprt: websocket\n", err: `unable to parse module "websocket"`},
+		{name: "unknown target field", content: "targets:\n  - name: home\n    uri: http://fenecon\n", err: "field uri not found"},
+		{name: "invalid module transport", content: "modules:\n  mqtt:\n    transport: mqtt\n", err: `module "mqtt": unsupported transport "mqtt"`},
+		{name: "invalid module timeout", content: "modules:\n  fast:\n    request:\n      timeout: 0s\n", err: "request.timeout must be greater than 0"},
+		{name: "invalid module query group", content: "modules:\n  sum:\n    queries: [summary]\n", err: `module "sum"`},
+		{name: "invalid module metrics compat", content: "modules:\n  new:\n    metrics:\n      compat: old\n", err: `metrics.compat "old" is invalid`},
+		{name: "target without name", content: "targets:\n  - url: http://fenecon\n", err: "target #0: name is missing"},
+		{name: "target without url", content: "targets:\n  - name: home\n", err: `target "home": url is missing`},
+		{name: "duplicate target", content: "targets:\n  - name: home\n    url: http://a\n  - name: home\n    url: http://b\n", err: `target "home": duplicate name`},
+		{name: "target with unknown module", content: "targets:\n  - name: home\n    url: http://fenecon\n    module: websocket\n", err: `target "home": module "websocket" not found`},
+		{name: "target with invalid transport", content: "targets:\n  - name: home\n    url: http://fenecon\n    transport: mqtt\n", err: `target "home": unsupported transport "mqtt"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setupTest(t, "")
+			Opts.Config.File = writeTestFile(t, "config.yaml", test.content)
+
+			_, err := loadConfigFile()
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileModuleDefaults(t *testing.T) {
+	setupTest(t, "modules:\n  websocket:\n    transport: websocket\n", "--fenecon.request.timeout=7s", "--fenecon.auth.password=secret")
+
+	// module settings which are not set default to the cli options, the default module is built from the cli options
+	for _, name := range []string{"", "default", "websocket"} {
+		module, err := getConfigFile().Module(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if module.Request.Timeout != 7*time.Second || module.Auth.Password != "secret" {
+			t.Errorf("module %q: expected timeout and password of cli options, got %v and %q", name, module.Request.Timeout, module.Auth.Password)
+		}
+	}
+
+	if module, _ := getConfigFile().Module("websocket"); module.Transport != "websocket" {
+		t.Errorf("expected transport websocket, got %v", module.Transport)
+	}
+	if module, _ := getConfigFile().Module("default"); module.Transport != "rest" {
+		t.Errorf("expected transport rest of default module, got %v", module.Transport)
+	}
+}
+
+func TestReloadConfigFile(t *testing.T) {
+	setupTest(t, "targets:\n  - name: home\n    url: http://127.0.0.1:1\n    poll: true\n", "--poll.interval=1h", "--fenecon.request.retries=0")
+	poller.SetTargets(pollTargets())
+
+	reloadSuccess := func() float64 {
+		metric := dto.Metric{}
+		if err := configReloadSuccess.Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		return metric.GetGauge().GetValue()
+	}
+
+	if poller.Target("home") == nil {
+		t.Fatal("expected home to be polled")
+	}
+
+	// changed config file (as on SIGHUP), polling follows the targets
+	writeConfig := func(content string) {
+		if err := os.WriteFile(Opts.Config.File, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig("modules:\n  websocket:\n    transport: websocket\ntargets:\n  - name: home\n    url: http://127.0.0.1:1\n  - name: office\n    url: http://127.0.0.1:2\n    module: websocket\n    poll: true\n")
+	reloadConfigFile()
+
+	if reloadSuccess() != 1 {
+		t.Error("expected successful reload")
+	}
+	if getConfigFile().Target("office") == nil {
+		t.Error("expected office after reload")
+	}
+	if poller.Target("home") != nil || poller.Target("office") == nil {
+		t.Error("expected polling of office instead of home after reload")
+	}
+
+	// invalid config file, previous config is kept
+	writeConfig("targets:\n  - name: office\n    url: http://127.0.0.1:2\n    module: unknown\n")
+	reloadConfigFile()
+
+	if reloadSuccess() != 0 {
+		t.Error("expected failed reload")
+	}
+	if target := getConfigFile().Target("office"); target == nil || target.Module != "websocket" {
+		t.Errorf("expected previous config after failed reload, got %+v", target)
+	}
+	if poller.Target("office") == nil {
+		t.Error("expected polling of office after failed reload")
+	}
+}
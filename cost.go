@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+var (
+	costAccountant *fenecon.CostAccountant
+)
+
+func initCostAccountant() {
+	if Opts.Cost.TariffFile == "" {
+		return
+	}
+
+	location, err := time.LoadLocation(Opts.Cost.Timezone)
+	if err != nil {
+		logger.Fatal(`invalid cost timezone`, slog.String("timezone", Opts.Cost.Timezone), slog.Any("error", err))
+	}
+
+	tariff, err := fenecon.LoadTariffFile(Opts.Cost.TariffFile, location)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	costAccountant, err = fenecon.NewCostAccountant(Opts.Cost.StateFile, tariff, location, Opts.Cost.MaxGap)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	if Opts.Cost.StateFile == "" {
+		logger.Warn(`cost state file not set, accumulated costs are lost on restart`)
+	}
+
+	logger.Info(`enabled cost accounting`, slog.String("tariff", Opts.Cost.TariffFile), slog.String("currency", tariff.Currency), slog.String("timezone", location.String()))
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	yaml "go.yaml.in/yaml/v3"
+
+	"github.com/webdevops/fenecon-exporter/config"
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+const (
+	DiscoverCommand = "discover"
+)
+
+var (
+	discoverOpts config.DiscoverOpts
+
+	// targets found by background discovery, merged into the config (see withDiscoveredTargets)
+	discoveredTargets     []*config.Target
+	discoveredTargetsLock sync.RWMutex
+
+	discoverySystems = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fenecon_discovery_systems",
+			Help: "Fenecon number of OpenEMS systems found by last discovery",
+		},
+	)
+
+	discoveryLastRun = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fenecon_discovery_last_run_timestamp_seconds",
+			Help: "Fenecon timestamp of last discovery",
+		},
+	)
+)
+
+func initDiscoverCommand() {
+	_, err := argparser.AddCommand(
+		DiscoverCommand,
+		"Discover OpenEMS systems",
+		"Scans the networks for OpenEMS systems (REST-Api or JSON-RPC websocket) and reports edge id, firmware version and reachable apis",
+		&discoverOpts,
+	)
+	if err != nil {
+		panic(err)
+	}
+	argparser.SubcommandsOptional = true
+}
+
+// newDiscoveryOpts builds the discovery options, the websocket port is taken from the default module,
+// the auth of the default module is not used (credentials are only sent if configured for discovery)
+func newDiscoveryOpts(networks []string, restPort int, timeout time.Duration, parallel int, username, password string) (fenecon.DiscoveryOpts, error) {
+	module, err := getConfigFile().Module("")
+	if err != nil {
+		return fenecon.DiscoveryOpts{}, err
+	}
+
+	if restPort <= 0 || restPort > 65535 {
+		return fenecon.DiscoveryOpts{}, fmt.Errorf(`rest port "%v" is invalid`, restPort)
+	}
+
+	return fenecon.DiscoveryOpts{
+		Networks:      networks,
+		RestPort:      restPort,
+		WebsocketPort: module.Websocket.Port,
+		Username:      username,
+		Password:      password,
+		UserAgent:     UserAgent + gitTag,
+		Timeout:       timeout,
+		Parallel:      parallel,
+	}, nil
+}
+
+// runDiscover runs the discover command and writes the found systems to stdout
+func runDiscover() error {
+	opts, err := newDiscoveryOpts(discoverOpts.Networks, discoverOpts.RestPort, discoverOpts.Timeout, discoverOpts.Parallel, discoverOpts.Auth.Username, discoverOpts.Auth.Password)
+	if err != nil {
+		return err
+	}
+
+	logger.Info(`starting discovery`, slog.Any("networks", opts.Networks))
+	systems, err := fenecon.Discover(context.Background(), logger, opts)
+	if err != nil {
+		return err
+	}
+	logger.Info(`finished discovery`, slog.Int("systems", len(systems)))
+
+	switch discoverOpts.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(systems)
+	case "yaml":
+		file := struct {
+			Targets []*config.Target `yaml:"targets"`
+		}{
+			Targets: newDiscoveredTargets(systems, nil, false),
+		}
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		return encoder.Encode(file)
+	default:
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "ADDRESS\tEDGE ID\tVERSION\tAPI\tURL") // nolint:errcheck
+		for _, system := range systems {
+			fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\n", system.Address, system.EdgeId, system.Version, strings.Join(system.Transports, ","), system.Url) // nolint:errcheck
+		}
+		return writer.Flush()
+	}
+}
+
+// initDiscovery starts the background discovery, found systems are added as targets
+func initDiscovery() {
+	if len(Opts.Discovery.Networks) == 0 {
+		return
+	}
+
+	prometheus.MustRegister(discoverySystems, discoveryLastRun)
+
+	opts, err := newDiscoveryOpts(Opts.Discovery.Networks, Opts.Discovery.RestPort, Opts.Discovery.Timeout, Opts.Discovery.Parallel, Opts.Discovery.Auth.Username, Opts.Discovery.Auth.Password)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	logger.Info(`starting background discovery`, slog.Any("networks", opts.Networks), slog.Duration("interval", Opts.Discovery.Interval))
+
+	go func() {
+		for {
+			runDiscovery(opts)
+			time.Sleep(Opts.Discovery.Interval)
+		}
+	}()
+}
+
+func runDiscovery(opts fenecon.DiscoveryOpts) {
+	startTime := time.Now()
+	systems, err := fenecon.Discover(context.Background(), logger, opts)
+	if err != nil {
+		logger.Error(`discovery failed`, slog.Any("error", err))
+		return
+	}
+
+	logger.Info(`finished discovery`, slog.Int("systems", len(systems)), slog.Duration("duration", time.Since(startTime)))
+	discoverySystems.Set(float64(len(systems)))
+	discoveryLastRun.SetToCurrentTime()
+
+	setDiscoveredTargets(newDiscoveredTargets(systems, configFileTargets(), Opts.Discovery.Poll))
+}
+
+// configFileTargets returns the targets of the loaded config (without discovered targets)
+func configFileTargets() []*config.Target {
+	configFileLock.RLock()
+	defer configFileLock.RUnlock()
+	return configFileLoaded.Targets
+}
+
+// setDiscoveredTargets replaces the discovered targets and updates the background polling
+func setDiscoveredTargets(targets []*config.Target) {
+	discoveredTargetsLock.Lock()
+	discoveredTargets = targets
+	discoveredTargetsLock.Unlock()
+
+	configFileLock.Lock()
+	configFile = withDiscoveredTargets(configFileLoaded)
+	configFileLock.Unlock()
+
+	poller.SetTargets(pollTargets())
+}
+
+// withDiscoveredTargets returns the config with the discovered targets,
+// configured targets take precedence (same name or url)
+func withDiscoveredTargets(conf *config.Config) *config.Config {
+	discoveredTargetsLock.RLock()
+	defer discoveredTargetsLock.RUnlock()
+
+	if len(discoveredTargets) == 0 {
+		return conf
+	}
+
+	ret := *conf
+	ret.Targets = slices.Clone(conf.Targets)
+	for _, target := range discoveredTargets {
+		if conf.Target(target.Name) == nil && conf.Target(target.Url) == nil {
+			ret.Targets = append(ret.Targets, target)
+		}
+	}
+
+	return &ret
+}
+
+// newDiscoveredTargets builds the targets of the discovered systems (named by edge id or address),
+// systems which are already configured (same url) are skipped
+func newDiscoveredTargets(systems []*fenecon.DiscoveredSystem, configured []*config.Target, poll bool) []*config.Target {
+	names := map[string]bool{}
+	for _, target := range configured {
+		names[target.Name] = true
+	}
+
+	ret := []*config.Target{}
+	for _, system := range systems {
+		if slices.ContainsFunc(configured, func(target *config.Target) bool { return target.Url == system.Url }) {
+			continue
+		}
+
+		target := config.Target{
+			Name: system.EdgeId,
+			Url:  system.Url,
+			Poll: poll,
+		}
+
+		if target.Name == "" || names[target.Name] {
+			target.Name = system.Address
+		}
+		names[target.Name] = true
+
+		// REST-Api is the default transport
+		if !slices.Contains(system.Transports, fenecon.TransportRest) {
+			target.Transport = fenecon.TransportWebsocket
+		}
+
+		ret = append(ret, &target)
+	}
+
+	return ret
+}
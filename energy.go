@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+var (
+	energyAggregator *fenecon.EnergyAggregator
+)
+
+func initEnergyAggregator() {
+	if Opts.Energy.Period.StateFile == "" {
+		return
+	}
+
+	location, err := time.LoadLocation(Opts.Energy.Period.Timezone)
+	if err != nil {
+		logger.Fatal(`invalid energy period timezone`, slog.String("timezone", Opts.Energy.Period.Timezone), slog.Any("error", err))
+	}
+
+	energyAggregator, err = fenecon.NewEnergyAggregator(Opts.Energy.Period.StateFile, location, Opts.Energy.Period.MaxGap, Opts.Energy.Period.StateInterval)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	logger.Info(`enabled energy aggregation per period`, slog.String("path", Opts.Energy.Period.StateFile), slog.String("timezone", location.String()))
+}
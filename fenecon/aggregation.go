@@ -0,0 +1,238 @@
+package fenecon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	EnergyPeriodDay   = "day"
+	EnergyPeriodMonth = "month"
+	EnergyPeriodYear  = "year"
+
+	EnergyKindProduction  = "production"
+	EnergyKindConsumption = "consumption"
+	EnergyKindGridBuy     = "grid_buy"
+	EnergyKindGridSell    = "grid_sell"
+	EnergyKindCharge      = "charge"
+	EnergyKindDischarge   = "discharge"
+)
+
+var (
+	energyPeriods = []string{EnergyPeriodDay, EnergyPeriodMonth, EnergyPeriodYear}
+)
+
+type (
+	// EnergyAggregator aggregates the cumulative energy channels of the summary (_sum) per calendar period (day, month, year),
+	// the period baselines are stored in a state file to survive restarts
+	EnergyAggregator struct {
+		path     string
+		location *time.Location
+
+		// max time between two updates, energy of longer gaps is not assigned to a new period
+		maxGap time.Duration
+
+		// min time between two writes of the state file (new baselines are written immediately)
+		saveInterval time.Duration
+
+		state energyAggregatorState
+		dirty bool
+		// baseline of a period was created
+		baselineChanged bool
+		saved           time.Time
+		lock            sync.Mutex
+	}
+
+	energyAggregatorState struct {
+		// target -> kind -> counter
+		Targets map[string]map[string]*energyPeriodCounter `json:"targets"`
+	}
+
+	energyPeriodCounter struct {
+		// last value of the cumulative channel
+		Last float64 `json:"last"`
+
+		// time of the last update
+		Updated time.Time `json:"updated"`
+
+		// period -> baseline
+		Periods map[string]*energyPeriodBaseline `json:"periods"`
+	}
+
+	energyPeriodBaseline struct {
+		// start of the period (in configured timezone)
+		Start time.Time `json:"start"`
+
+		// value of the cumulative channel at start of the period
+		Value float64 `json:"value"`
+	}
+)
+
+// NewEnergyAggregator creates the aggregator and loads the state file (if it exists), empty path disables persistence,
+// energy of gaps longer than maxGap between two updates is not assigned to a new period
+func NewEnergyAggregator(path string, location *time.Location, maxGap, saveInterval time.Duration) (*EnergyAggregator, error) {
+	a := EnergyAggregator{}
+	a.path = path
+	a.location = location
+	a.maxGap = maxGap
+	a.saveInterval = saveInterval
+	a.state.Targets = map[string]map[string]*energyPeriodCounter{}
+
+	if path == "" {
+		return &a, nil
+	}
+
+	// #nosec G304 -- path is passed by the user
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &a, nil
+	} else if err != nil {
+		return nil, fmt.Errorf(`unable to read energy state file "%v": %w`, path, err)
+	}
+
+	if err := json.Unmarshal(content, &a.state); err != nil {
+		return nil, fmt.Errorf(`unable to parse energy state file "%v": %w`, path, err)
+	}
+
+	if a.state.Targets == nil {
+		a.state.Targets = map[string]map[string]*energyPeriodCounter{}
+	}
+
+	return &a, nil
+}
+
+// Update updates the cumulative value (Watthours) of the target and returns the energy per period,
+// the baseline of a new period is the last value of the previous period or the current value if the
+// last update is older than the max gap (the energy of the gap might belong to the previous periods, eg. downtime)
+func (a *EnergyAggregator) Update(target, kind string, value float64, now time.Time) map[string]float64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if _, exists := a.state.Targets[target]; !exists {
+		a.state.Targets[target] = map[string]*energyPeriodCounter{}
+	}
+
+	counter, exists := a.state.Targets[target][kind]
+	if !exists {
+		counter = &energyPeriodCounter{Last: value, Periods: map[string]*energyPeriodBaseline{}}
+		a.state.Targets[target][kind] = counter
+	}
+
+	// device counter reset, keep energy of the period before the reset
+	if value < counter.Last {
+		for _, baseline := range counter.Periods {
+			baseline.Value -= counter.Last
+		}
+	}
+
+	baselineValue := counter.Last
+	if counter.Updated.IsZero() || now.Sub(counter.Updated) > a.maxGap {
+		baselineValue = value
+	}
+
+	ret := map[string]float64{}
+	for _, period := range energyPeriods {
+		start := a.periodStart(period, now)
+
+		baseline, exists := counter.Periods[period]
+		if !exists || !baseline.Start.Equal(start) {
+			baseline = &energyPeriodBaseline{Start: start, Value: baselineValue}
+			counter.Periods[period] = baseline
+			a.baselineChanged = true
+		}
+
+		ret[period] = value - baseline.Value
+	}
+
+	counter.Last = value
+	counter.Updated = now
+	a.dirty = true
+
+	return ret
+}
+
+func (a *EnergyAggregator) periodStart(period string, now time.Time) time.Time {
+	now = now.In(a.location)
+
+	switch period {
+	case EnergyPeriodYear:
+		return time.Date(now.Year(), 1, 1, 0, 0, 0, 0, a.location)
+	case EnergyPeriodMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, a.location)
+	default:
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, a.location)
+	}
+}
+
+// Save writes the state file if changed, at most once per save interval unless the baseline of a period was created
+func (a *EnergyAggregator) Save() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.path == "" || !a.dirty {
+		return nil
+	}
+
+	if !a.baselineChanged && time.Since(a.saved) < a.saveInterval {
+		return nil
+	}
+
+	content, err := json.Marshal(a.state)
+	if err != nil {
+		return err
+	}
+
+	// write to temp file and rename, state file must not be truncated on crash
+	tmpFile, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf(`unable to write energy state file "%v": %w`, a.path, err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.Write(content); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf(`unable to write energy state file "%v": %w`, a.path, err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf(`unable to write energy state file "%v": %w`, a.path, err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), a.path); err != nil {
+		return fmt.Errorf(`unable to write energy state file "%v": %w`, a.path, err)
+	}
+
+	a.dirty = false
+	a.baselineChanged = false
+	a.saved = time.Now()
+	return nil
+}
+
+// SetEnergyAggregator enables the aggregation of energy per period (shared between all probers)
+func (fp *FeneconProber) SetEnergyAggregator(aggregator *EnergyAggregator) {
+	fp.aggregator = aggregator
+}
+
+// aggregateEnergy updates the period aggregation and sets the energy per period metrics
+func (fp *FeneconProber) aggregateEnergy(kind string, value *float64) {
+	if fp.aggregator == nil || value == nil {
+		return
+	}
+
+	for period, periodValue := range fp.aggregator.Update(fp.target.Target, kind, *value, time.Now()) {
+		fp.prometheus.energyPeriod.With(prometheus.Labels{
+			"target": fp.target.Target,
+			"period": period,
+			"kind":   kind,
+		}).Set(periodValue)
+	}
+}
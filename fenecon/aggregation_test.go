@@ -0,0 +1,112 @@
+package fenecon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnergyAggregatorRollover(t *testing.T) {
+	aggregator, err := NewEnergyAggregator("", time.UTC, 15*time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 1, 31, 23, 50, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		now    time.Time
+		value  float64
+		energy map[string]float64
+	}{
+		{name: "first update", now: start, value: 1000, energy: map[string]float64{"day": 0, "month": 0, "year": 0}},
+		{name: "same day", now: start.Add(5 * time.Minute), value: 1200, energy: map[string]float64{"day": 200, "month": 200, "year": 200}},
+		{name: "new day and month", now: start.Add(15 * time.Minute), value: 1500, energy: map[string]float64{"day": 300, "month": 300, "year": 500}},
+		{name: "counter reset", now: start.Add(20 * time.Minute), value: 100, energy: map[string]float64{"day": 400, "month": 400, "year": 600}},
+		{name: "after counter reset", now: start.Add(25 * time.Minute), value: 150, energy: map[string]float64{"day": 450, "month": 450, "year": 650}},
+	}
+
+	for _, test := range tests {
+		energy := aggregator.Update("http://fenecon", EnergyKindProduction, test.value, test.now)
+		for period, expected := range test.energy {
+			if !floatEqual(energy[period], expected) {
+				t.Errorf("%v: expected %v energy %v, got %v", test.name, period, expected, energy[period])
+			}
+		}
+	}
+}
+
+func TestEnergyAggregatorGap(t *testing.T) {
+	aggregator, err := NewEnergyAggregator("", time.UTC, 15*time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	aggregator.Update("http://fenecon", EnergyKindGridBuy, 1000, start)
+	aggregator.Update("http://fenecon", EnergyKindGridBuy, 1100, start.Add(10*time.Minute))
+
+	// downtime across midnight, the energy of the gap is not assigned to the new day but kept in month and year
+	energy := aggregator.Update("http://fenecon", EnergyKindGridBuy, 3000, start.Add(14*time.Hour))
+	expected := map[string]float64{"day": 0, "month": 2000, "year": 2000}
+	for period, value := range expected {
+		if !floatEqual(energy[period], value) {
+			t.Errorf("expected %v energy %v, got %v", period, value, energy[period])
+		}
+	}
+
+	// gap within the period is kept
+	energy = aggregator.Update("http://fenecon", EnergyKindGridBuy, 3500, start.Add(16*time.Hour))
+	if !floatEqual(energy["day"], 500) {
+		t.Errorf("expected day energy 500, got %v", energy["day"])
+	}
+}
+
+func TestEnergyAggregatorReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "energy.json")
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	aggregator, err := NewEnergyAggregator(path, time.UTC, 15*time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggregator.Update("http://fenecon", EnergyKindConsumption, 1000, start)
+	if err := aggregator.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// no new period, not written within the save interval
+	aggregator.Update("http://fenecon", EnergyKindConsumption, 1100, start.Add(5*time.Minute))
+	if err := aggregator.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewEnergyAggregator(path, time.UTC, 15*time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := reloaded.state.Targets["http://fenecon"][EnergyKindConsumption].Last; last != 1000 {
+		t.Errorf("expected throttled state with last value 1000, got %v", last)
+	}
+
+	// baselines of the periods are kept after reload
+	energy := reloaded.Update("http://fenecon", EnergyKindConsumption, 1300, start.Add(10*time.Minute))
+	if !floatEqual(energy["day"], 300) {
+		t.Errorf("expected day energy 300 after reload, got %v", energy["day"])
+	}
+
+	// new periods are written immediately
+	reloaded.Update("http://fenecon", EnergyKindConsumption, 1400, start.Add(12*time.Hour+5*time.Minute))
+	if err := reloaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err = NewEnergyAggregator(path, time.UTC, 15*time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := reloaded.state.Targets["http://fenecon"][EnergyKindConsumption].Periods[EnergyPeriodDay]
+	if !baseline.Start.Equal(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)) || baseline.Value != 1400 {
+		t.Errorf("unexpected day baseline after new period %+v", baseline)
+	}
+}
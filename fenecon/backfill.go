@@ -0,0 +1,311 @@
+package fenecon
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	// power channels of the summary (_sum), queried via queryHistoricTimeseriesData
+	backfillPowerChannels = []string{
+		"_sum/EssSoc",
+		"_sum/EssCapacity",
+		"_sum/EssActivePower",
+		"_sum/EssDischargePower",
+		"_sum/EssActivePowerL1",
+		"_sum/EssActivePowerL2",
+		"_sum/EssActivePowerL3",
+		"_sum/GridActivePower",
+		"_sum/GridActivePowerL1",
+		"_sum/GridActivePowerL2",
+		"_sum/GridActivePowerL3",
+		"_sum/ProductionActivePower",
+		"_sum/ProductionAcActivePower",
+		"_sum/ProductionDcActualPower",
+		"_sum/ProductionAcActivePowerL1",
+		"_sum/ProductionAcActivePowerL2",
+		"_sum/ProductionAcActivePowerL3",
+		"_sum/ConsumptionActivePower",
+		"_sum/ConsumptionActivePowerL1",
+		"_sum/ConsumptionActivePowerL2",
+		"_sum/ConsumptionActivePowerL3",
+		"_sum/EvcsActivePower",
+	}
+
+	// cumulative energy channels of the summary (_sum), queried via queryHistoricTimeseriesEnergyPerPeriod
+	backfillEnergyChannels = []string{
+		"_sum/EssActiveChargeEnergy",
+		"_sum/EssActiveDischargeEnergy",
+		"_sum/EssDcChargeEnergy",
+		"_sum/EssDcDischargeEnergy",
+		"_sum/GridBuyActiveEnergy",
+		"_sum/GridSellActiveEnergy",
+		"_sum/ProductionActiveEnergy",
+		"_sum/ProductionAcActiveEnergy",
+		"_sum/ProductionDcActiveEnergy",
+		"_sum/ConsumptionActiveEnergy",
+	}
+)
+
+type (
+	BackfillOpts struct {
+		From       time.Time
+		To         time.Time
+		Resolution time.Duration
+		Location   *time.Location
+	}
+
+	historicTimeseriesRequest struct {
+		Timezone   string                       `json:"timezone"`
+		FromDate   string                       `json:"fromDate"`
+		ToDate     string                       `json:"toDate"`
+		Channels   []string                     `json:"channels"`
+		Resolution historicTimeseriesResolution `json:"resolution"`
+	}
+
+	historicTimeseriesResolution struct {
+		Value int    `json:"value"`
+		Unit  string `json:"unit"`
+	}
+
+	historicTimeseriesResponse struct {
+		Timestamps []string              `json:"timestamps"`
+		Data       map[string][]*float64 `json:"data"`
+	}
+)
+
+// Backfill queries the history of the summary (_sum) channels and returns the metrics of the sum query group
+// for every timestamp between from and to (metrics have timestamps),
+// cumulative energy values are calculated backwards from the current values and the energy per period
+func (fp *FeneconProber) Backfill(target FeneconProberTarget, opts BackfillOpts) ([]*dto.MetricFamily, error) {
+	fp.target = target
+
+	resolution, err := newHistoricTimeseriesResolution(opts.Resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	timezone, err := timezoneName(opts.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := fp.newTransport(target)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := transport.Close(); err != nil {
+			fp.logger.Warn(`failed to close transport`, slog.Any("error", err))
+		}
+	}()
+
+	now := time.Now()
+	rows := map[time.Time]ResultWildcard{}
+
+	// power
+	fp.logger.Info(`query historic power`, slog.Time("from", opts.From), slog.Time("to", opts.To))
+	power := historicTimeseriesResponse{}
+	if err := transport.EdgeRequest(fp.ctx, "queryHistoricTimeseriesData", newHistoricTimeseriesRequest(opts, timezone, opts.To, backfillPowerChannels, resolution), &power); err != nil {
+		return nil, fmt.Errorf(`queryHistoricTimeseriesData failed: %w`, err)
+	}
+
+	timestamps, err := power.parseTimestamps()
+	if err != nil {
+		return nil, err
+	}
+
+	for channel, values := range power.Data {
+		for i, value := range values {
+			if i < len(timestamps) && value != nil {
+				rows[timestamps[i]] = append(rows[timestamps[i]], ResultCommon{Address: channel, Value: ResultValue{ValueNumeric: value}})
+			}
+		}
+	}
+
+	// energy (until now, cumulative values are calculated backwards from the current values)
+	fp.logger.Info(`query historic energy`, slog.Time("from", opts.From), slog.Time("to", now))
+	current, _, err := transport.Query(fp.ctx, "_sum/.*")
+	if err != nil {
+		return nil, fmt.Errorf(`query of current values failed: %w`, err)
+	}
+
+	energy := historicTimeseriesResponse{}
+	if err := transport.EdgeRequest(fp.ctx, "queryHistoricTimeseriesEnergyPerPeriod", newHistoricTimeseriesRequest(opts, timezone, now, backfillEnergyChannels, resolution), &energy); err != nil {
+		return nil, fmt.Errorf(`queryHistoricTimeseriesEnergyPerPeriod failed: %w`, err)
+	}
+
+	timestamps, err = energy.parseTimestamps()
+	if err != nil {
+		return nil, err
+	}
+
+	for channel, values := range energy.Data {
+		currentValue := current.Address(channel).Value.ValueNumeric
+		if currentValue == nil {
+			fp.logger.Warn(`current value of channel not available, skipping`, slog.String("channel", channel))
+			continue
+		}
+
+		// value at start of period = current value - energy of this and all following periods
+		cumulative := *currentValue
+		for i := len(values) - 1; i >= 0; i-- {
+			if values[i] != nil {
+				cumulative -= *values[i]
+			}
+
+			if i < len(timestamps) {
+				value := cumulative
+				rows[timestamps[i]] = append(rows[timestamps[i]], ResultCommon{Address: channel, Value: ResultValue{ValueNumeric: &value}})
+			}
+		}
+	}
+
+	return fp.backfillMetrics(target, rows, opts)
+}
+
+// backfillMetrics maps the channels of every timestamp to metrics (like collectSum) and merges them into metric families with timestamps
+func (fp *FeneconProber) backfillMetrics(target FeneconProberTarget, rows map[time.Time]ResultWildcard, opts BackfillOpts) ([]*dto.MetricFamily, error) {
+	timestamps := []time.Time{}
+	for timestamp := range rows {
+		if !timestamp.Before(opts.From) && !timestamp.After(opts.To) {
+			timestamps = append(timestamps, timestamp)
+		}
+	}
+	slices.SortFunc(timestamps, func(a, b time.Time) int { return a.Compare(b) })
+
+	// historic values are mapped as reported, without counter protection (state of live probes) and
+	// without energy aggregation and cost accounting
+	prober := New(fp.ctx, prometheus.NewRegistry(), fp.logger)
+	prober.target = target
+	prober.legacyMetrics = fp.legacyMetrics
+	prober.counters = nil
+
+	families := map[string]*dto.MetricFamily{}
+	for _, timestamp := range timestamps {
+		result := rows[timestamp]
+
+		// metrics of channels missing at this timestamp must not be repeated from the previous timestamp
+		prober.resetMetrics()
+		prober.collectSum(&result)
+
+		gathered, err := prober.registry.Gather()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, family := range gathered {
+			if _, exists := families[family.GetName()]; !exists {
+				families[family.GetName()] = &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type}
+			}
+
+			for _, metric := range family.Metric {
+				timestampMs := timestamp.UnixMilli()
+				metric.TimestampMs = &timestampMs
+				families[family.GetName()].Metric = append(families[family.GetName()].Metric, metric)
+			}
+		}
+	}
+
+	ret := []*dto.MetricFamily{}
+	for _, family := range families {
+		// group samples by series (ordered by timestamp)
+		sort.SliceStable(family.Metric, func(i, j int) bool {
+			return labelSignature(family.Metric[i]) < labelSignature(family.Metric[j])
+		})
+		ret = append(ret, family)
+	}
+	slices.SortFunc(ret, func(a, b *dto.MetricFamily) int { return strings.Compare(a.GetName(), b.GetName()) })
+
+	if len(ret) == 0 {
+		return nil, errors.New(`no historic data found`)
+	}
+
+	return ret, nil
+}
+
+func labelSignature(metric *dto.Metric) string {
+	ret := []string{}
+	for _, label := range metric.Label {
+		ret = append(ret, label.GetName()+"="+label.GetValue())
+	}
+	return strings.Join(ret, "\xff")
+}
+
+func newHistoricTimeseriesRequest(opts BackfillOpts, timezone string, to time.Time, channels []string, resolution historicTimeseriesResolution) historicTimeseriesRequest {
+	return historicTimeseriesRequest{
+		Timezone:   timezone,
+		FromDate:   opts.From.In(opts.Location).Format(time.DateOnly),
+		ToDate:     to.In(opts.Location).Format(time.DateOnly),
+		Channels:   channels,
+		Resolution: resolution,
+	}
+}
+
+// timezoneName returns the IANA name of the location (eg. Europe/Berlin), the name of the local timezone
+// is taken from $TZ or /etc/localtime as the edge doesn't know "Local"
+func timezoneName(location *time.Location) (string, error) {
+	if location.String() != "Local" {
+		return location.String(), nil
+	}
+
+	name := strings.TrimPrefix(os.Getenv("TZ"), ":")
+	if name == "" {
+		if link, err := os.Readlink("/etc/localtime"); err == nil {
+			if _, zone, found := strings.Cut(link, "zoneinfo/"); found {
+				name = zone
+			}
+		}
+	}
+
+	if name == "" {
+		return "", errors.New(`unable to detect name of local timezone, please set the timezone (eg. Europe/Berlin)`)
+	}
+
+	if _, err := time.LoadLocation(name); err != nil {
+		return "", fmt.Errorf(`invalid local timezone "%v": %w`, name, err)
+	}
+
+	return name, nil
+}
+
+func newHistoricTimeseriesResolution(val time.Duration) (historicTimeseriesResolution, error) {
+	switch {
+	case val >= 24*time.Hour && val%(24*time.Hour) == 0:
+		return historicTimeseriesResolution{Value: int(val / (24 * time.Hour)), Unit: "DAYS"}, nil
+	case val >= time.Hour && val%time.Hour == 0:
+		return historicTimeseriesResolution{Value: int(val / time.Hour), Unit: "HOURS"}, nil
+	case val >= time.Minute && val%time.Minute == 0:
+		return historicTimeseriesResolution{Value: int(val / time.Minute), Unit: "MINUTES"}, nil
+	default:
+		return historicTimeseriesResolution{}, fmt.Errorf(`resolution "%v" must be a multiple of one minute`, val)
+	}
+}
+
+// parseTimestamps parses the timestamps of the response (eg. 2024-10-16T00:00:00Z or 2024-10-16T00:00+02:00[Europe/Berlin])
+func (r *historicTimeseriesResponse) parseTimestamps() ([]time.Time, error) {
+	ret := make([]time.Time, len(r.Timestamps))
+	for i, val := range r.Timestamps {
+		val, _, _ = strings.Cut(val, "[")
+
+		var err error
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+			if ret[i], err = time.Parse(layout, val); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf(`unable to parse historic timestamp "%v": %w`, val, err)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,99 @@
+package fenecon
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestBackfillMetricsWithoutCounterProtection(t *testing.T) {
+	fp := New(context.Background(), prometheus.NewRegistry(), newTestLogger())
+	target := FeneconProberTarget{Target: "backfill-test"}
+
+	start := time.Date(2024, 10, 16, 0, 0, 0, 0, time.UTC)
+	rows := map[time.Time]ResultWildcard{}
+	for i, value := range []float64{1000, 900, 1100} {
+		rows[start.Add(time.Duration(i)*time.Hour)] = ResultWildcard{
+			{Address: "_sum/GridBuyActiveEnergy", Unit: "Wh", Value: ResultValue{ValueNumeric: &value}},
+		}
+	}
+
+	families, err := fp.backfillMetrics(target, rows, BackfillOpts{From: start, To: start.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{}
+	for _, family := range families {
+		if family.GetName() == "fenecon_grid_buy_energy_watt_hours_total" {
+			for _, metric := range family.GetMetric() {
+				values = append(values, metric.GetCounter().GetValue())
+			}
+		}
+	}
+
+	// historic values are exported as reported (lower values are not replaced by the previous value)
+	if len(values) != 3 || values[0] != 1000 || values[1] != 900 || values[2] != 1100 {
+		t.Errorf("expected historic values [1000 900 1100], got %v", values)
+	}
+
+	counters.lock.Lock()
+	defer counters.lock.Unlock()
+	for key := range counters.counters {
+		if !strings.Contains(key, target.Target) {
+			continue
+		}
+		t.Errorf("backfill must not update the counter state of live probes, got %q", key)
+	}
+}
+
+func TestBackfillMetricsMissingChannels(t *testing.T) {
+	fp := New(context.Background(), prometheus.NewRegistry(), newTestLogger())
+	target := FeneconProberTarget{Target: "backfill-test"}
+
+	start := time.Date(2024, 10, 16, 0, 0, 0, 0, time.UTC)
+	gridPower := 1200.0
+	soc := 55.0
+	rows := map[time.Time]ResultWildcard{
+		start: {
+			{Address: "_sum/GridActivePower", Unit: "W", Value: ResultValue{ValueNumeric: &gridPower}},
+			{Address: "_sum/EssSoc", Unit: "%", Value: ResultValue{ValueNumeric: &soc}},
+		},
+		start.Add(time.Hour): {
+			{Address: "_sum/EssSoc", Unit: "%", Value: ResultValue{ValueNumeric: &soc}},
+		},
+	}
+
+	families, err := fp.backfillMetrics(target, rows, BackfillOpts{From: start, To: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// channels missing at a timestamp are not repeated from the previous timestamp
+	samples := map[string]int{}
+	for _, family := range families {
+		samples[family.GetName()] = len(family.GetMetric())
+	}
+	if samples["fenecon_grid_power"] != 1 || samples["fenecon_battery_charge_percent"] != 2 {
+		t.Errorf("expected 1 grid power and 2 battery charge samples, got %v", samples)
+	}
+}
+
+func TestTimezoneName(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+
+	if name, err := timezoneName(berlin); err != nil || name != "Europe/Berlin" {
+		t.Errorf("expected Europe/Berlin, got %q (%v)", name, err)
+	}
+
+	t.Setenv("TZ", "Europe/Berlin")
+	if name, err := timezoneName(time.Local); err != nil || name != "Europe/Berlin" {
+		t.Errorf("expected local timezone Europe/Berlin, got %q (%v)", name, err)
+	}
+}
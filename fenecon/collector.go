@@ -0,0 +1,86 @@
+package fenecon
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// constMetricVec is a collector for values which are set from the device (eg. counters which can't be incremented)
+	constMetricVec struct {
+		name       string
+		desc       *prometheus.Desc
+		valueType  prometheus.ValueType
+		labelNames []string
+
+		metrics map[string]prometheus.Metric
+		lock    sync.Mutex
+	}
+)
+
+func newConstMetricVec(name, help string, valueType prometheus.ValueType, labelNames []string, constLabels prometheus.Labels) *constMetricVec {
+	v := constMetricVec{}
+	v.name = name
+	v.desc = prometheus.NewDesc(name, help, labelNames, constLabels)
+	v.valueType = valueType
+	v.labelNames = labelNames
+	v.metrics = map[string]prometheus.Metric{}
+	return &v
+}
+
+// Set sets the value for the labels, invalid labels are ignored
+func (v *constMetricVec) Set(labels prometheus.Labels, value float64) {
+	v.SetWithCreatedTimestamp(labels, value, time.Time{})
+}
+
+// SetWithCreatedTimestamp sets the value and the created timestamp (only counters, zero time is ignored) for the labels
+func (v *constMetricVec) SetWithCreatedTimestamp(labels prometheus.Labels, value float64, created time.Time) {
+	var (
+		metric prometheus.Metric
+		err    error
+	)
+
+	labelValues := v.labelValues(labels)
+	if created.IsZero() {
+		metric, err = prometheus.NewConstMetric(v.desc, v.valueType, value, labelValues...)
+	} else {
+		metric, err = prometheus.NewConstMetricWithCreatedTimestamp(v.desc, v.valueType, value, created, labelValues...)
+	}
+	if err != nil {
+		return
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.metrics[strings.Join(labelValues, "\xff")] = metric
+}
+
+func (v *constMetricVec) labelValues(labels prometheus.Labels) []string {
+	ret := make([]string, len(v.labelNames))
+	for i, name := range v.labelNames {
+		ret[i] = labels[name]
+	}
+	return ret
+}
+
+func (v *constMetricVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+func (v *constMetricVec) Collect(ch chan<- prometheus.Metric) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	for _, metric := range v.metrics {
+		ch <- metric
+	}
+}
+
+// Reset deletes all metrics
+func (v *constMetricVec) Reset() {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.metrics = map[string]prometheus.Metric{}
+}
@@ -0,0 +1,166 @@
+package fenecon
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// ComponentInfo is the configuration of a component (from getEdgeConfig)
+	ComponentInfo struct {
+		// alias configured by the user (eg. "Heat pump")
+		Alias string
+
+		// factory id (eg. Meter.Socomec.Threephase)
+		Factory string
+
+		// level (eg. FAULT, WARNING) of the state channels, the REST-Api doesn't provide category and level
+		StateChannels map[string]string
+	}
+
+	// ComponentCache caches the component configuration and the edge info per target, getEdgeConfig returns the
+	// configuration of all channels and is too expensive for every probe, the edge info (_meta, _host) is static
+	ComponentCache struct {
+		ttl time.Duration
+
+		targets map[string]*componentCacheEntry
+		edges   map[string]*edgeCacheEntry
+		lock    sync.Mutex
+	}
+
+	componentCacheEntry struct {
+		components map[string]ComponentInfo
+		fetched    time.Time
+	}
+
+	edgeCacheEntry struct {
+		info    *EdgeInfo
+		host    *ResultWildcard
+		fetched time.Time
+	}
+)
+
+// NewComponentCache creates the cache, ttl of 0 (or negative) disables fetching the component configuration
+// (websocket transport still uses the edge config fetched on connect) and fetches the edge info on every probe
+func NewComponentCache(ttl time.Duration) *ComponentCache {
+	c := ComponentCache{}
+	c.ttl = ttl
+	c.targets = map[string]*componentCacheEntry{}
+	c.edges = map[string]*edgeCacheEntry{}
+	return &c
+}
+
+// Disabled returns true if fetching the component configuration is disabled (ttl of 0 or negative)
+func (c *ComponentCache) Disabled() bool {
+	return c.ttl <= 0
+}
+
+// Get returns the cached components of the target, nil if not cached or expired
+func (c *ComponentCache) Get(target string) map[string]ComponentInfo {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if entry, exists := c.targets[target]; exists && time.Since(entry.fetched) < c.ttl {
+		return entry.components
+	}
+	return nil
+}
+
+// Set caches the components of the target
+func (c *ComponentCache) Set(target string, components map[string]ComponentInfo) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.targets[target] = &componentCacheEntry{components: components, fetched: time.Now()}
+}
+
+// GetEdgeInfo returns the cached edge info and _host channels of the target, nil if not cached or expired
+func (c *ComponentCache) GetEdgeInfo(target string) (*EdgeInfo, *ResultWildcard) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if entry, exists := c.edges[target]; exists && time.Since(entry.fetched) < c.ttl {
+		return entry.info, entry.host
+	}
+	return nil, nil
+}
+
+// SetEdgeInfo caches the edge info and _host channels of the target
+func (c *ComponentCache) SetEdgeInfo(target string, info *EdgeInfo, host *ResultWildcard) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.edges[target] = &edgeCacheEntry{info: info, host: host, fetched: time.Now()}
+}
+
+func (fp *FeneconProber) SetComponentCache(cache *ComponentCache) {
+	fp.componentCache = cache
+}
+
+// collectComponents exports fenecon_component_info with alias and factory of every component
+// (join with other metrics via module label)
+func (fp *FeneconProber) collectComponents() {
+	fp.components = fp.fetchComponents()
+
+	for componentId, component := range fp.components {
+		fp.prometheus.component.With(prometheus.Labels{
+			"target":  fp.target.Target,
+			"module":  componentId,
+			"alias":   component.Alias,
+			"factory": component.Factory,
+		}).Set(1)
+	}
+}
+
+// fetchComponents returns the component configuration (cached per target if a cache is set), websocket transport
+// already fetched the edge config on connect. Failures are recorded as query but are not probe errors as getEdgeConfig
+// might not be permitted, failures are not cached
+func (fp *FeneconProber) fetchComponents() map[string]ComponentInfo {
+	if fp.componentCache != nil {
+		if components := fp.componentCache.Get(fp.target.Target); components != nil {
+			return components
+		}
+	}
+
+	var edgeConfig *EdgeConfig
+	var err error
+	if ws, ok := fp.transport.(*websocketTransport); ok && ws.edgeConfig != nil {
+		edgeConfig = ws.edgeConfig
+	} else if fp.componentCache != nil && fp.componentCache.Disabled() {
+		return map[string]ComponentInfo{}
+	} else {
+		edgeConfig = &EdgeConfig{}
+		startTime := time.Now()
+		err = fp.transport.EdgeRequest(fp.ctx, "getEdgeConfig", nil, edgeConfig)
+		fp.recordQueryResult("getEdgeConfig", startTime, 0, err)
+		if err != nil {
+			fp.logger.Debug(`unable to fetch edge config`, slog.Any("error", err))
+		}
+	}
+
+	components := map[string]ComponentInfo{}
+	for componentId, component := range edgeConfig.Components {
+		stateChannels := map[string]string{}
+		for channelId, channel := range component.Channels {
+			if strings.EqualFold(channel.Category, ChannelCategoryState) {
+				stateChannels[channelId] = channel.Level
+			}
+		}
+
+		components[componentId] = ComponentInfo{
+			Alias:         component.Alias,
+			Factory:       component.FactoryId,
+			StateChannels: stateChannels,
+		}
+	}
+
+	if fp.componentCache != nil && err == nil {
+		fp.componentCache.Set(fp.target.Target, components)
+	}
+
+	return components
+}
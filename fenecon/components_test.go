@@ -0,0 +1,80 @@
+package fenecon
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCollectComponentsFailureNotCached(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.denied = map[string]bool{"getEdgeConfig": true}
+	server := fake.startRest(t)
+	cache := NewComponentCache(time.Hour)
+
+	fp, registry := newTestProber(t, "sum")
+	fp.SetComponentCache(cache)
+	// channel queries succeeded, getEdgeConfig is no probe error
+	if err := fp.Run(FeneconProberTarget{Target: server.URL}); err != nil {
+		t.Fatalf("expected successful probe, got %v", err)
+	}
+
+	if probeSuccess := gatherMetrics(t, registry, "fenecon_probe_success"); probeSuccess[""] != 1 {
+		t.Errorf("expected probe success, got %v", probeSuccess)
+	}
+
+	querySuccess := gatherMetrics(t, registry, "fenecon_probe_query_success")
+	if value, exists := querySuccess["query=getEdgeConfig"]; !exists || value != 0 {
+		t.Errorf("expected failed getEdgeConfig query, got %v", querySuccess)
+	}
+
+	if components := cache.Get(server.URL); components != nil {
+		t.Errorf("expected failed component configuration not to be cached, got %v", components)
+	}
+
+	// fetched again on next probe
+	fake.lock.Lock()
+	fake.denied = nil
+	fake.lock.Unlock()
+
+	fp, registry = newTestProber(t, "sum")
+	fp.SetComponentCache(cache)
+	if err := fp.Run(FeneconProberTarget{Target: server.URL}); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+
+	componentInfo := gatherMetrics(t, registry, "fenecon_component_info")
+	if componentInfo["alias=Battery,factory=Ess.Fenecon.Commercial40,module=ess0"] != 1 {
+		t.Errorf("unexpected fenecon_component_info %v", componentInfo)
+	}
+
+	if components := cache.Get(server.URL); components == nil {
+		t.Error("expected component configuration to be cached")
+	}
+
+	methods := fake.receivedMethods()
+	if count := len(slices.DeleteFunc(methods, func(method string) bool { return method != "getEdgeConfig" })); count != 2 {
+		t.Errorf("expected getEdgeConfig on both probes, got %v", count)
+	}
+}
+
+func TestCollectComponentsDisabled(t *testing.T) {
+	fake := newFakeOpenEMS()
+	server := fake.startRest(t)
+
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		fp, registry := newTestProber(t, "sum")
+		fp.SetComponentCache(NewComponentCache(ttl))
+		if err := fp.Run(FeneconProberTarget{Target: server.URL}); err != nil {
+			t.Fatalf("ttl %v: probe failed: %v", ttl, err)
+		}
+
+		if componentInfo := gatherMetrics(t, registry, "fenecon_component_info"); len(componentInfo) != 0 {
+			t.Errorf("ttl %v: expected no fenecon_component_info, got %v", ttl, componentInfo)
+		}
+	}
+
+	if methods := fake.receivedMethods(); slices.Contains(methods, "getEdgeConfig") {
+		t.Errorf("expected no getEdgeConfig, got %v", methods)
+	}
+}
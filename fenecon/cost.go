@@ -0,0 +1,483 @@
+package fenecon
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	yaml "go.yaml.in/yaml/v3"
+)
+
+const (
+	CostKindGridBuy  = "grid_buy"
+	CostKindGridSell = "grid_sell"
+	CostKindBaseline = "baseline"
+
+	DefaultCurrency = "EUR"
+)
+
+var (
+	tariffWeekdays = map[string]time.Weekday{
+		"sun": time.Sunday,
+		"mon": time.Monday,
+		"tue": time.Tuesday,
+		"wed": time.Wednesday,
+		"thu": time.Thursday,
+		"fri": time.Friday,
+		"sat": time.Saturday,
+	}
+)
+
+type (
+	// Tariff are the prices (per kWh) for buying from and selling (feed-in) to the grid
+	Tariff struct {
+		Currency string       `yaml:"currency"`
+		Buy      TariffPrices `yaml:"buy"`
+		Sell     TariffPrices `yaml:"sell"`
+	}
+
+	// TariffPrices is a fixed price, time of day windows override the fixed price and a price file overrides both
+	TariffPrices struct {
+		Price   float64         `yaml:"price"`
+		Windows []*TariffWindow `yaml:"windows"`
+
+		// CSV (timestamp,price) or JSON ([{"timestamp": ..., "price": ...}]) file with prices (eg. dynamic tariff),
+		// a price is valid until the next timestamp
+		File string `yaml:"file"`
+
+		prices []tariffPrice
+	}
+
+	TariffWindow struct {
+		// time of day (eg. 22:00), windows can span midnight
+		From string `yaml:"from"`
+		To   string `yaml:"to"`
+
+		// weekdays (eg. mon, sat), all days if empty
+		Weekdays []string `yaml:"weekdays"`
+
+		Price float64 `yaml:"price"`
+
+		from     time.Duration
+		to       time.Duration
+		weekdays []time.Weekday
+	}
+
+	tariffPrice struct {
+		Timestamp time.Time `json:"timestamp"`
+		Price     float64   `json:"price"`
+	}
+
+	// CostAccountant accumulates the cost of grid buy, the revenue of grid sell and the cost without PV and battery (baseline:
+	// consumption bought from grid) from the cumulative energy channels of the summary (_sum),
+	// the accumulated costs are stored in a state file to survive restarts
+	CostAccountant struct {
+		path     string
+		tariff   *Tariff
+		location *time.Location
+
+		// max time between two updates, the price changes within longer gaps are unknown
+		maxGap time.Duration
+
+		state costState
+		dirty bool
+		lock  sync.Mutex
+	}
+
+	costState struct {
+		Targets map[string]*costTarget `json:"targets"`
+	}
+
+	costTarget struct {
+		// start of accounting
+		Since time.Time `json:"since"`
+
+		// kind -> last value of the cumulative channel (Wh)
+		Last map[string]float64 `json:"last"`
+
+		// kind -> time of last value
+		Updated map[string]time.Time `json:"updated"`
+
+		// kind -> accumulated cost (currency)
+		Total map[string]float64 `json:"total"`
+	}
+)
+
+// LoadTariffFile loads and validates the tariff file, price files are relative to the tariff file
+func LoadTariffFile(path string, location *time.Location) (*Tariff, error) {
+	// #nosec G304 -- path is passed by the user
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to read tariff file "%v": %w`, path, err)
+	}
+
+	tariff := Tariff{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&tariff); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf(`unable to parse tariff file "%v": %w`, path, err)
+	}
+
+	if tariff.Currency == "" {
+		tariff.Currency = DefaultCurrency
+	}
+
+	// validated in fixed order (buy, sell), errors must be reproducible
+	for _, kind := range []struct {
+		name   string
+		prices *TariffPrices
+	}{{"buy", &tariff.Buy}, {"sell", &tariff.Sell}} {
+		if err := kind.prices.init(filepath.Dir(path), location); err != nil {
+			return nil, fmt.Errorf(`invalid tariff file "%v": %v: %w`, path, kind.name, err)
+		}
+	}
+
+	return &tariff, nil
+}
+
+func (p *TariffPrices) init(dir string, location *time.Location) error {
+	for i, window := range p.Windows {
+		var err error
+		if window.from, err = parseTariffTimeOfDay(window.From); err != nil {
+			return fmt.Errorf(`window #%v: %w`, i, err)
+		}
+		if window.to, err = parseTariffTimeOfDay(window.To); err != nil {
+			return fmt.Errorf(`window #%v: %w`, i, err)
+		}
+
+		for _, name := range window.Weekdays {
+			weekday, exists := tariffWeekdays[strings.ToLower(name)]
+			if !exists {
+				return fmt.Errorf(`window #%v: invalid weekday "%v" (expected mon, tue, wed, thu, fri, sat or sun)`, i, name)
+			}
+			window.weekdays = append(window.weekdays, weekday)
+		}
+	}
+
+	if p.File != "" {
+		path := p.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		prices, err := loadTariffPriceFile(path, location)
+		if err != nil {
+			return err
+		}
+		p.prices = prices
+	}
+
+	return nil
+}
+
+func parseTariffTimeOfDay(val string) (time.Duration, error) {
+	if val == "24:00" {
+		return 24 * time.Hour, nil
+	}
+
+	ret, err := time.Parse("15:04", val)
+	if err != nil {
+		return 0, fmt.Errorf(`invalid time of day "%v" (expected HH:MM)`, val)
+	}
+	return time.Duration(ret.Hour())*time.Hour + time.Duration(ret.Minute())*time.Minute, nil
+}
+
+// loadTariffPriceFile loads the prices of the CSV or JSON file (sorted by timestamp),
+// timestamps are RFC3339 or "2006-01-02 15:04" in the tariff timezone
+func loadTariffPriceFile(path string, location *time.Location) ([]tariffPrice, error) {
+	// #nosec G304 -- path is passed by the user
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to read price file "%v": %w`, path, err)
+	}
+
+	prices := []tariffPrice{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(content, &prices); err != nil {
+			return nil, fmt.Errorf(`unable to parse price file "%v": %w`, path, err)
+		}
+	} else {
+		reader := csv.NewReader(bytes.NewReader(content))
+		reader.FieldsPerRecord = 2
+		reader.TrimLeadingSpace = true
+		reader.Comment = '#'
+
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf(`unable to parse price file "%v": %w`, path, err)
+		}
+
+		for i, record := range records {
+			timestamp, err := parseTariffTimestamp(record[0], location)
+			if err != nil && i == 0 {
+				// header
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf(`unable to parse price file "%v" line %v: %w`, path, i+1, err)
+			}
+
+			price, err := strconv.ParseFloat(record[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf(`unable to parse price file "%v" line %v: invalid price "%v"`, path, i+1, record[1])
+			}
+
+			prices = append(prices, tariffPrice{Timestamp: timestamp, Price: price})
+		}
+	}
+
+	slices.SortFunc(prices, func(a, b tariffPrice) int { return a.Timestamp.Compare(b.Timestamp) })
+	return prices, nil
+}
+
+func parseTariffTimestamp(val string, location *time.Location) (time.Time, error) {
+	if ret, err := time.Parse(time.RFC3339, val); err == nil {
+		return ret, nil
+	}
+
+	ret, err := time.ParseInLocation("2006-01-02 15:04", val, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(`invalid timestamp "%v" (expected RFC3339 or "2006-01-02 15:04")`, val)
+	}
+	return ret, nil
+}
+
+// PriceAt returns the price per kWh at the time (in the tariff timezone)
+func (p *TariffPrices) PriceAt(now time.Time) float64 {
+	// price file, last price is valid for the duration of the previous price (one hour if there is only one price)
+	if index, found := slices.BinarySearchFunc(p.prices, now, func(price tariffPrice, t time.Time) int { return price.Timestamp.Compare(t) }); found || index > 0 {
+		if !found {
+			index--
+		}
+
+		validity := time.Hour
+		switch {
+		case index+1 < len(p.prices):
+			validity = p.prices[index+1].Timestamp.Sub(p.prices[index].Timestamp)
+		case index > 0:
+			validity = p.prices[index].Timestamp.Sub(p.prices[index-1].Timestamp)
+		}
+
+		if now.Before(p.prices[index].Timestamp.Add(validity)) {
+			return p.prices[index].Price
+		}
+	}
+
+	// time of day windows, first matching window wins
+	timeOfDay := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+	for _, window := range p.Windows {
+		if window.matches(now.Weekday(), timeOfDay) {
+			return window.Price
+		}
+	}
+
+	return p.Price
+}
+
+func (w *TariffWindow) matches(weekday time.Weekday, timeOfDay time.Duration) bool {
+	if len(w.weekdays) > 0 && !slices.Contains(w.weekdays, weekday) {
+		return false
+	}
+
+	if w.from <= w.to {
+		return timeOfDay >= w.from && timeOfDay < w.to
+	}
+
+	// spans midnight (eg. 22:00 - 06:00)
+	return timeOfDay >= w.from || timeOfDay < w.to
+}
+
+// NewCostAccountant creates the accountant and loads the state file (if it exists), empty path disables persistence,
+// energy of gaps longer than maxGap between two updates is not priced
+func NewCostAccountant(path string, tariff *Tariff, location *time.Location, maxGap time.Duration) (*CostAccountant, error) {
+	a := CostAccountant{}
+	a.path = path
+	a.tariff = tariff
+	a.location = location
+	a.maxGap = maxGap
+	a.state.Targets = map[string]*costTarget{}
+
+	if path == "" {
+		return &a, nil
+	}
+
+	// #nosec G304 -- path is passed by the user
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &a, nil
+	} else if err != nil {
+		return nil, fmt.Errorf(`unable to read cost state file "%v": %w`, path, err)
+	}
+
+	if err := json.Unmarshal(content, &a.state); err != nil {
+		return nil, fmt.Errorf(`unable to parse cost state file "%v": %w`, path, err)
+	}
+
+	if a.state.Targets == nil {
+		a.state.Targets = map[string]*costTarget{}
+	}
+
+	return &a, nil
+}
+
+// Currency returns the currency of the tariff (eg. EUR)
+func (a *CostAccountant) Currency() string {
+	return a.tariff.Currency
+}
+
+// Price returns the current price per kWh of the kind (grid_buy and baseline use the buy price)
+func (a *CostAccountant) Price(kind string, now time.Time) float64 {
+	if kind == CostKindGridSell {
+		return a.tariff.Sell.PriceAt(now.In(a.location))
+	}
+	return a.tariff.Buy.PriceAt(now.In(a.location))
+}
+
+// Update updates the cumulative value (Watthours) of the target, the energy since the last update is priced with the current price,
+// returns the accumulated cost and the start of the accounting, fails if the energy was not priced as the last update is
+// older than the max gap (the value is the new baseline)
+func (a *CostAccountant) Update(target, kind string, value float64, now time.Time) (float64, time.Time, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	state, exists := a.state.Targets[target]
+	if !exists {
+		state = &costTarget{Since: now, Last: map[string]float64{}, Total: map[string]float64{}}
+		a.state.Targets[target] = state
+	}
+	if state.Updated == nil {
+		state.Updated = map[string]time.Time{}
+	}
+
+	var err error
+
+	// first value and lower values (device counter reset) are the new baseline
+	if last, exists := state.Last[kind]; exists && value > last {
+		// the price might have changed several times within a long gap (eg. downtime), pricing the whole energy
+		// with the current price would be wrong
+		updated, known := state.Updated[kind]
+		switch gap := now.Sub(updated); {
+		case !known:
+			err = fmt.Errorf(`energy of %v Wh was not priced, time of last update is unknown`, value-last)
+		case gap > a.maxGap:
+			err = fmt.Errorf(`energy of %v Wh was not priced, last update %v ago (max %v)`, value-last, gap.Round(time.Second), a.maxGap)
+		default:
+			state.Total[kind] += (value - last) / 1000 * a.Price(kind, now)
+		}
+	}
+	state.Last[kind] = value
+	state.Updated[kind] = now
+	a.dirty = true
+
+	return state.Total[kind], state.Since, err
+}
+
+// Totals returns the accumulated costs of the target
+func (a *CostAccountant) Totals(target string) map[string]float64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if state, exists := a.state.Targets[target]; exists {
+		return maps.Clone(state.Total)
+	}
+	return map[string]float64{}
+}
+
+// Save writes the state file (if changed)
+func (a *CostAccountant) Save() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.path == "" || !a.dirty {
+		return nil
+	}
+
+	content, err := json.Marshal(a.state)
+	if err != nil {
+		return err
+	}
+
+	// write to temp file and rename, state file must not be truncated on crash
+	tmpFile, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf(`unable to write cost state file "%v": %w`, a.path, err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.Write(content); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf(`unable to write cost state file "%v": %w`, a.path, err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf(`unable to write cost state file "%v": %w`, a.path, err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), a.path); err != nil {
+		return fmt.Errorf(`unable to write cost state file "%v": %w`, a.path, err)
+	}
+
+	a.dirty = false
+	return nil
+}
+
+// SetCostAccountant enables the cost accounting (shared between all probers)
+func (fp *FeneconProber) SetCostAccountant(accountant *CostAccountant) {
+	fp.costAccountant = accountant
+}
+
+// accountCost updates the accumulated costs from the cumulative energy values (Watthours, nil if not available)
+// and sets the cost metrics
+func (fp *FeneconProber) accountCost(gridBuy, gridSell, consumption *float64) {
+	if fp.costAccountant == nil {
+		return
+	}
+
+	now := time.Now()
+	currency := fp.costAccountant.Currency()
+
+	for kind, value := range map[string]*float64{CostKindGridBuy: gridBuy, CostKindGridSell: gridSell, CostKindBaseline: consumption} {
+		if value == nil {
+			continue
+		}
+
+		total, since, err := fp.costAccountant.Update(fp.target.Target, kind, *value, now)
+		if err != nil {
+			fp.logger.Warn(`skipped cost accounting of gap`, slog.String("kind", kind), slog.Any("error", err))
+		}
+		fp.prometheus.cost.total.SetWithCreatedTimestamp(prometheus.Labels{
+			"target":   fp.target.Target,
+			"kind":     kind,
+			"currency": currency,
+		}, total, since)
+	}
+
+	for _, kind := range []string{CostKindGridBuy, CostKindGridSell} {
+		fp.prometheus.cost.price.With(prometheus.Labels{
+			"target":   fp.target.Target,
+			"kind":     kind,
+			"currency": currency,
+		}).Set(fp.costAccountant.Price(kind, now))
+	}
+
+	// saved cost compared to buying the whole consumption from grid (without PV and battery)
+	totals := fp.costAccountant.Totals(fp.target.Target)
+	fp.prometheus.cost.saved.With(prometheus.Labels{
+		"target":   fp.target.Target,
+		"currency": currency,
+	}).Set(totals[CostKindBaseline] - totals[CostKindGridBuy] + totals[CostKindGridSell])
+}
@@ -0,0 +1,200 @@
+package fenecon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestTariffPrices(t *testing.T, prices TariffPrices) *TariffPrices {
+	t.Helper()
+
+	if err := prices.init(t.TempDir(), time.UTC); err != nil {
+		t.Fatalf("invalid tariff prices: %v", err)
+	}
+	return &prices
+}
+
+func TestTariffPriceAt(t *testing.T) {
+	prices := newTestTariffPrices(t, TariffPrices{
+		Price: 0.32,
+		Windows: []*TariffWindow{
+			{From: "22:00", To: "06:00", Price: 0.24},
+			{From: "00:00", To: "24:00", Weekdays: []string{"sat", "sun"}, Price: 0.26},
+		},
+	})
+	prices.prices = []tariffPrice{
+		{Timestamp: time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC), Price: 0.30},
+		{Timestamp: time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC), Price: 0.40},
+	}
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		price float64
+	}{
+		{name: "fixed price", now: time.Date(2026, 10, 14, 12, 30, 0, 0, time.UTC), price: 0.32},
+		{name: "window before midnight", now: time.Date(2026, 10, 14, 23, 0, 0, 0, time.UTC), price: 0.24},
+		{name: "window after midnight", now: time.Date(2026, 10, 15, 5, 59, 0, 0, time.UTC), price: 0.24},
+		{name: "end of window is exclusive", now: time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC), price: 0.32},
+		{name: "weekday window", now: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), price: 0.26},
+		{name: "first matching window wins", now: time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC), price: 0.24},
+		{name: "price file", now: time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC), price: 0.30},
+		{name: "last price of price file", now: time.Date(2026, 10, 14, 11, 59, 0, 0, time.UTC), price: 0.40},
+		{name: "before price file", now: time.Date(2026, 10, 14, 9, 59, 0, 0, time.UTC), price: 0.32},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if price := prices.PriceAt(test.now); price != test.price {
+				t.Errorf("expected price %v, got %v", test.price, price)
+			}
+		})
+	}
+}
+
+func TestTariffWindowMatches(t *testing.T) {
+	window := newTestTariffPrices(t, TariffPrices{
+		Windows: []*TariffWindow{{From: "22:00", To: "06:00", Weekdays: []string{"mon"}}},
+	}).Windows[0]
+
+	tests := []struct {
+		weekday   time.Weekday
+		timeOfDay time.Duration
+		matches   bool
+	}{
+		{weekday: time.Monday, timeOfDay: 21*time.Hour + 59*time.Minute, matches: false},
+		{weekday: time.Monday, timeOfDay: 22 * time.Hour, matches: true},
+		{weekday: time.Monday, timeOfDay: 0, matches: true},
+		{weekday: time.Monday, timeOfDay: 5*time.Hour + 59*time.Minute, matches: true},
+		{weekday: time.Monday, timeOfDay: 6 * time.Hour, matches: false},
+		{weekday: time.Tuesday, timeOfDay: 23 * time.Hour, matches: false},
+	}
+
+	for _, test := range tests {
+		if matches := window.matches(test.weekday, test.timeOfDay); matches != test.matches {
+			t.Errorf("%v %v: expected %v, got %v", test.weekday, test.timeOfDay, test.matches, matches)
+		}
+	}
+}
+
+func TestLoadTariffFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		currency string
+		err      string
+	}{
+		{name: "valid", content: "buy:\n  price: 0.32\nsell:\n  price: 0.08\n", currency: DefaultCurrency},
+		{name: "currency", content: "currency: CHF\nbuy:\n  price: 0.30\n", currency: "CHF"},
+		{name: "unknown field", content: "buy:\n  prize: 0.32\n", err: "unable to parse tariff file"},
+		{name: "invalid sell", content: "sell:\n  windows:\n    - from: \"25:00\"\n      to: \"06:00\"\n", err: ": sell: window #0"},
+		{name: "invalid buy and sell", content: "buy:\n  windows:\n    - from: \"22:00\"\n      to: \"06:00\"\n      weekdays: [monday]\nsell:\n  windows:\n    - from: \"25:00\"\n      to: \"06:00\"\n", err: ": buy: window #0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tariff.yaml")
+			if err := os.WriteFile(path, []byte(test.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			// errors are reported in a fixed order
+			for range 10 {
+				tariff, err := LoadTariffFile(path, time.UTC)
+				if test.err != "" {
+					if err == nil || !strings.Contains(err.Error(), test.err) {
+						t.Fatalf("expected error containing %q, got %v", test.err, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tariff.Currency != test.currency {
+					t.Errorf("expected currency %v, got %v", test.currency, tariff.Currency)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTariffPriceFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		prices  int
+		fails   bool
+	}{
+		{name: "header", content: "timestamp,price\n2026-10-14 10:00,0.30\n2026-10-14T11:00:00Z,0.40\n", prices: 2},
+		{name: "without header", content: "2026-10-14 11:00,0.40\n2026-10-14 10:00,0.30\n", prices: 2},
+		{name: "comment", content: "# prices\n2026-10-14 10:00,0.30\n", prices: 1},
+		{name: "invalid timestamp after header", content: "timestamp,price\nyesterday,0.30\n", fails: true},
+		{name: "invalid price", content: "2026-10-14 10:00,free\n", fails: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "prices.csv")
+			if err := os.WriteFile(path, []byte(test.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			prices, err := loadTariffPriceFile(path, time.UTC)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected error, got %v", prices)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(prices) != test.prices {
+				t.Fatalf("expected %v prices, got %v", test.prices, prices)
+			}
+			if prices[0].Timestamp != time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC) || prices[0].Price != 0.30 {
+				t.Errorf("unexpected first price %+v", prices[0])
+			}
+		})
+	}
+}
+
+func TestCostAccountantUpdateGap(t *testing.T) {
+	tariff := &Tariff{Currency: DefaultCurrency, Buy: TariffPrices{Price: 0.30}}
+	accountant, err := NewCostAccountant("", tariff, time.UTC, 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	steps := []struct {
+		offset  time.Duration
+		value   float64
+		total   float64
+		skipped bool
+	}{
+		// baseline
+		{offset: 0, value: 1000, total: 0},
+		{offset: 5 * time.Minute, value: 2000, total: 0.30},
+		// downtime, energy is not priced
+		{offset: 2 * time.Hour, value: 12000, total: 0.30, skipped: true},
+		{offset: 2*time.Hour + 15*time.Minute, value: 13000, total: 0.60},
+	}
+
+	for i, step := range steps {
+		total, since, err := accountant.Update("fenecon", CostKindGridBuy, step.value, start.Add(step.offset))
+		if (err != nil) != step.skipped {
+			t.Errorf("step %v: expected skipped %v, got error %v", i, step.skipped, err)
+		}
+		if !floatEqual(total, step.total) {
+			t.Errorf("step %v: expected total %v, got %v", i, step.total, total)
+		}
+		if !since.Equal(start) {
+			t.Errorf("step %v: expected accounting since %v, got %v", i, start, since)
+		}
+	}
+}
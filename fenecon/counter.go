@@ -0,0 +1,110 @@
+package fenecon
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// number of consecutive lower readings until a lower value is accepted as counter reset of the device
+	counterResetReadings = 3
+
+	// counter states which are not updated within this duration are removed
+	counterExpiry = 24 * time.Hour
+)
+
+type (
+	// counterStore keeps the last value of cumulative channels across probes (probers are created per probe)
+	// to protect counters against briefly lower values reported by the device
+	counterStore struct {
+		counters  map[string]*counterState
+		lastPrune time.Time
+		lock      sync.Mutex
+	}
+
+	counterState struct {
+		value         float64
+		created       time.Time
+		lowerReadings int
+		lowerSince    time.Time
+		lastUpdate    time.Time
+	}
+)
+
+var (
+	counters = counterStore{counters: map[string]*counterState{}}
+)
+
+// update returns the protected counter value and the created timestamp (zero if unknown):
+// lower values are ignored until they are reported counterResetReadings times in a row, then they are accepted as counter reset
+func (s *counterStore) update(key string, value float64, now time.Time) (float64, time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.prune(now)
+
+	state, exists := s.counters[key]
+	if !exists {
+		// start of counter is not known
+		state = &counterState{value: value}
+		s.counters[key] = state
+	}
+	state.lastUpdate = now
+
+	switch {
+	case value >= state.value:
+		state.value = value
+		state.lowerReadings = 0
+	default:
+		if state.lowerReadings == 0 {
+			state.lowerSince = now
+		}
+		state.lowerReadings++
+
+		if state.lowerReadings >= counterResetReadings {
+			// counter was reset by the device with the first lower reading
+			state.value = value
+			state.lowerReadings = 0
+			state.created = state.lowerSince
+		}
+	}
+
+	return state.value, state.created
+}
+
+func (s *counterStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Hour {
+		return
+	}
+	s.lastPrune = now
+
+	for key, state := range s.counters {
+		if now.Sub(state.lastUpdate) > counterExpiry {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// setEnergyCounter sets the energy counter (Watthours) and the legacy gauge (if legacy metrics are enabled and gauge is passed),
+// zero values are ignored as the device reports zero if the value is not available, returns the counter value (nil if not set)
+func (fp *FeneconProber) setEnergyCounter(row *ResultCommon, labels prometheus.Labels, legacy *prometheus.GaugeVec, counter *constMetricVec) *float64 {
+	if fp.legacyMetrics && legacy != nil {
+		row.SetGaugeIfNotZero(labels, legacy)
+	}
+
+	value, ok := row.NormalizedValue("Wh")
+	if !ok || value <= 0 {
+		return nil
+	}
+
+	created := time.Time{}
+	if fp.counters != nil {
+		key := counter.name + "\xff" + strings.Join(counter.labelValues(labels), "\xff")
+		value, created = fp.counters.update(key, value, time.Now())
+	}
+	counter.SetWithCreatedTimestamp(labels, value, created)
+	return &value
+}
@@ -0,0 +1,99 @@
+package fenecon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCounterStoreUpdate(t *testing.T) {
+	start := time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		values  []float64
+		value   float64
+		created bool
+	}{
+		{name: "increasing", values: []float64{100, 150, 150, 200}, value: 200},
+		{name: "briefly lower", values: []float64{100, 90, 95, 110}, value: 110},
+		{name: "lower less than required readings", values: []float64{100, 10, 20}, value: 100},
+		{name: "counter reset", values: []float64{100, 10, 20, 30}, value: 30, created: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := counterStore{counters: map[string]*counterState{}}
+
+			var (
+				value   float64
+				created time.Time
+			)
+			for i, val := range test.values {
+				value, created = store.update("counter", val, start.Add(time.Duration(i)*time.Minute))
+			}
+
+			if value != test.value {
+				t.Errorf("expected %v, got %v", test.value, value)
+			}
+
+			// counter was reset with the first lower reading
+			if test.created && !created.Equal(start.Add(time.Minute)) {
+				t.Errorf("expected created timestamp of first lower reading, got %v", created)
+			} else if !test.created && !created.IsZero() {
+				t.Errorf("expected unknown created timestamp, got %v", created)
+			}
+		})
+	}
+}
+
+func TestCounterStorePrune(t *testing.T) {
+	start := time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	store := counterStore{counters: map[string]*counterState{}}
+	store.update("expired", 100, start)
+	store.update("active", 100, start)
+	store.update("active", 110, start.Add(counterExpiry))
+	store.update("active", 120, start.Add(counterExpiry+2*time.Hour))
+
+	if _, exists := store.counters["expired"]; exists {
+		t.Error("expected expired counter to be removed")
+	}
+	if _, exists := store.counters["active"]; !exists {
+		t.Error("expected active counter to be kept")
+	}
+}
+
+func TestCollectEnergyCounters(t *testing.T) {
+	fake := newFakeOpenEMS()
+	server := fake.startRest(t)
+
+	probe := func() *prometheus.Registry {
+		t.Helper()
+
+		// probers are created per probe, the counter state is kept
+		fp, registry := newTestProber(t, QueryGroupSum)
+		if err := fp.Run(FeneconProberTarget{Target: server.URL}); err != nil {
+			t.Fatalf("probe failed: %v", err)
+		}
+		return registry
+	}
+
+	assertMetrics(t, probe(), "fenecon_grid_buy_energy_watt_hours_total", map[string]float64{"module=_sum": 123456})
+
+	// briefly lower reading is replaced by the previous value
+	fake.setChannelValue("_sum", "GridBuyActiveEnergy", 123000)
+	assertMetrics(t, probe(), "fenecon_grid_buy_energy_watt_hours_total", map[string]float64{"module=_sum": 123456})
+
+	// zero is reported if the value is not available
+	fake.setChannelValue("_sum", "GridBuyActiveEnergy", 0)
+	registry := probe()
+	assertMetrics(t, registry, "fenecon_grid_buy_energy_watt_hours_total", map[string]float64{})
+	assertMetrics(t, registry, "fenecon_consumption_energy_watt_hours_total", map[string]float64{"module=_sum": 199999})
+
+	// third lower reading in a row (zero is not a reading) is accepted as counter reset of the device
+	fake.setChannelValue("_sum", "GridBuyActiveEnergy", 100)
+	assertMetrics(t, probe(), "fenecon_grid_buy_energy_watt_hours_total", map[string]float64{"module=_sum": 123456})
+	assertMetrics(t, probe(), "fenecon_grid_buy_energy_watt_hours_total", map[string]float64{"module=_sum": 100})
+}
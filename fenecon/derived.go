@@ -0,0 +1,151 @@
+package fenecon
+
+import (
+	"math"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	EnergyFlowPv      = "pv"
+	EnergyFlowGrid    = "grid"
+	EnergyFlowBattery = "battery"
+	EnergyFlowHome    = "home"
+)
+
+type (
+	// sumPower is the instantaneous power of the summary (_sum) in Watts with OpenEMS sign conventions
+	sumPower struct {
+		// ProductionActivePower (>= 0)
+		production float64
+
+		// ConsumptionActivePower (>= 0)
+		consumption float64
+
+		// GridActivePower (> 0: buy from grid, < 0: sell to grid)
+		grid float64
+
+		// EssDischargePower (> 0: discharge, < 0: charge)
+		ess float64
+	}
+
+	// energyFlow is the breakdown of the power flows (like the FEMS UI energy monitor) in Watts
+	energyFlow struct {
+		pvToHome        float64
+		pvToBattery     float64
+		pvToGrid        float64
+		gridToHome      float64
+		gridToBattery   float64
+		batteryToHome   float64
+		batteryToGrid   float64
+		autarky         *float64
+		selfConsumption *float64
+	}
+)
+
+// calculateEnergyFlow splits production, grid and battery power into flows,
+// pv is used for home first, then for charging the battery and the rest is sold,
+// the battery discharges to home first and grid buy covers the remaining consumption and charging
+func calculateEnergyFlow(power sumPower) energyFlow {
+	flow := energyFlow{}
+
+	pv := math.Max(power.production, 0)
+	home := math.Max(power.consumption, 0)
+	gridBuy := math.Max(power.grid, 0)
+	gridSell := math.Max(-power.grid, 0)
+	batteryDischarge := math.Max(power.ess, 0)
+	batteryCharge := math.Max(-power.ess, 0)
+
+	// pv
+	flow.pvToHome = math.Min(pv, home)
+	pv -= flow.pvToHome
+	home -= flow.pvToHome
+
+	flow.pvToBattery = math.Min(pv, batteryCharge)
+	pv -= flow.pvToBattery
+	batteryCharge -= flow.pvToBattery
+
+	flow.pvToGrid = math.Min(pv, gridSell)
+	gridSell -= flow.pvToGrid
+
+	// battery
+	flow.batteryToHome = math.Min(batteryDischarge, home)
+	batteryDischarge -= flow.batteryToHome
+	home -= flow.batteryToHome
+
+	flow.batteryToGrid = math.Min(batteryDischarge, gridSell)
+
+	// grid
+	flow.gridToHome = math.Min(gridBuy, home)
+	gridBuy -= flow.gridToHome
+
+	flow.gridToBattery = math.Min(gridBuy, batteryCharge)
+
+	// ratios (same as FEMS UI)
+	if power.consumption > 0 {
+		autarky := clampRatio(1 - math.Max(power.grid, 0)/power.consumption)
+		flow.autarky = &autarky
+	}
+
+	if power.production > 0 {
+		selfConsumption := clampRatio(1 - math.Max(-power.grid, 0)/power.production)
+		flow.selfConsumption = &selfConsumption
+	}
+
+	return flow
+}
+
+func clampRatio(val float64) float64 {
+	return math.Max(0, math.Min(1, val))
+}
+
+// collectDerived sets the derived autarky, self-consumption and energy flow metrics from the summary (_sum)
+func (fp *FeneconProber) collectDerived(result *ResultWildcard) {
+	production := result.Address("_sum/ProductionActivePower").Value.ValueNumeric
+	consumption := result.Address("_sum/ConsumptionActivePower").Value.ValueNumeric
+	grid := result.Address("_sum/GridActivePower").Value.ValueNumeric
+
+	// EssDischargePower excludes DC production of hybrid inverters, EssActivePower as fallback
+	ess := result.Address("_sum/EssDischargePower").Value.ValueNumeric
+	if ess == nil {
+		ess = result.Address("_sum/EssActivePower").Value.ValueNumeric
+	}
+
+	if production == nil || consumption == nil || grid == nil {
+		return
+	}
+
+	power := sumPower{production: *production, consumption: *consumption, grid: *grid}
+	if ess != nil {
+		power.ess = *ess
+	}
+
+	flow := calculateEnergyFlow(power)
+
+	commonLabels := prometheus.Labels{"target": fp.target.Target, "module": "_sum"}
+	if flow.autarky != nil {
+		fp.prometheus.derived.autarky.With(commonLabels).Set(*flow.autarky)
+	}
+	if flow.selfConsumption != nil {
+		fp.prometheus.derived.selfConsumption.With(commonLabels).Set(*flow.selfConsumption)
+	}
+
+	for _, row := range []struct {
+		from, to string
+		value    float64
+	}{
+		{EnergyFlowPv, EnergyFlowHome, flow.pvToHome},
+		{EnergyFlowPv, EnergyFlowBattery, flow.pvToBattery},
+		{EnergyFlowPv, EnergyFlowGrid, flow.pvToGrid},
+		{EnergyFlowGrid, EnergyFlowHome, flow.gridToHome},
+		{EnergyFlowGrid, EnergyFlowBattery, flow.gridToBattery},
+		{EnergyFlowBattery, EnergyFlowHome, flow.batteryToHome},
+		{EnergyFlowBattery, EnergyFlowGrid, flow.batteryToGrid},
+	} {
+		fp.prometheus.derived.energyFlow.With(prometheus.Labels{
+			"target": fp.target.Target,
+			"from":   row.from,
+			"to":     row.to,
+		}).Set(row.value)
+	}
+}
@@ -0,0 +1,142 @@
+package fenecon
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollectDerived(t *testing.T) {
+	ratio := func(val float64) *float64 { return &val }
+
+	testCases := []struct {
+		name     string
+		channels map[string]float64
+
+		// expected flows (from=...,to=...), not listed flows are expected to be 0
+		flows           map[string]float64
+		autarky         *float64
+		selfConsumption *float64
+	}{
+		{
+			name:     "grid import",
+			channels: map[string]float64{"ProductionActivePower": 0, "ConsumptionActivePower": 1000, "GridActivePower": 1000, "EssActivePower": 0},
+			flows:    map[string]float64{"from=grid,to=home": 1000},
+			autarky:  ratio(0),
+		},
+		{
+			name:            "grid export",
+			channels:        map[string]float64{"ProductionActivePower": 3000, "ConsumptionActivePower": 1000, "GridActivePower": -2000, "EssActivePower": 0},
+			flows:           map[string]float64{"from=pv,to=home": 1000, "from=pv,to=grid": 2000},
+			autarky:         ratio(1),
+			selfConsumption: ratio(1.0 / 3),
+		},
+		{
+			name:            "battery charge from pv",
+			channels:        map[string]float64{"ProductionActivePower": 3000, "ConsumptionActivePower": 1000, "GridActivePower": 0, "EssActivePower": -2000},
+			flows:           map[string]float64{"from=pv,to=home": 1000, "from=pv,to=battery": 2000},
+			autarky:         ratio(1),
+			selfConsumption: ratio(1),
+		},
+		{
+			name:     "battery charge from grid",
+			channels: map[string]float64{"ProductionActivePower": 0, "ConsumptionActivePower": 500, "GridActivePower": 2500, "EssActivePower": -2000},
+			flows:    map[string]float64{"from=grid,to=home": 500, "from=grid,to=battery": 2000},
+			autarky:  ratio(0),
+		},
+		{
+			name:     "battery discharge with grid import",
+			channels: map[string]float64{"ProductionActivePower": 0, "ConsumptionActivePower": 1500, "GridActivePower": 500, "EssActivePower": 1000},
+			flows:    map[string]float64{"from=battery,to=home": 1000, "from=grid,to=home": 500},
+			autarky:  ratio(2.0 / 3),
+		},
+		{
+			name:     "battery discharge to grid",
+			channels: map[string]float64{"ProductionActivePower": 0, "ConsumptionActivePower": 500, "GridActivePower": -1000, "EssActivePower": 1500},
+			flows:    map[string]float64{"from=battery,to=home": 500, "from=battery,to=grid": 1000},
+			autarky:  ratio(1),
+		},
+		{
+			name: "EssDischargePower preferred over EssActivePower",
+			channels: map[string]float64{
+				"ProductionActivePower": 3000, "ConsumptionActivePower": 2000, "GridActivePower": 0,
+				"EssActivePower": -3000, "EssDischargePower": -1000,
+			},
+			flows:           map[string]float64{"from=pv,to=home": 2000, "from=pv,to=battery": 1000},
+			autarky:         ratio(1),
+			selfConsumption: ratio(1),
+		},
+		{
+			name:     "zero",
+			channels: map[string]float64{"ProductionActivePower": 0, "ConsumptionActivePower": 0, "GridActivePower": 0, "EssActivePower": 0},
+			flows:    map[string]float64{},
+		},
+		{
+			name:            "without battery",
+			channels:        map[string]float64{"ProductionActivePower": 1000, "ConsumptionActivePower": 400, "GridActivePower": -600},
+			flows:           map[string]float64{"from=pv,to=home": 400, "from=pv,to=grid": 600},
+			autarky:         ratio(1),
+			selfConsumption: ratio(0.4),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			fp := New(context.Background(), registry, newTestLogger())
+			fp.target = FeneconProberTarget{Target: "test"}
+
+			result := ResultWildcard{}
+			for channel, value := range testCase.channels {
+				result = append(result, ResultCommon{Address: "_sum/" + channel, Unit: "W", Value: ResultValue{ValueNumeric: &value}})
+			}
+			fp.collectDerived(&result)
+
+			flows := gatherMetrics(t, registry, "fenecon_energy_flow_watts")
+			if len(flows) != 7 {
+				t.Fatalf("expected 7 energy flows, got %v", flows)
+			}
+			for flow, value := range flows {
+				if expected := testCase.flows[flow]; !floatEqual(value, expected) {
+					t.Errorf("flow %v: expected %v, got %v", flow, expected, value)
+				}
+			}
+
+			assertRatio(t, "fenecon_autarky_ratio", gatherMetrics(t, registry, "fenecon_autarky_ratio"), testCase.autarky)
+			assertRatio(t, "fenecon_self_consumption_ratio", gatherMetrics(t, registry, "fenecon_self_consumption_ratio"), testCase.selfConsumption)
+		})
+	}
+}
+
+func TestCollectDerivedIncomplete(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	fp := New(context.Background(), registry, newTestLogger())
+	fp.target = FeneconProberTarget{Target: "test"}
+
+	production := 1000.0
+	fp.collectDerived(&ResultWildcard{{Address: "_sum/ProductionActivePower", Value: ResultValue{ValueNumeric: &production}}})
+
+	if flows := gatherMetrics(t, registry, "fenecon_energy_flow_watts"); len(flows) != 0 {
+		t.Errorf("expected no energy flows without consumption and grid power, got %v", flows)
+	}
+}
+
+func assertRatio(t *testing.T, name string, metrics map[string]float64, expected *float64) {
+	t.Helper()
+
+	value, exists := metrics["module=_sum"]
+	switch {
+	case expected == nil && exists:
+		t.Errorf("%v: expected no value, got %v", name, value)
+	case expected != nil && !exists:
+		t.Errorf("%v: expected %v, got no value", name, *expected)
+	case expected != nil && !floatEqual(value, *expected):
+		t.Errorf("%v: expected %v, got %v", name, *expected, value)
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
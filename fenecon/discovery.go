@@ -0,0 +1,253 @@
+package fenecon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/remeh/sizedwaitgroup"
+	"github.com/webdevops/go-common/log/slogger"
+)
+
+const (
+	DefaultRestPort = 80
+
+	// max number of addresses per scan (/16 for IPv4)
+	discoveryMaxAddresses = 65536
+)
+
+type (
+	DiscoveryOpts struct {
+		// networks to scan (eg. 192.168.1.0/24 or single addresses)
+		Networks []string
+
+		RestPort      int
+		WebsocketPort int
+
+		// credentials for systems which require authentication (only sent if password is set)
+		Username string
+		Password string
+
+		UserAgent string
+		Timeout   time.Duration
+		Parallel  int
+	}
+
+	// DiscoveredSystem is an OpenEMS system found by Discover
+	DiscoveredSystem struct {
+		Address string `json:"address"`
+
+		// target url (eg. http://192.168.1.10)
+		Url string `json:"url"`
+
+		EdgeId  string `json:"edgeId"`
+		Version string `json:"version"`
+
+		// reachable apis (rest, websocket)
+		Transports []string `json:"transports"`
+	}
+)
+
+// Discover scans the networks for OpenEMS systems (answering the REST-Api or the JSON-RPC websocket),
+// systems are sorted by address
+func Discover(ctx context.Context, logger *slogger.Logger, opts DiscoveryOpts) ([]*DiscoveredSystem, error) {
+	addresses, err := discoveryAddresses(opts.Networks)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []*DiscoveredSystem{}
+	lock := sync.Mutex{}
+
+	wg := sizedwaitgroup.New(max(opts.Parallel, 1))
+	for _, address := range addresses {
+		wg.Add()
+		go func() {
+			defer wg.Done()
+
+			if system := discoverSystem(ctx, logger, address, opts); system != nil {
+				lock.Lock()
+				defer lock.Unlock()
+				ret = append(ret, system)
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(ret, func(a, b *DiscoveredSystem) int {
+		return netip.MustParseAddr(a.Address).Compare(netip.MustParseAddr(b.Address))
+	})
+
+	return ret, nil
+}
+
+// discoveryAddresses returns all host addresses of the networks (without network and broadcast address)
+func discoveryAddresses(networks []string) ([]netip.Addr, error) {
+	ret := []netip.Addr{}
+
+	for _, network := range networks {
+		if !strings.Contains(network, "/") {
+			address, err := netip.ParseAddr(network)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid address "%v": %w`, network, err)
+			}
+			ret = append(ret, address)
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid network "%v": %w`, network, err)
+		}
+		prefix = prefix.Masked()
+
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits > 16 {
+			return nil, fmt.Errorf(`network "%v" is too large (max %v addresses)`, network, discoveryMaxAddresses)
+		}
+
+		addresses := []netip.Addr{}
+		for address := prefix.Addr(); prefix.Contains(address); address = address.Next() {
+			addresses = append(addresses, address)
+		}
+
+		// network and broadcast address are no hosts (except /31 and /32)
+		if prefix.Addr().Is4() && hostBits >= 2 {
+			addresses = addresses[1 : len(addresses)-1]
+		}
+
+		ret = append(ret, addresses...)
+	}
+
+	if len(ret) > discoveryMaxAddresses {
+		return nil, fmt.Errorf(`too many addresses to scan (%v, max %v)`, len(ret), discoveryMaxAddresses)
+	}
+
+	return ret, nil
+}
+
+// discoverSystem checks the REST-Api and the JSON-RPC websocket of the address, nil if no OpenEMS system was found.
+// The REST-Api is queried anonymously first, credentials are only sent if configured and required by the system,
+// the websocket always requires authentication and is only checked with configured credentials
+func discoverSystem(ctx context.Context, logger *slogger.Logger, address netip.Addr, opts DiscoveryOpts) *DiscoveredSystem {
+	system := DiscoveredSystem{
+		Address:    address.String(),
+		Url:        "http://" + discoveryHost(address, opts.RestPort, DefaultRestPort),
+		Transports: []string{},
+	}
+
+	for _, transportName := range []string{TransportRest, TransportWebsocket} {
+		port := opts.RestPort
+		if transportName == TransportWebsocket {
+			port = opts.WebsocketPort
+		}
+
+		if transportName == TransportWebsocket && opts.Password == "" {
+			continue
+		}
+
+		// fast check before connecting, most addresses don't answer
+		if !discoveryPortOpen(ctx, address, port, opts.Timeout) {
+			continue
+		}
+
+		edgeInfo, err := discoveryIdentify(ctx, logger, system.Url, transportName, opts, transportName == TransportWebsocket)
+		if errors.Is(err, ErrAuthentication) && transportName == TransportRest && opts.Password != "" {
+			edgeInfo, err = discoveryIdentify(ctx, logger, system.Url, transportName, opts, true)
+		}
+		if err != nil {
+			logger.Debug(`no OpenEMS system found`, slog.String("address", system.Address), slog.String("transport", transportName), slog.Any("error", err))
+			continue
+		}
+
+		system.Transports = append(system.Transports, transportName)
+		if system.EdgeId == "" {
+			system.EdgeId = edgeInfo.Id
+		}
+		if system.Version == "" {
+			system.Version = edgeInfo.Version
+		}
+	}
+
+	if len(system.Transports) == 0 {
+		return nil
+	}
+
+	return &system
+}
+
+// discoveryIdentify identifies the system with the transport, credentials are only sent with auth
+func discoveryIdentify(ctx context.Context, logger *slogger.Logger, url, transportName string, opts DiscoveryOpts, auth bool) (*EdgeInfo, error) {
+	fp := New(ctx, prometheus.NewRegistry(), logger)
+	fp.SetUserAgent(opts.UserAgent)
+	fp.SetTimeout(opts.Timeout)
+	fp.SetRetry(0, 0, 0)
+	fp.SetWebsocketPort(opts.WebsocketPort)
+	if auth {
+		fp.SetHttpAuth(opts.Username, opts.Password)
+	}
+	if err := fp.SetTransport(transportName); err != nil {
+		return nil, err
+	}
+
+	return fp.Identify(FeneconProberTarget{Target: url})
+}
+
+// Identify connects to the target and returns the edge id and firmware version, _meta is queried first and
+// _host only on OpenEMS systems, fails if the firmware version is not available (no OpenEMS system)
+func (fp *FeneconProber) Identify(target FeneconProberTarget) (*EdgeInfo, error) {
+	fp.target = target
+
+	transport, err := fp.newTransport(target)
+	if err != nil {
+		return nil, err
+	}
+	fp.transport = transport
+	defer func() {
+		if err := fp.transport.Close(); err != nil {
+			fp.logger.Debug(`failed to close transport`, slog.Any("error", err))
+		}
+	}()
+
+	meta, _, err := fp.transport.Query(fp.ctx, ComponentMeta+"/.*")
+	if err != nil {
+		return nil, err
+	}
+
+	if version := meta.Address(ComponentMeta, "Version").Value.ValueString; version == nil || *version == "" {
+		return nil, errors.New(`unable to query _meta/Version`)
+	}
+
+	host, _ := fp.queryComponent(ComponentHost)
+	return fp.newEdgeInfo(meta, host), nil
+}
+
+func discoveryPortOpen(ctx context.Context, address netip.Addr, port int, timeout time.Duration) bool {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", netip.AddrPortFrom(address, uint16(port)).String()) // #nosec G115 -- port is validated by the caller
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// discoveryHost returns the host with port (without port if it's the default port)
+func discoveryHost(address netip.Addr, port, defaultPort int) string {
+	if port == defaultPort {
+		if address.Is6() {
+			return "[" + address.String() + "]"
+		}
+		return address.String()
+	}
+	return net.JoinHostPort(address.String(), strconv.Itoa(port))
+}
@@ -0,0 +1,121 @@
+package fenecon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiscoverSystem(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		opts        DiscoveryOpts
+		found       bool
+		transports  []string
+		credentials []string
+	}{
+		{
+			name:        "anonymous",
+			found:       true,
+			transports:  []string{TransportRest},
+			credentials: []string{},
+		},
+		{
+			name:        "authentication required without credentials",
+			password:    "user",
+			found:       false,
+			credentials: []string{},
+		},
+		{
+			name:       "authentication required with credentials",
+			password:   "user",
+			opts:       DiscoveryOpts{Username: "x", Password: "user"},
+			found:      true,
+			transports: []string{TransportRest, TransportWebsocket},
+			// REST-Api (_meta, _host) and websocket login
+			credentials: []string{"x:user", "x:user", "x:user"},
+		},
+		{
+			name:       "anonymous with credentials",
+			opts:       DiscoveryOpts{Username: "x", Password: "user"},
+			found:      true,
+			transports: []string{TransportRest, TransportWebsocket},
+			// websocket login only
+			credentials: []string{"x:user"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newFakeOpenEMS()
+			fake.password = test.password
+
+			opts := test.opts
+			opts.RestPort = testServerPort(t, fake.startRest(t).URL)
+			opts.WebsocketPort = testServerPort(t, fake.startWebsocket(t).URL)
+			opts.Timeout = 5 * time.Second
+
+			system := discoverSystem(context.Background(), newTestLogger(), netip.MustParseAddr("127.0.0.1"), opts)
+			if (system != nil) != test.found {
+				t.Fatalf("expected found %v, got %+v", test.found, system)
+			}
+
+			if system != nil {
+				if system.Version != "2024.10.1" {
+					t.Errorf("expected version 2024.10.1, got %q", system.Version)
+				}
+				if !slices.Equal(system.Transports, test.transports) {
+					t.Errorf("expected transports %v, got %v", test.transports, system.Transports)
+				}
+			}
+
+			credentials := fake.receivedCredentials()
+			if !slices.Equal(credentials, test.credentials) {
+				t.Errorf("expected credentials %v, got %v", test.credentials, credentials)
+			}
+		})
+	}
+}
+
+func TestDiscoverSystemNoOpenEMS(t *testing.T) {
+	authorization := []string{}
+	lock := sync.Mutex{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	opts := DiscoveryOpts{
+		RestPort: testServerPort(t, server.URL),
+		Username: "x",
+		Password: "user",
+		Timeout:  5 * time.Second,
+	}
+	// websocket port is not listening
+	opts.WebsocketPort = opts.RestPort + 1
+
+	if system := discoverSystem(context.Background(), newTestLogger(), netip.MustParseAddr("127.0.0.1"), opts); system != nil {
+		t.Fatalf("expected no system, got %+v", system)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(authorization) == 0 {
+		t.Fatal("expected anonymous identification request")
+	}
+	for _, header := range authorization {
+		if header != "" {
+			t.Errorf("expected no credentials, got %q", header)
+		}
+	}
+}
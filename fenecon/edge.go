@@ -0,0 +1,131 @@
+package fenecon
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	ComponentMeta = "_meta"
+	ComponentHost = "_host"
+)
+
+type (
+	// EdgeInfo identifies the OpenEMS edge of the target (empty if not available)
+	EdgeInfo struct {
+		// edge id (websocket login, hostname as fallback, eg. fems12345)
+		Id string
+
+		// firmware version (_meta/Version)
+		Version string
+
+		// hostname (_host/Hostname)
+		Hostname string
+	}
+)
+
+// EdgeInfo returns the edge id, firmware version and hostname of the last probe (nil if not probed)
+func (fp *FeneconProber) EdgeInfo() *EdgeInfo {
+	return fp.edgeInfo
+}
+
+// collectEdgeInfo sets fenecon_info (with version, edge id and hostname) and the host metrics from the _meta and _host
+// components, failures are not probe errors as the components are not available on all firmware versions
+func (fp *FeneconProber) collectEdgeInfo() {
+	info, host := fp.fetchEdgeInfo()
+	fp.edgeInfo = info
+
+	fp.prometheus.info.With(prometheus.Labels{
+		"target":   fp.target.Target,
+		"module":   "_sum",
+		"version":  info.Version,
+		"edge_id":  info.Id,
+		"hostname": info.Hostname,
+	}).Set(1)
+
+	fp.collectHost(host)
+}
+
+// fetchEdgeInfo returns the edge info and the _host channels (cached per target with the component configuration
+// if a cache is set, failed queries are not cached)
+func (fp *FeneconProber) fetchEdgeInfo() (*EdgeInfo, *ResultWildcard) {
+	if fp.componentCache != nil {
+		if info, host := fp.componentCache.GetEdgeInfo(fp.target.Target); info != nil {
+			return info, host
+		}
+	}
+
+	meta, metaErr := fp.queryComponent(ComponentMeta)
+	host, hostErr := fp.queryComponent(ComponentHost)
+	info := fp.newEdgeInfo(meta, host)
+
+	if fp.componentCache != nil && metaErr == nil && hostErr == nil {
+		fp.componentCache.SetEdgeInfo(fp.target.Target, info, host)
+	}
+
+	return info, host
+}
+
+// newEdgeInfo builds the edge info from the _meta and _host components and the websocket login
+func (fp *FeneconProber) newEdgeInfo(meta, host *ResultWildcard) *EdgeInfo {
+	info := EdgeInfo{}
+
+	if value := meta.Address(ComponentMeta, "Version").Value.ValueString; value != nil {
+		info.Version = *value
+	}
+
+	if value := host.Address(ComponentHost, "Hostname").Value.ValueString; value != nil {
+		info.Hostname = *value
+	}
+
+	// local edge is always "0" and doesn't identify the system
+	if ws, ok := fp.transport.(*websocketTransport); ok && ws.edgeId != "0" {
+		info.Id = ws.edgeId
+	}
+	if info.Id == "" {
+		info.Id = info.Hostname
+	}
+
+	return &info
+}
+
+// collectHost exports the numeric channels (eg. disk and memory usage, depending on firmware) and the string channels
+// (eg. ip addresses and network interfaces) of the _host component
+func (fp *FeneconProber) collectHost(result *ResultWildcard) {
+	for _, row := range *result {
+		_, channel := splitAddress(row.Address)
+
+		switch {
+		case row.Value.ValueNumeric != nil:
+			row.SetGauge(prometheus.Labels{
+				"target":  fp.target.Target,
+				"channel": channel,
+				"unit":    row.Unit,
+			}, fp.prometheus.host.value)
+		case row.Value.ValueString != nil:
+			fp.prometheus.host.info.With(prometheus.Labels{
+				"target":  fp.target.Target,
+				"channel": channel,
+				"value":   *row.Value.ValueString,
+			}).Set(1)
+		}
+	}
+}
+
+// queryComponent queries all channels of the component (recorded as query but not as probe error),
+// returns an empty result if the component is not available
+func (fp *FeneconProber) queryComponent(component string) (*ResultWildcard, error) {
+	query := component + "/.*"
+	startTime := time.Now()
+
+	result, statusCode, err := fp.transport.Query(fp.ctx, query)
+	fp.recordQueryResult(query, startTime, statusCode, err)
+	if err != nil || result == nil {
+		fp.logger.Debug(`unable to query component`, slog.String("component", component), slog.Any("error", err))
+		return &ResultWildcard{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package fenecon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollectEdgeInfoCached(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.components[ComponentHost] = fakeComponent{
+		Channels: map[string]fakeChannel{
+			"Hostname":   {Type: "STRING", Value: "fems12345"},
+			"DiskIsFull": {Type: "BOOLEAN", Value: 0},
+		},
+	}
+	server := fake.startRest(t)
+	cache := NewComponentCache(time.Hour)
+
+	for i := range 2 {
+		fp, registry := newTestProber(t, "sum")
+		fp.SetComponentCache(cache)
+		if err := fp.Run(FeneconProberTarget{Target: server.URL}); err != nil {
+			t.Fatalf("probe %v failed: %v", i, err)
+		}
+
+		info := gatherMetrics(t, registry, "fenecon_info")
+		if info["edge_id=fems12345,hostname=fems12345,module=_sum,version=2024.10.1"] != 1 {
+			t.Errorf("probe %v: unexpected fenecon_info %v", i, info)
+		}
+
+		host := gatherMetrics(t, registry, "fenecon_host_value")
+		if _, exists := host["channel=DiskIsFull,unit="]; !exists {
+			t.Errorf("probe %v: expected DiskIsFull host channel, got %v", i, host)
+		}
+
+		querySuccess := gatherMetrics(t, registry, "fenecon_probe_query_success")
+		if i == 0 && (querySuccess["query=_meta/.*"] != 1 || querySuccess["query=_host/.*"] != 1) {
+			t.Errorf("expected recorded _meta and _host queries, got %v", querySuccess)
+		}
+	}
+
+	// second probe is served from the cache
+	counts := map[string]int{}
+	for _, query := range fake.receivedQueries() {
+		counts[query]++
+	}
+	if counts["_meta/.*"] != 1 || counts["_host/.*"] != 1 {
+		t.Errorf("expected one query of _meta and _host, got %v", counts)
+	}
+}
+
+func TestCollectEdgeInfoFailureNotCached(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.password = "secret"
+	server := fake.startRest(t)
+	cache := NewComponentCache(time.Hour)
+
+	fp, registry := newTestProber(t, "sum")
+	fp.SetComponentCache(cache)
+	_ = fp.Run(FeneconProberTarget{Target: server.URL})
+
+	querySuccess := gatherMetrics(t, registry, "fenecon_probe_query_success")
+	if value, exists := querySuccess["query=_meta/.*"]; !exists || value != 0 {
+		t.Errorf("expected failed _meta query, got %v", querySuccess)
+	}
+
+	queryError := gatherMetrics(t, registry, "fenecon_probe_query_error")
+	if queryError["class=auth,query=_meta/.*"] != 1 {
+		t.Errorf("expected auth error of _meta query, got %v", queryError)
+	}
+
+	if info, _ := cache.GetEdgeInfo(server.URL); info != nil {
+		t.Errorf("expected failed edge info not to be cached, got %+v", info)
+	}
+}
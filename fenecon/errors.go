@@ -0,0 +1,63 @@
+package fenecon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+)
+
+const (
+	ErrorClassAuth       = "auth"
+	ErrorClassTimeout    = "timeout"
+	ErrorClassConnection = "connection"
+	ErrorClassHttp       = "http"
+	ErrorClassDecode     = "decode"
+	ErrorClassJsonRpc    = "jsonrpc"
+	ErrorClassUnknown    = "unknown"
+)
+
+var (
+	ErrAuthentication = errors.New(`fenecon requires authentication or credentials are invalid`)
+	ErrHttpStatus     = errors.New(`unexpected http status`)
+	ErrTimeout        = errors.New(`timeout`)
+)
+
+// ErrorClass classifies the error (eg. auth, timeout, decode) for metrics
+func ErrorClass(err error) string {
+	var (
+		jsonSyntaxError  *json.SyntaxError
+		jsonTypeError    *json.UnmarshalTypeError
+		jsonRpcError     *JsonRpcError
+		netError         net.Error
+		netOpError       *net.OpError
+		netDnsError      *net.DNSError
+		jsonInvalidError *json.InvalidUnmarshalError
+	)
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrAuthentication):
+		return ErrorClassAuth
+	case errors.Is(err, ErrTimeout),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netError) && netError.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, ErrHttpStatus):
+		return ErrorClassHttp
+	case errors.As(err, &jsonSyntaxError),
+		errors.As(err, &jsonTypeError),
+		errors.As(err, &jsonInvalidError):
+		return ErrorClassDecode
+	case errors.As(err, &jsonRpcError):
+		return ErrorClassJsonRpc
+	case errors.As(err, &netOpError),
+		errors.As(err, &netDnsError):
+		return ErrorClassConnection
+	default:
+		return ErrorClassUnknown
+	}
+}
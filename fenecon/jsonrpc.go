@@ -0,0 +1,86 @@
+package fenecon
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	JsonRpcVersion = "2.0"
+)
+
+type (
+	JsonRpcRequest struct {
+		JsonRpc string      `json:"jsonrpc"`
+		Id      string      `json:"id,omitempty"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params"`
+	}
+
+	JsonRpcMessage struct {
+		JsonRpc string          `json:"jsonrpc"`
+		Id      string          `json:"id,omitempty"`
+		Method  string          `json:"method,omitempty"`
+		Params  json.RawMessage `json:"params,omitempty"`
+		Result  json.RawMessage `json:"result,omitempty"`
+		Error   *JsonRpcError   `json:"error,omitempty"`
+	}
+
+	JsonRpcError struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data,omitempty"`
+	}
+
+	// EdgeRpcParams wraps a request/notification for a specific edge (method "edgeRpc")
+	EdgeRpcParams struct {
+		EdgeId  string      `json:"edgeId"`
+		Payload interface{} `json:"payload"`
+	}
+
+	EdgeConfig struct {
+		Components map[string]EdgeConfigComponent `json:"components"`
+	}
+
+	EdgeConfigComponent struct {
+		Alias     string                       `json:"alias"`
+		FactoryId string                       `json:"factoryId"`
+		Channels  map[string]EdgeConfigChannel `json:"channels"`
+	}
+
+	EdgeConfigChannel struct {
+		Type       string `json:"type"`
+		AccessMode string `json:"accessMode"`
+		Unit       string `json:"unit"`
+		Text       string `json:"text"`
+		Category   string `json:"category"`
+		Level      string `json:"level"`
+	}
+)
+
+func (e *JsonRpcError) Error() string {
+	return fmt.Sprintf(`jsonrpc error %v: %v`, e.Code, e.Message)
+}
+
+func newJsonRpcRequest(method string, params interface{}) JsonRpcRequest {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+
+	return JsonRpcRequest{
+		JsonRpc: JsonRpcVersion,
+		Id:      newJsonRpcId(),
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// newJsonRpcId generates a random UUID (v4), OpenEMS requires request ids to be UUIDs
+func newJsonRpcId() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
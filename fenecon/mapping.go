@@ -0,0 +1,212 @@
+package fenecon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	yaml "go.yaml.in/yaml/v3"
+)
+
+const (
+	MappingTypeGauge   = "gauge"
+	MappingTypeCounter = "counter"
+)
+
+var (
+	mappingMetricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	mappingLabelNameRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	// named groups are not supported by the OpenEMS REST-Api (java regex syntax differs)
+	mappingNamedGroupRegexp = regexp.MustCompile(`\(\?P?<[a-zA-Z0-9_]+>`)
+
+	// labels which are set for every mapped metric
+	mappingCommonLabels = []string{"target", "module"}
+)
+
+type (
+	// Mapping maps additional channels to metrics (on top of the built-in query groups)
+	Mapping struct {
+		Metrics []*MappingMetric `yaml:"metrics"`
+	}
+
+	MappingMetric struct {
+		// channel address regex (eg. ctrlEssLimiter0/(?P<channel>.*)), named groups are used as labels
+		Channel string `yaml:"channel"`
+
+		// wildcard query (eg. ctrlEssLimiter0/.*), defaults to all channels of the component
+		Query string `yaml:"query"`
+
+		Name   string            `yaml:"name"`
+		Help   string            `yaml:"help"`
+		Type   string            `yaml:"type"`
+		Scale  float64           `yaml:"scale"`
+		Labels map[string]string `yaml:"labels"`
+
+		channelRegexp *regexp.Regexp
+		labelNames    []string
+	}
+)
+
+// LoadMappingFile loads and validates the mapping file
+func LoadMappingFile(path string) (*Mapping, error) {
+	// #nosec G304 -- path is passed by the user
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to read mapping file "%v": %w`, path, err)
+	}
+
+	mapping := Mapping{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&mapping); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf(`unable to parse mapping file "%v": %w`, path, err)
+	}
+
+	if err := mapping.Validate(); err != nil {
+		return nil, fmt.Errorf(`invalid mapping file "%v": %w`, path, err)
+	}
+
+	return &mapping, nil
+}
+
+// Validate validates and compiles all mapped metrics, metric collisions are checked by SetMapping
+func (m *Mapping) Validate() error {
+	for i, metric := range m.Metrics {
+		if err := metric.Validate(); err != nil {
+			return fmt.Errorf(`metric #%v "%v": %w`, i, metric.Name, err)
+		}
+	}
+	return nil
+}
+
+// Queries returns all distinct wildcard queries of the mapping
+func (m *Mapping) Queries() []string {
+	ret := []string{}
+	for _, metric := range m.Metrics {
+		if !slices.Contains(ret, metric.Query) {
+			ret = append(ret, metric.Query)
+		}
+	}
+	return ret
+}
+
+// Validate validates the metric and sets defaults
+func (m *MappingMetric) Validate() error {
+	if !mappingMetricNameRegexp.MatchString(m.Name) {
+		return errors.New(`name is missing or invalid`)
+	}
+
+	component, _, found := strings.Cut(m.Channel, "/")
+	if !found || component == "" {
+		return fmt.Errorf(`channel "%v" must be in format component/channel`, m.Channel)
+	}
+
+	channelRegexp, err := regexp.Compile(`^(?:` + m.Channel + `)$`)
+	if err != nil {
+		return fmt.Errorf(`channel "%v" is not a valid regex: %w`, m.Channel, err)
+	}
+	m.channelRegexp = channelRegexp
+
+	if m.Query == "" {
+		m.Query = mappingNamedGroupRegexp.ReplaceAllString(component, "(") + "/.*"
+	}
+
+	switch m.Type {
+	case "":
+		m.Type = MappingTypeGauge
+	case MappingTypeGauge, MappingTypeCounter:
+	default:
+		return fmt.Errorf(`unsupported type "%v" (available: %v, %v)`, m.Type, MappingTypeGauge, MappingTypeCounter)
+	}
+
+	if m.Scale == 0 {
+		m.Scale = 1
+	}
+
+	if m.Help == "" {
+		m.Help = fmt.Sprintf("Fenecon mapped channel (%v)", m.Channel)
+	}
+
+	m.labelNames = slices.Clone(mappingCommonLabels)
+	for _, name := range channelRegexp.SubexpNames() {
+		if name == "" {
+			continue
+		}
+
+		if !mappingLabelNameRegexp.MatchString(name) || slices.Contains(m.labelNames, name) {
+			return fmt.Errorf(`regex group "%v" is an invalid or duplicate label name`, name)
+		}
+		m.labelNames = append(m.labelNames, name)
+	}
+
+	for name := range m.Labels {
+		if !mappingLabelNameRegexp.MatchString(name) || slices.Contains(m.labelNames, name) {
+			return fmt.Errorf(`label "%v" is an invalid or duplicate label name`, name)
+		}
+	}
+
+	return nil
+}
+
+func (m *MappingMetric) valueType() prometheus.ValueType {
+	if m.Type == MappingTypeCounter {
+		return prometheus.CounterValue
+	}
+	return prometheus.GaugeValue
+}
+
+// SetMapping registers the mapped metrics, fails on duplicate metric names or inconsistent labels
+func (fp *FeneconProber) SetMapping(mapping *Mapping) error {
+	metrics := make([]*constMetricVec, len(mapping.Metrics))
+	for i, metric := range mapping.Metrics {
+		metrics[i] = newConstMetricVec(metric.Name, metric.Help, metric.valueType(), metric.labelNames, metric.Labels)
+		if err := fp.registry.Register(metrics[i]); err != nil {
+			return fmt.Errorf(`mapping metric "%v": %w`, metric.Name, err)
+		}
+	}
+
+	fp.mapping = mapping
+	fp.mappingMetrics = metrics
+	return nil
+}
+
+// collectMapping sets the mapped metrics for all channels of the query result
+func (fp *FeneconProber) collectMapping(query string, result *ResultWildcard) {
+	if fp.mapping == nil {
+		return
+	}
+
+	for i, metric := range fp.mapping.Metrics {
+		if metric.Query != query {
+			continue
+		}
+
+		for _, row := range *result {
+			if row.Value.ValueNumeric == nil {
+				continue
+			}
+
+			match := metric.channelRegexp.FindStringSubmatch(row.Address)
+			if match == nil {
+				continue
+			}
+
+			component, _ := splitAddress(row.Address)
+			labels := prometheus.Labels{"target": fp.target.Target, "module": component}
+			for j, name := range metric.channelRegexp.SubexpNames() {
+				if name != "" {
+					labels[name] = match[j]
+				}
+			}
+
+			fp.mappingMetrics[i].Set(labels, *row.Value.ValueNumeric*metric.Scale)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package fenecon
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadMappingFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "valid", content: "metrics:\n  - channel: 'ctrlEssLimiter0/(?P<channel>.*Power)'\n    name: fenecon_ess_limiter_power\n"},
+		{name: "unknown field", content: "metrics:\n  - channel: 'ctrlEssLimiter0/ActivePower'\n    name: fenecon_ess_limiter_power\n    unit: W\n", err: "field unit not found"},
+		{name: "missing name", content: "metrics:\n  - channel: 'ctrlEssLimiter0/ActivePower'\n", err: "name is missing or invalid"},
+		{name: "invalid name", content: "metrics:\n  - channel: 'ctrlEssLimiter0/ActivePower'\n    name: fenecon-power\n", err: "name is missing or invalid"},
+		{name: "channel without component", content: "metrics:\n  - channel: 'ActivePower'\n    name: fenecon_power\n", err: "must be in format component/channel"},
+		{name: "invalid regex", content: "metrics:\n  - channel: 'ctrl(/ActivePower'\n    name: fenecon_power\n", err: "is not a valid regex"},
+		{name: "unsupported type", content: "metrics:\n  - channel: 'ctrl0/ActivePower'\n    name: fenecon_power\n    type: histogram\n", err: `unsupported type "histogram"`},
+		{name: "reserved label group", content: "metrics:\n  - channel: '(?P<module>ctrl0)/ActivePower'\n    name: fenecon_power\n", err: `regex group "module"`},
+		{name: "duplicate static label", content: "metrics:\n  - channel: 'ctrl0/(?P<channel>.*)'\n    name: fenecon_power\n    labels:\n      channel: power\n", err: `label "channel"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mapping.yaml")
+			if err := os.WriteFile(path, []byte(test.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadMappingFile(path)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestMappingMetricDefaults(t *testing.T) {
+	metric := MappingMetric{Channel: `meter(?P<meter>\d+)/ActiveConsumptionEnergy`, Name: "fenecon_meter_consumption"}
+	if err := metric.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// named groups are removed from the default query (not supported by OpenEMS)
+	if metric.Query != `meter(\d+)/.*` {
+		t.Errorf("unexpected default query %v", metric.Query)
+	}
+	if metric.Type != MappingTypeGauge || metric.Scale != 1 || metric.Help == "" {
+		t.Errorf("unexpected defaults type %v, scale %v, help %q", metric.Type, metric.Scale, metric.Help)
+	}
+	if !slices.Equal(metric.labelNames, []string{"target", "module", "meter"}) {
+		t.Errorf("unexpected label names %v", metric.labelNames)
+	}
+}
+
+func TestCollectMapping(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.components["ctrlEssLimiter0"] = fakeComponent{
+		Channels: map[string]fakeChannel{
+			"ActivePowerLimit":    {Type: "INTEGER", Unit: "W", Value: 5000},
+			"ReactivePowerLimit":  {Type: "INTEGER", Unit: "var", Value: 1000},
+			"StateMachine":        {Type: "STRING", Value: "RUNNING"},
+			"MinActivePowerLimit": {Type: "INTEGER", Unit: "W", Value: nil},
+		},
+	}
+
+	mapping := &Mapping{Metrics: []*MappingMetric{
+		{Channel: "ctrlEssLimiter0/(?P<channel>.*ActivePowerLimit)", Name: "fenecon_ess_limiter_power", Labels: map[string]string{"source": "mapping"}},
+		{Channel: "_sum/GridBuyActiveEnergy", Query: "_sum/.*", Name: "fenecon_grid_buy_kwh_total", Type: MappingTypeCounter, Scale: 0.001},
+	}}
+	if err := mapping.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	fp, registry := newTestProber(t, QueryGroupSum)
+	if err := fp.SetMapping(mapping); err != nil {
+		t.Fatal(err)
+	}
+	if err := fp.Run(FeneconProberTarget{Target: fake.startRest(t).URL}); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+
+	// only channels with numeric values (no strings, no null values), static labels are const labels
+	assertMetrics(t, registry, "fenecon_ess_limiter_power", map[string]float64{
+		"channel=ActivePowerLimit,module=ctrlEssLimiter0,source=mapping": 5000,
+	})
+	assertMetrics(t, registry, "fenecon_grid_buy_kwh_total", map[string]float64{"module=_sum": 123.456})
+
+	// query of enabled query group is not requested twice
+	queries := fake.receivedQueries()
+	for _, query := range []string{"_sum/.*", "ctrlEssLimiter0/.*"} {
+		if count := len(slices.DeleteFunc(slices.Clone(queries), func(val string) bool { return val != query })); count != 1 {
+			t.Errorf("expected query %v once, got %v", query, queries)
+		}
+	}
+}
+
+func TestSetMappingCollision(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics []*MappingMetric
+	}{
+		{name: "built-in metric", metrics: []*MappingMetric{{Channel: "ctrl0/(?P<channel>.*)", Name: "fenecon_grid_power"}}},
+		{name: "mapped metric with other labels", metrics: []*MappingMetric{
+			{Channel: "ctrl0/ActivePower", Name: "fenecon_ctrl_power"},
+			{Channel: "ctrl1/(?P<channel>.*)", Name: "fenecon_ctrl_power"},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapping := &Mapping{Metrics: test.metrics}
+			if err := mapping.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			fp, _ := newTestProber(t)
+			if err := fp.SetMapping(mapping); err == nil {
+				t.Error("expected collision error")
+			}
+		})
+	}
+}
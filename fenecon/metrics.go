@@ -6,8 +6,29 @@ import (
 
 type (
 	feneconMetrics struct {
-		info   *prometheus.GaugeVec
-		status *prometheus.GaugeVec
+		info      *prometheus.GaugeVec
+		component *prometheus.GaugeVec
+		status    *prometheus.GaugeVec
+		fault     *prometheus.GaugeVec
+
+		channel struct {
+			value *prometheus.GaugeVec
+			info  *prometheus.GaugeVec
+		}
+
+		host struct {
+			value *prometheus.GaugeVec
+			info  *prometheus.GaugeVec
+		}
+
+		probe struct {
+			success         *prometheus.GaugeVec
+			duration        *prometheus.GaugeVec
+			querySuccess    *prometheus.GaugeVec
+			queryDuration   *prometheus.GaugeVec
+			queryHttpStatus *prometheus.GaugeVec
+			queryError      *prometheus.GaugeVec
+		}
 
 		meter struct {
 			frequency             *prometheus.GaugeVec
@@ -23,6 +44,17 @@ type (
 			maxActivePower        *prometheus.GaugeVec
 			powerProductionTotal  *prometheus.GaugeVec
 			powerConsumptionTotal *prometheus.GaugeVec
+
+			// normalized to base units
+			frequencyHertz      *prometheus.GaugeVec
+			voltageVolts        *prometheus.GaugeVec
+			voltagePhaseVolts   *prometheus.GaugeVec
+			currentAmperes      *prometheus.GaugeVec
+			currentPhaseAmperes *prometheus.GaugeVec
+
+			// counters in Watthours
+			productionEnergy  *constMetricVec
+			consumptionEnergy *constMetricVec
 		}
 
 		battery struct {
@@ -36,6 +68,42 @@ type (
 			powerDcDischargeTotal *prometheus.GaugeVec
 			allowedChargePower    *prometheus.GaugeVec
 			allowedDischargePower *prometheus.GaugeVec
+
+			// normalized to base units
+			capacityWattHours *prometheus.GaugeVec
+
+			// counters in Watthours
+			chargeEnergy      *constMetricVec
+			dischargeEnergy   *constMetricVec
+			dcChargeEnergy    *constMetricVec
+			dcDischargeEnergy *constMetricVec
+		}
+
+		batteryCell struct {
+			soh                 *prometheus.GaugeVec
+			voltage             *prometheus.GaugeVec
+			voltageMin          *prometheus.GaugeVec
+			voltageMax          *prometheus.GaugeVec
+			voltageSpread       *prometheus.GaugeVec
+			temperature         *prometheus.GaugeVec
+			temperatureMin      *prometheus.GaugeVec
+			temperatureMax      *prometheus.GaugeVec
+			temperatureSpread   *prometheus.GaugeVec
+			chargeMaxCurrent    *prometheus.GaugeVec
+			dischargeMaxCurrent *prometheus.GaugeVec
+			towerCycles         *prometheus.GaugeVec
+
+			// normalized to base units
+			voltageVolts               *prometheus.GaugeVec
+			voltageMinVolts            *prometheus.GaugeVec
+			voltageMaxVolts            *prometheus.GaugeVec
+			voltageSpreadVolts         *prometheus.GaugeVec
+			temperatureCelsius         *prometheus.GaugeVec
+			temperatureMinCelsius      *prometheus.GaugeVec
+			temperatureMaxCelsius      *prometheus.GaugeVec
+			temperatureSpreadCelsius   *prometheus.GaugeVec
+			chargeMaxCurrentAmperes    *prometheus.GaugeVec
+			dischargeMaxCurrentAmperes *prometheus.GaugeVec
 		}
 
 		grid struct {
@@ -44,6 +112,10 @@ type (
 			powerPhase     *prometheus.GaugeVec
 			powerBuyTotal  *prometheus.GaugeVec
 			powerSellTotal *prometheus.GaugeVec
+
+			// counters in Watthours
+			buyEnergy  *constMetricVec
+			sellEnergy *constMetricVec
 		}
 
 		production struct {
@@ -55,12 +127,77 @@ type (
 			powerAcTotal   *prometheus.GaugeVec
 			powerDcTotal   *prometheus.GaugeVec
 			maxActualPower *prometheus.GaugeVec
+
+			// counters in Watthours
+			energy   *constMetricVec
+			energyAc *constMetricVec
+			energyDc *constMetricVec
 		}
 
 		consumption struct {
 			power      *prometheus.GaugeVec
 			powerPhase *prometheus.GaugeVec
 			powerTotal *prometheus.GaugeVec
+
+			// counters in Watthours
+			energy *constMetricVec
+		}
+
+		energyPeriod *prometheus.GaugeVec
+
+		cost struct {
+			// counters in currency
+			total *constMetricVec
+			price *prometheus.GaugeVec
+			saved *prometheus.GaugeVec
+		}
+
+		derived struct {
+			autarky         *prometheus.GaugeVec
+			selfConsumption *prometheus.GaugeVec
+			energyFlow      *prometheus.GaugeVec
+		}
+
+		evcs struct {
+			power                 *prometheus.GaugeVec
+			powerConsumptionTotal *prometheus.GaugeVec
+			energySession         *prometheus.GaugeVec
+			status                *prometheus.GaugeVec
+			phases                *prometheus.GaugeVec
+			chargingType          *prometheus.GaugeVec
+			setChargePowerLimit   *prometheus.GaugeVec
+			minHardwarePower      *prometheus.GaugeVec
+			maxHardwarePower      *prometheus.GaugeVec
+
+			// normalized to base units
+			energySessionWattHours *prometheus.GaugeVec
+
+			// counters in Watthours
+			consumptionEnergy *constMetricVec
+		}
+
+		controller struct {
+			runFailed            *prometheus.GaugeVec
+			stateMachine         *prometheus.GaugeVec
+			timeOfUseState       *prometheus.GaugeVec
+			reserveSoc           *prometheus.GaugeVec
+			delayChargeState     *prometheus.GaugeVec
+			delayChargeLimit     *prometheus.GaugeVec
+			sellToGridLimitState *prometheus.GaugeVec
+			sellToGridLimit      *prometheus.GaugeVec
+			property             *prometheus.GaugeVec
+			propertyInfo         *prometheus.GaugeVec
+		}
+
+		schedule struct {
+			price                *prometheus.GaugeVec
+			slotPrice            *prometheus.GaugeVec
+			slotState            *prometheus.GaugeVec
+			slotGridPower        *prometheus.GaugeVec
+			slotProductionPower  *prometheus.GaugeVec
+			slotConsumptionPower *prometheus.GaugeVec
+			slotEssPower         *prometheus.GaugeVec
+			slotSoc              *prometheus.GaugeVec
 		}
 	}
 )
@@ -68,6 +205,60 @@ type (
 func (fp *FeneconProber) initMetrics() {
 	commonLabels := []string{"target", "module"}
 	phaseLabels := []string{"target", "module", "phase"}
+	queryLabels := []string{"target", "query"}
+	towerLabels := []string{"target", "module", "tower"}
+	cellLabels := []string{"target", "module", "tower", "battery_module", "cell"}
+
+	// ##########################################
+	// Probe
+
+	fp.newGaugeVec(&fp.prometheus.probe.success, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_probe_success",
+			Help: "Fenecon probe success (1=all queries successful, 0=at least one query failed)",
+		},
+		[]string{"target"},
+	))
+
+	fp.newGaugeVec(&fp.prometheus.probe.duration, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_probe_duration_seconds",
+			Help: "Fenecon probe duration in seconds",
+		},
+		[]string{"target"},
+	))
+
+	fp.newGaugeVec(&fp.prometheus.probe.querySuccess, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_probe_query_success",
+			Help: "Fenecon probe query success (1=successful, 0=failed)",
+		},
+		queryLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.probe.queryDuration, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_probe_query_duration_seconds",
+			Help: "Fenecon probe query duration in seconds",
+		},
+		queryLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.probe.queryHttpStatus, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_probe_query_http_status",
+			Help: "Fenecon probe query http status code (only for rest transport)",
+		},
+		queryLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.probe.queryError, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_probe_query_error",
+			Help: "Fenecon probe query error (class: auth, timeout, connection, http, decode, jsonrpc, unknown)",
+		},
+		append(queryLabels, "class"),
+	))
 
 	// ##########################################
 	// Info
@@ -75,9 +266,36 @@ func (fp *FeneconProber) initMetrics() {
 	fp.newGaugeVec(&fp.prometheus.info, prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "fenecon_info",
-			Help: "Fenecon info",
+			Help: "Fenecon info with firmware version (_meta/Version), edge id and hostname (_host/Hostname)",
 		},
-		commonLabels,
+		[]string{"target", "module", "version", "edge_id", "hostname"},
+	))
+
+	fp.newGaugeVec(&fp.prometheus.component, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_component_info",
+			Help: "Fenecon component info with alias and factory id (getEdgeConfig)",
+		},
+		[]string{"target", "module", "alias", "factory"},
+	))
+
+	// ##########################################
+	// Host
+
+	fp.newGaugeVec(&fp.prometheus.host.value, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_host_value",
+			Help: "Fenecon numeric channel value of the host (_host, eg. disk and memory usage, available channels depend on firmware)",
+		},
+		[]string{"target", "channel", "unit"},
+	))
+
+	fp.newGaugeVec(&fp.prometheus.host.info, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_host_info",
+			Help: "Fenecon string channel value of the host (_host, eg. ip addresses and network interfaces)",
+		},
+		[]string{"target", "channel", "value"},
 	))
 
 	// ##########################################
@@ -91,6 +309,36 @@ func (fp *FeneconProber) initMetrics() {
 		commonLabels,
 	))
 
+	// ##########################################
+	// Faults
+
+	fp.newGaugeVec(&fp.prometheus.fault, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_fault_active",
+			Help: "Fenecon active fault/warning state channel (only active state channels are exported)",
+		},
+		[]string{"target", "module", "channel", "level", "text"},
+	))
+
+	// ##########################################
+	// Channels (raw mode)
+
+	fp.newGaugeVec(&fp.prometheus.channel.value, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_channel_value",
+			Help: "Fenecon numeric channel value as reported by OpenEMS (raw mode, description in fenecon_channel_info)",
+		},
+		[]string{"target", "component", "channel", "unit"},
+	))
+
+	fp.newGaugeVec(&fp.prometheus.channel.info, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_channel_info",
+			Help: "Fenecon channel info with description and value of string channels (raw mode)",
+		},
+		[]string{"target", "component", "channel", "text", "value"},
+	))
+
 	// ##########################################
 	// Meter
 
@@ -198,6 +446,62 @@ func (fp *FeneconProber) initMetrics() {
 		commonLabels,
 	))
 
+	fp.newConstMetricVec(&fp.prometheus.meter.productionEnergy, newConstMetricVec(
+		"fenecon_meter_production_energy_watt_hours_total",
+		"Fenecon meter production energy in Watthours (ActiveProductionEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	fp.newConstMetricVec(&fp.prometheus.meter.consumptionEnergy, newConstMetricVec(
+		"fenecon_meter_consumption_energy_watt_hours_total",
+		"Fenecon meter consumption energy in Watthours (ActiveConsumptionEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.meter.frequencyHertz, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_meter_frequency_hertz",
+			Help: "Fenecon meter frequency in Hertz (Frequency)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.meter.voltageVolts, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_meter_voltage_volts",
+			Help: "Fenecon meter voltage in Volts (Voltage)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.meter.voltagePhaseVolts, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_meter_voltage_phase_volts",
+			Help: "Fenecon meter voltage in Volts (VoltageLx)",
+		},
+		phaseLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.meter.currentAmperes, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_meter_current_amperes",
+			Help: "Fenecon meter current in Amperes (Current)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.meter.currentPhaseAmperes, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_meter_current_phase_amperes",
+			Help: "Fenecon meter current in Amperes (CurrentLx)",
+		},
+		phaseLabels,
+	))
+
 	// ##########################################
 	// Battery
 
@@ -265,6 +569,38 @@ func (fp *FeneconProber) initMetrics() {
 		commonLabels,
 	))
 
+	fp.newConstMetricVec(&fp.prometheus.battery.chargeEnergy, newConstMetricVec(
+		"fenecon_battery_charge_energy_watt_hours_total",
+		"Fenecon battery charge energy in Watthours (EssActiveChargeEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	fp.newConstMetricVec(&fp.prometheus.battery.dischargeEnergy, newConstMetricVec(
+		"fenecon_battery_discharge_energy_watt_hours_total",
+		"Fenecon battery discharge energy in Watthours (EssActiveDischargeEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	fp.newConstMetricVec(&fp.prometheus.battery.dcChargeEnergy, newConstMetricVec(
+		"fenecon_battery_dc_charge_energy_watt_hours_total",
+		"Fenecon battery dc charge energy in Watthours (EssDcChargeEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	fp.newConstMetricVec(&fp.prometheus.battery.dcDischargeEnergy, newConstMetricVec(
+		"fenecon_battery_dc_discharge_energy_watt_hours_total",
+		"Fenecon battery dc discharge energy in Watthours (EssDcDischargeEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
 	fp.newGaugeVec(&fp.prometheus.battery.allowedChargePower, prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "fenecon_battery_allowed_charge_power",
@@ -281,6 +617,193 @@ func (fp *FeneconProber) initMetrics() {
 		commonLabels,
 	))
 
+	fp.newGaugeVec(&fp.prometheus.battery.capacityWattHours, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_capacity_watt_hours",
+			Help: "Fenecon battery capacity in Watthours (EssCapacity)",
+		},
+		commonLabels,
+	))
+
+	// ##########################################
+	// Battery (cells)
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.soh, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_soh_percent",
+			Help: "Fenecon battery state of health in percent (Soh)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.voltage, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_voltage",
+			Help: "Fenecon battery cell voltage in mV (TowerXModuleYCellZVoltage)",
+		},
+		cellLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.voltageMin, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_voltage_min",
+			Help: "Fenecon battery min cell voltage in mV (MinCellVoltage)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.voltageMax, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_voltage_max",
+			Help: "Fenecon battery max cell voltage in mV (MaxCellVoltage)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.voltageSpread, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_voltage_spread",
+			Help: "Fenecon battery cell voltage spread (max - min) in mV",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.temperature, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_temperature",
+			Help: "Fenecon battery cell temperature in unit of channel (TowerXModuleYCellZTemperature)",
+		},
+		cellLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.temperatureMin, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_temperature_min",
+			Help: "Fenecon battery min cell temperature in degree Celsius (MinCellTemperature)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.temperatureMax, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_temperature_max",
+			Help: "Fenecon battery max cell temperature in degree Celsius (MaxCellTemperature)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.temperatureSpread, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_temperature_spread",
+			Help: "Fenecon battery cell temperature spread (max - min) in degree Celsius",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.chargeMaxCurrent, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_charge_max_current",
+			Help: "Fenecon battery max charge current in A (ChargeMaxCurrent)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.dischargeMaxCurrent, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_discharge_max_current",
+			Help: "Fenecon battery max discharge current in A (DischargeMaxCurrent)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.towerCycles, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_tower_cycles",
+			Help: "Fenecon battery tower number of cycles (TowerXNoOfCycles)",
+		},
+		towerLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.voltageVolts, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_voltage_volts",
+			Help: "Fenecon battery cell voltage in Volts (TowerXModuleYCellZVoltage)",
+		},
+		cellLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.voltageMinVolts, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_voltage_min_volts",
+			Help: "Fenecon battery min cell voltage in Volts (MinCellVoltage)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.voltageMaxVolts, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_voltage_max_volts",
+			Help: "Fenecon battery max cell voltage in Volts (MaxCellVoltage)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.voltageSpreadVolts, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_voltage_spread_volts",
+			Help: "Fenecon battery cell voltage spread (max - min) in Volts",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.temperatureCelsius, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_temperature_celsius",
+			Help: "Fenecon battery cell temperature in degree Celsius (TowerXModuleYCellZTemperature)",
+		},
+		cellLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.temperatureMinCelsius, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_temperature_min_celsius",
+			Help: "Fenecon battery min cell temperature in degree Celsius (MinCellTemperature)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.temperatureMaxCelsius, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_temperature_max_celsius",
+			Help: "Fenecon battery max cell temperature in degree Celsius (MaxCellTemperature)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.temperatureSpreadCelsius, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_cell_temperature_spread_celsius",
+			Help: "Fenecon battery cell temperature spread (max - min) in degree Celsius",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.chargeMaxCurrentAmperes, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_charge_max_current_amperes",
+			Help: "Fenecon battery max charge current in Amperes (ChargeMaxCurrent)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.batteryCell.dischargeMaxCurrentAmperes, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_battery_discharge_max_current_amperes",
+			Help: "Fenecon battery max discharge current in Amperes (DischargeMaxCurrent)",
+		},
+		commonLabels,
+	))
+
 	// ##########################################
 	// Grid
 
@@ -324,6 +847,22 @@ func (fp *FeneconProber) initMetrics() {
 		commonLabels,
 	))
 
+	fp.newConstMetricVec(&fp.prometheus.grid.buyEnergy, newConstMetricVec(
+		"fenecon_grid_buy_energy_watt_hours_total",
+		"Fenecon grid buy energy in Watthours (GridBuyActiveEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	fp.newConstMetricVec(&fp.prometheus.grid.sellEnergy, newConstMetricVec(
+		"fenecon_grid_sell_energy_watt_hours_total",
+		"Fenecon grid sell energy in Watthours (GridSellActiveEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
 	// ##########################################
 	// Production
 
@@ -383,6 +922,30 @@ func (fp *FeneconProber) initMetrics() {
 		commonLabels,
 	))
 
+	fp.newConstMetricVec(&fp.prometheus.production.energy, newConstMetricVec(
+		"fenecon_production_energy_watt_hours_total",
+		"Fenecon production energy in Watthours (ProductionActiveEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	fp.newConstMetricVec(&fp.prometheus.production.energyAc, newConstMetricVec(
+		"fenecon_production_ac_energy_watt_hours_total",
+		"Fenecon production ac energy in Watthours (ProductionAcActiveEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	fp.newConstMetricVec(&fp.prometheus.production.energyDc, newConstMetricVec(
+		"fenecon_production_dc_energy_watt_hours_total",
+		"Fenecon production dc energy in Watthours (ProductionDcActiveEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
 	fp.newGaugeVec(&fp.prometheus.production.maxActualPower, prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "fenecon_production_max_actual_power",
@@ -417,9 +980,337 @@ func (fp *FeneconProber) initMetrics() {
 		},
 		commonLabels,
 	))
+
+	fp.newConstMetricVec(&fp.prometheus.consumption.energy, newConstMetricVec(
+		"fenecon_consumption_energy_watt_hours_total",
+		"Fenecon consumption energy in Watthours (ConsumptionActiveEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	// ##########################################
+	// Energy per period
+
+	fp.newGaugeVec(&fp.prometheus.energyPeriod, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_energy_period_watt_hours",
+			Help: "Fenecon energy of current calendar period in Watthours (period: day, month, year; kind: production, consumption, grid_buy, grid_sell, charge, discharge)",
+		},
+		[]string{"target", "period", "kind"},
+	))
+
+	// ##########################################
+	// Cost
+
+	fp.newConstMetricVec(&fp.prometheus.cost.total, newConstMetricVec(
+		"fenecon_cost_currency_total",
+		"Fenecon accumulated grid cost and feed-in revenue in currency (kind: grid_buy, grid_sell, baseline)",
+		prometheus.CounterValue,
+		[]string{"target", "kind", "currency"},
+		nil,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.cost.price, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_cost_price_per_kwh",
+			Help: "Fenecon current tariff price in currency per kWh (kind: grid_buy, grid_sell)",
+		},
+		[]string{"target", "kind", "currency"},
+	))
+
+	fp.newGaugeVec(&fp.prometheus.cost.saved, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_cost_saved_currency",
+			Help: "Fenecon estimated saved cost in currency compared to buying the whole consumption from grid (baseline - grid_buy + grid_sell)",
+		},
+		[]string{"target", "currency"},
+	))
+
+	// ##########################################
+	// Derived
+
+	fp.newGaugeVec(&fp.prometheus.derived.autarky, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_autarky_ratio",
+			Help: "Fenecon autarky (0-1, share of consumption not bought from grid; derived from ConsumptionActivePower and GridActivePower)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.derived.selfConsumption, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_self_consumption_ratio",
+			Help: "Fenecon self-consumption (0-1, share of production not sold to grid; derived from ProductionActivePower and GridActivePower)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.derived.energyFlow, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_energy_flow_watts",
+			Help: "Fenecon energy flow in Watts (from: pv, grid, battery; to: home, grid, battery; derived from _sum like FEMS energy monitor)",
+		},
+		[]string{"target", "from", "to"},
+	))
+
+	// ##########################################
+	// EVCS (electric vehicle charging station)
+
+	fp.newGaugeVec(&fp.prometheus.evcs.power, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_power",
+			Help: "Fenecon evcs charge power in Watts (ChargePower)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.evcs.powerConsumptionTotal, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_power_consumption_total",
+			Help: "Fenecon evcs power consumption total in Watthours (ActiveConsumptionEnergy)",
+		},
+		commonLabels,
+	))
+
+	fp.newConstMetricVec(&fp.prometheus.evcs.consumptionEnergy, newConstMetricVec(
+		"fenecon_evcs_consumption_energy_watt_hours_total",
+		"Fenecon evcs consumption energy in Watthours (ActiveConsumptionEnergy)",
+		prometheus.CounterValue,
+		commonLabels,
+		nil,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.evcs.energySession, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_energy_session",
+			Help: "Fenecon evcs energy of current charging session in Watthours (EnergySession)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.evcs.status, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_status",
+			Help: "Fenecon evcs status (-1=undefined, 0=starting, 1=not ready for charging, 2=ready for charging, 3=charging, 4=error, 5=charging rejected, 6=energy limit reached, 7=charging finished; Status)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.evcs.phases, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_phases",
+			Help: "Fenecon evcs number of phases used for charging (Phases)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.evcs.chargingType, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_charging_type",
+			Help: "Fenecon evcs charging type (-1=undefined, 0=CCS, 1=CHAdeMO, 2=AC; ChargingType)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.evcs.setChargePowerLimit, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_set_charge_power_limit",
+			Help: "Fenecon evcs charge power limit in Watts (SetChargePowerLimit)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.evcs.minHardwarePower, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_min_hardware_power",
+			Help: "Fenecon evcs minimum charge power of the hardware in Watts (MinimumHardwarePower)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.evcs.maxHardwarePower, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_max_hardware_power",
+			Help: "Fenecon evcs maximum charge power of the hardware in Watts (MaximumHardwarePower)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.evcs.energySessionWattHours, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_evcs_energy_session_watt_hours",
+			Help: "Fenecon evcs energy of current charging session in Watthours (EnergySession)",
+		},
+		commonLabels,
+	))
+	// ##########################################
+	// Controller
+
+	fp.newGaugeVec(&fp.prometheus.controller.runFailed, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_run_failed",
+			Help: "Fenecon controller run failed (RunFailed)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.controller.stateMachine, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_state_machine",
+			Help: "Fenecon controller state machine enum value (StateMachine)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.controller.timeOfUseState, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_time_of_use_state",
+			Help: "Fenecon time of use tariff controller state (BALANCING, DELAY_DISCHARGE, CHARGE_GRID; StateMachine)",
+		},
+		[]string{"target", "module", "state"},
+	))
+
+	fp.newGaugeVec(&fp.prometheus.controller.reserveSoc, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_reserve_soc_percent",
+			Help: "Fenecon emergency capacity reserve actual reserve soc in percent (ActualReserveSoc)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.controller.delayChargeState, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_delay_charge_state",
+			Help: "Fenecon grid optimized charge delay charge state enum value (DelayChargeState)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.controller.delayChargeLimit, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_delay_charge_limit_watts",
+			Help: "Fenecon grid optimized charge maximum charge limit of delay charge in Watt (DelayChargeMaximumChargeLimit)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.controller.sellToGridLimitState, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_sell_to_grid_limit_state",
+			Help: "Fenecon grid optimized charge sell to grid limit state enum value (SellToGridLimitState)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.controller.sellToGridLimit, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_sell_to_grid_limit_watts",
+			Help: "Fenecon grid optimized charge minimum charge limit of sell to grid limit in Watt (SellToGridLimitMinimumChargeLimit)",
+		},
+		commonLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.controller.property, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_property",
+			Help: "Fenecon controller numeric configuration property (eg. ReserveSoc, PeakShavingPower; _Property channels)",
+		},
+		[]string{"target", "module", "property", "unit"},
+	))
+
+	fp.newGaugeVec(&fp.prometheus.controller.propertyInfo, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_controller_property_info",
+			Help: "Fenecon controller string configuration property (eg. Mode; _Property channels)",
+		},
+		[]string{"target", "module", "property", "value"},
+	))
+	// ##########################################
+	// Schedule (time of use tariff)
+
+	slotLabels := []string{"target", "module", "slot_start"}
+
+	fp.newGaugeVec(&fp.prometheus.schedule.price, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_time_of_use_price",
+			Help: "Fenecon time of use tariff current electricity price in currency per MWh (QuarterlyPrices)",
+		},
+		[]string{"target", "module", "unit"},
+	))
+
+	fp.newGaugeVec(&fp.prometheus.schedule.slotPrice, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_time_of_use_schedule_price",
+			Help: "Fenecon time of use tariff planned slot electricity price in currency per MWh (getSchedule)",
+		},
+		slotLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.schedule.slotState, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_time_of_use_schedule_state",
+			Help: "Fenecon time of use tariff planned slot state enum value (getSchedule)",
+		},
+		append(slotLabels, "state"),
+	))
+
+	fp.newGaugeVec(&fp.prometheus.schedule.slotGridPower, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_time_of_use_schedule_grid_power_watts",
+			Help: "Fenecon time of use tariff planned slot grid power in Watt (getSchedule)",
+		},
+		slotLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.schedule.slotProductionPower, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_time_of_use_schedule_production_power_watts",
+			Help: "Fenecon time of use tariff predicted slot production power in Watt (getSchedule)",
+		},
+		slotLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.schedule.slotConsumptionPower, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_time_of_use_schedule_consumption_power_watts",
+			Help: "Fenecon time of use tariff predicted slot consumption power in Watt (getSchedule)",
+		},
+		slotLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.schedule.slotEssPower, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_time_of_use_schedule_ess_power_watts",
+			Help: "Fenecon time of use tariff planned slot ess power in Watt (getSchedule)",
+		},
+		slotLabels,
+	))
+
+	fp.newGaugeVec(&fp.prometheus.schedule.slotSoc, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_time_of_use_schedule_soc_percent",
+			Help: "Fenecon time of use tariff predicted slot state of charge in percent (getSchedule)",
+		},
+		slotLabels,
+	))
 }
 
 func (fp *FeneconProber) newGaugeVec(dest **prometheus.GaugeVec, def *prometheus.GaugeVec) {
 	(*dest) = def
 	fp.registry.MustRegister(def)
+	fp.metricVecs = append(fp.metricVecs, def)
+}
+
+func (fp *FeneconProber) newConstMetricVec(dest **constMetricVec, def *constMetricVec) {
+	(*dest) = def
+	fp.registry.MustRegister(def)
+	fp.metricVecs = append(fp.metricVecs, def)
+}
+
+// resetMetrics deletes all series of the metrics
+func (fp *FeneconProber) resetMetrics() {
+	for _, metricVec := range fp.metricVecs {
+		metricVec.Reset()
+	}
 }
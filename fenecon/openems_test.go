@@ -0,0 +1,469 @@
+package fenecon
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/webdevops/go-common/log/slogger"
+)
+
+type (
+	// fakeOpenEMS is a minimal OpenEMS edge serving the REST-Api (/rest/channel, /jsonrpc)
+	// and the JSON-RPC websocket for tests
+	fakeOpenEMS struct {
+		// required password (empty allows anonymous access)
+		password string
+
+		components map[string]fakeComponent
+
+		// JSON-RPC methods answered with an access denied error
+		denied map[string]bool
+
+		// schedule slots of the time of use tariff controllers (componentJsonApi getSchedule)
+		schedules map[string][]map[string]interface{}
+
+		// received JSON-RPC methods (edgeRpc payloads are unwrapped)
+		methods []string
+
+		// received channel queries of the REST-Api
+		queries []string
+
+		// received credentials (basic auth of the REST-Api, password of authenticateWithPassword)
+		credentials []string
+
+		lock sync.Mutex
+	}
+
+	fakeComponent struct {
+		Alias     string
+		FactoryId string
+		Channels  map[string]fakeChannel
+	}
+
+	fakeChannel struct {
+		Type     string
+		Unit     string
+		Text     string
+		Category string
+		Level    string
+		Value    interface{}
+	}
+)
+
+func newTestLogger() *slogger.Logger {
+	return slogger.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func newFakeOpenEMS() *fakeOpenEMS {
+	return &fakeOpenEMS{
+		password: "user",
+		components: map[string]fakeComponent{
+			"_sum": {
+				Channels: map[string]fakeChannel{
+					"State":                   {Type: "INTEGER", Value: 0},
+					"EssSoc":                  {Type: "INTEGER", Unit: "%", Value: 55},
+					"GridActivePower":         {Type: "INTEGER", Unit: "W", Value: 1200},
+					"EssActivePower":          {Type: "INTEGER", Unit: "W", Value: -300},
+					"ProductionActivePower":   {Type: "INTEGER", Unit: "W", Value: 2000},
+					"ConsumptionActivePower":  {Type: "INTEGER", Unit: "W", Value: 2900},
+					"GridBuyActiveEnergy":     {Type: "LONG", Unit: "Wh", Value: 123456},
+					"ConsumptionActiveEnergy": {Type: "LONG", Unit: "Wh", Value: 199999},
+				},
+			},
+			"_meta": {
+				Channels: map[string]fakeChannel{
+					"Version": {Type: "STRING", Value: "2024.10.1"},
+				},
+			},
+			"ess0": {
+				Alias:     "Battery",
+				FactoryId: "Ess.Fenecon.Commercial40",
+				Channels: map[string]fakeChannel{
+					"Soc":         {Type: "INTEGER", Unit: "%", Value: 55},
+					"SystemError": {Type: "BOOLEAN", Text: "System error", Category: "STATE", Level: "FAULT", Value: 1},
+					"LowSoc":      {Type: "BOOLEAN", Text: "Low state of charge", Category: "STATE", Level: "WARNING", Value: 0},
+				},
+			},
+		},
+	}
+}
+
+func (f *fakeOpenEMS) recordMethod(method string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.methods = append(f.methods, method)
+}
+
+func (f *fakeOpenEMS) recordQuery(query string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.queries = append(f.queries, query)
+}
+
+func (f *fakeOpenEMS) recordCredentials(username, password string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.credentials = append(f.credentials, username+":"+password)
+}
+
+func (f *fakeOpenEMS) receivedCredentials() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.credentials...)
+}
+
+func (f *fakeOpenEMS) receivedMethods() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.methods...)
+}
+
+func (f *fakeOpenEMS) receivedQueries() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.queries...)
+}
+
+// startRest starts the REST-Api (/rest/channel/<pattern> and /jsonrpc)
+func (f *fakeOpenEMS) startRest(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/channel/", f.serveRestChannel)
+	mux.HandleFunc("/jsonrpc", f.serveRestJsonRpc)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// startWebsocket starts the JSON-RPC websocket
+func (f *fakeOpenEMS) startWebsocket(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(f.serveWebsocket))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (f *fakeOpenEMS) restAuthorized(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	if ok {
+		f.recordCredentials(username, password)
+	}
+	return f.password == "" || (ok && password == f.password)
+}
+
+func (f *fakeOpenEMS) serveRestChannel(w http.ResponseWriter, r *http.Request) {
+	if !f.restAuthorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	query := strings.TrimPrefix(r.URL.Path, "/rest/channel/")
+	f.recordQuery(query)
+
+	rows := []map[string]interface{}{}
+	for address, channel := range f.matchChannels(query) {
+		rows = append(rows, map[string]interface{}{
+			"address":    address,
+			"type":       channel.Type,
+			"accessMode": "RO",
+			"text":       channel.Text,
+			"unit":       channel.Unit,
+			"value":      channel.Value,
+		})
+	}
+
+	if len(rows) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rows)
+}
+
+func (f *fakeOpenEMS) serveRestJsonRpc(w http.ResponseWriter, r *http.Request) {
+	if !f.restAuthorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	request := JsonRpcMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.recordMethod(request.Method)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(f.handleEdgeRequest(request))
+}
+
+func (f *fakeOpenEMS) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	for {
+		request := JsonRpcMessage{}
+		if err := conn.ReadJSON(&request); err != nil {
+			return
+		}
+
+		switch request.Method {
+		case "authenticateWithPassword":
+			params := map[string]string{}
+			_ = json.Unmarshal(request.Params, &params)
+			f.recordMethod(request.Method)
+			f.recordCredentials(params["username"], params["password"])
+
+			if f.password != "" && params["password"] != f.password {
+				_ = conn.WriteJSON(map[string]interface{}{
+					"jsonrpc": JsonRpcVersion, "id": request.Id,
+					"error": JsonRpcError{Code: 1003, Message: "Authentication failed"},
+				})
+				continue
+			}
+
+			_ = conn.WriteJSON(map[string]interface{}{
+				"jsonrpc": JsonRpcVersion, "id": request.Id,
+				"result": map[string]interface{}{
+					"token": "token",
+					"edges": []interface{}{map[string]interface{}{"id": "0", "version": "2024.10.1"}},
+				},
+			})
+		case "edgeRpc":
+			params := struct {
+				EdgeId  string         `json:"edgeId"`
+				Payload JsonRpcMessage `json:"payload"`
+			}{}
+			_ = json.Unmarshal(request.Params, &params)
+			f.recordMethod(params.Payload.Method)
+
+			var notification *JsonRpcMessage
+			response := f.handleEdgeRequest(params.Payload)
+			if params.Payload.Method == "subscribeChannels" {
+				subscribe := struct {
+					Channels []string `json:"channels"`
+				}{}
+				_ = json.Unmarshal(params.Payload.Params, &subscribe)
+				notification = f.currentData(subscribe.Channels)
+			}
+
+			_ = conn.WriteJSON(map[string]interface{}{
+				"jsonrpc": JsonRpcVersion, "id": request.Id,
+				"result": map[string]interface{}{"payload": response},
+			})
+
+			if notification != nil {
+				_ = conn.WriteJSON(map[string]interface{}{
+					"jsonrpc": JsonRpcVersion, "method": "edgeRpc",
+					"params": map[string]interface{}{"edgeId": params.EdgeId, "payload": notification},
+				})
+			}
+		}
+	}
+}
+
+// handleEdgeRequest answers the JSON-RPC request of the edge (getEdgeConfig, subscribeChannels, componentJsonApi)
+func (f *fakeOpenEMS) handleEdgeRequest(request JsonRpcMessage) JsonRpcMessage {
+	response := JsonRpcMessage{JsonRpc: JsonRpcVersion, Id: request.Id}
+
+	f.lock.Lock()
+	denied := f.denied[request.Method]
+	f.lock.Unlock()
+	if denied {
+		response.Error = &JsonRpcError{Code: 1002, Message: "Access denied"}
+		return response
+	}
+
+	var result interface{}
+	switch request.Method {
+	case "getEdgeConfig":
+		result = f.edgeConfig()
+	case "subscribeChannels":
+		result = map[string]interface{}{}
+	case "componentJsonApi":
+		params := componentJsonApiRequest{}
+		_ = json.Unmarshal(request.Params, &params)
+
+		f.lock.Lock()
+		schedule, exists := f.schedules[params.ComponentId]
+		f.lock.Unlock()
+		if params.Payload.Method != "getSchedule" || !exists {
+			response.Error = &JsonRpcError{Code: 1001, Message: "Unhandled request"}
+			return response
+		}
+		result = map[string]interface{}{"schedule": schedule}
+	default:
+		response.Error = &JsonRpcError{Code: 1001, Message: "Unhandled request"}
+		return response
+	}
+
+	response.Result, _ = json.Marshal(result)
+	return response
+}
+
+func (f *fakeOpenEMS) edgeConfig() EdgeConfig {
+	config := EdgeConfig{Components: map[string]EdgeConfigComponent{}}
+	for componentId, component := range f.components {
+		channels := map[string]EdgeConfigChannel{}
+		for channelId, channel := range component.Channels {
+			channels[channelId] = EdgeConfigChannel{
+				Type:       channel.Type,
+				AccessMode: "RO",
+				Unit:       channel.Unit,
+				Text:       channel.Text,
+				Category:   channel.Category,
+				Level:      channel.Level,
+			}
+		}
+		config.Components[componentId] = EdgeConfigComponent{Alias: component.Alias, FactoryId: component.FactoryId, Channels: channels}
+	}
+	return config
+}
+
+func (f *fakeOpenEMS) currentData(addresses []string) *JsonRpcMessage {
+	data := map[string]interface{}{}
+	for _, address := range addresses {
+		componentId, channelId := splitAddress(address)
+		data[address] = f.components[componentId].Channels[channelId].Value
+	}
+
+	params, _ := json.Marshal(data)
+	return &JsonRpcMessage{JsonRpc: JsonRpcVersion, Method: "currentData", Params: params}
+}
+
+// setChannelValue changes the value of an existing channel (eg. between probes)
+func (f *fakeOpenEMS) setChannelValue(component, channel string, value interface{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	row := f.components[component].Channels[channel]
+	row.Value = value
+	f.components[component].Channels[channel] = row
+}
+
+// matchChannels returns all channels matching the wildcard pattern (eg. "_sum/.*")
+func (f *fakeOpenEMS) matchChannels(pattern string) map[string]fakeChannel {
+	componentPattern, channelPattern := splitWildcardPattern(pattern)
+	componentRegexp := regexp.MustCompile(`^(?:` + componentPattern + `)$`)
+	channelRegexp := regexp.MustCompile(`^(?:` + channelPattern + `)$`)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	ret := map[string]fakeChannel{}
+	for componentId, component := range f.components {
+		if !componentRegexp.MatchString(componentId) {
+			continue
+		}
+		for channelId, channel := range component.Channels {
+			if channelRegexp.MatchString(channelId) {
+				ret[componentId+"/"+channelId] = channel
+			}
+		}
+	}
+	return ret
+}
+
+// newTestProber creates a prober for the fake with the query groups
+func newTestProber(t *testing.T, queryGroups ...string) (*FeneconProber, *prometheus.Registry) {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	fp := New(context.Background(), registry, newTestLogger())
+	fp.SetRetry(0, 0, 0)
+	fp.SetTimeout(5 * time.Second)
+	fp.SetHttpAuth("x", "user")
+	if err := fp.SetQueryGroups(queryGroups); err != nil {
+		t.Fatalf("invalid query groups: %v", err)
+	}
+	return fp, registry
+}
+
+// gatherMetrics returns the label sets and values of all series of the metric
+func gatherMetrics(t *testing.T, gatherer prometheus.Gatherer, name string) map[string]float64 {
+	t.Helper()
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("unable to gather metrics: %v", err)
+	}
+
+	ret := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			labels := []string{}
+			for _, label := range metric.GetLabel() {
+				if label.GetName() != "target" {
+					labels = append(labels, label.GetName()+"="+label.GetValue())
+				}
+			}
+
+			value := metric.GetGauge().GetValue()
+			if metric.GetCounter() != nil {
+				value = metric.GetCounter().GetValue()
+			}
+			ret[strings.Join(labels, ",")] = value
+		}
+	}
+	return ret
+}
+
+// assertMetrics compares all series of the metric with the expected label sets and values (see gatherMetrics)
+func assertMetrics(t *testing.T, gatherer prometheus.Gatherer, name string, expected map[string]float64) {
+	t.Helper()
+
+	got := gatherMetrics(t, gatherer, name)
+	if len(got) != len(expected) {
+		t.Errorf("%v: expected %v, got %v", name, expected, got)
+		return
+	}
+	for labels, value := range expected {
+		if gotValue, exists := got[labels]; !exists || !floatEqual(gotValue, value) {
+			t.Errorf("%v: expected %v, got %v", name, expected, got)
+			return
+		}
+	}
+}
+
+// testServerPort returns the port of the test server url
+func testServerPort(t *testing.T, serverUrl string) int {
+	t.Helper()
+
+	u, err := url.Parse(serverUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return port
+}
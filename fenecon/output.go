@@ -0,0 +1,35 @@
+package fenecon
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	OutputFormatPrometheus = "prometheus"
+	OutputFormatInflux     = "influx"
+)
+
+type (
+	// Output renders the gathered metrics (eg. registry of the prober) in another format than Prometheus exposition format
+	Output interface {
+		// ContentType returns the HTTP content type of the rendered metrics
+		ContentType() string
+
+		// Write renders the metrics, timestamp is used for metrics without timestamp
+		Write(w io.Writer, families []*dto.MetricFamily, timestamp time.Time) error
+	}
+)
+
+// NewOutput creates the output for the format (Prometheus exposition format is served by promhttp)
+func NewOutput(format string) (Output, error) {
+	switch format {
+	case OutputFormatInflux:
+		return &influxOutput{}, nil
+	default:
+		return nil, fmt.Errorf(`unsupported output format "%v"`, format)
+	}
+}
@@ -0,0 +1,191 @@
+package fenecon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	resty "resty.dev/v3"
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `, "\n", `\n`)
+	influxTagEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`)
+)
+
+type (
+	// influxOutput renders the metrics as InfluxDB line protocol:
+	// measurement is the metric name, labels are tags and the value is the field "value"
+	// (summaries and histograms: fields "sum", "count" and one field per quantile/bucket)
+	influxOutput struct{}
+
+	influxField struct {
+		key   string
+		value float64
+	}
+
+	// InfluxWriter pushes metrics as InfluxDB line protocol to the write api (/api/v2/write)
+	InfluxWriter struct {
+		client *resty.Client
+		url    string
+		output influxOutput
+	}
+)
+
+func (o *influxOutput) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (o *influxOutput) Write(w io.Writer, families []*dto.MetricFamily, timestamp time.Time) error {
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			fields := influxFields(family.GetType(), metric)
+			if len(fields) == 0 {
+				continue
+			}
+
+			metricTimestamp := timestamp
+			if metric.TimestampMs != nil {
+				metricTimestamp = time.UnixMilli(metric.GetTimestampMs())
+			}
+
+			if _, err := io.WriteString(w, influxLine(family.GetName(), metric.Label, fields, metricTimestamp)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func influxLine(name string, labels []*dto.LabelPair, fields []influxField, timestamp time.Time) string {
+	line := strings.Builder{}
+	line.WriteString(influxMeasurementEscaper.Replace(name))
+
+	// tags sorted by key (recommended by InfluxDB), empty values are not allowed
+	labels = slices.Clone(labels)
+	slices.SortFunc(labels, func(a, b *dto.LabelPair) int { return strings.Compare(a.GetName(), b.GetName()) })
+	for _, label := range labels {
+		if label.GetValue() == "" {
+			continue
+		}
+		line.WriteString(",")
+		line.WriteString(influxTagEscaper.Replace(label.GetName()))
+		line.WriteString("=")
+		line.WriteString(influxTagEscaper.Replace(label.GetValue()))
+	}
+
+	for i, field := range fields {
+		if i == 0 {
+			line.WriteString(" ")
+		} else {
+			line.WriteString(",")
+		}
+		line.WriteString(influxTagEscaper.Replace(field.key))
+		line.WriteString("=")
+		line.WriteString(strconv.FormatFloat(field.value, 'g', -1, 64))
+	}
+
+	line.WriteString(" ")
+	line.WriteString(strconv.FormatInt(timestamp.UnixNano(), 10))
+	line.WriteString("\n")
+
+	return line.String()
+}
+
+// influxFields returns the fields of the metric, NaN and Inf are not supported by InfluxDB and skipped
+func influxFields(metricType dto.MetricType, metric *dto.Metric) []influxField {
+	fields := []influxField{}
+
+	switch metricType {
+	case dto.MetricType_GAUGE:
+		fields = append(fields, influxField{"value", metric.GetGauge().GetValue()})
+	case dto.MetricType_COUNTER:
+		fields = append(fields, influxField{"value", metric.GetCounter().GetValue()})
+	case dto.MetricType_UNTYPED:
+		fields = append(fields, influxField{"value", metric.GetUntyped().GetValue()})
+	case dto.MetricType_SUMMARY:
+		fields = append(fields,
+			influxField{"sum", metric.GetSummary().GetSampleSum()},
+			influxField{"count", float64(metric.GetSummary().GetSampleCount())},
+		)
+		for _, quantile := range metric.GetSummary().GetQuantile() {
+			fields = append(fields, influxField{strconv.FormatFloat(quantile.GetQuantile(), 'g', -1, 64), quantile.GetValue()})
+		}
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		fields = append(fields,
+			influxField{"sum", metric.GetHistogram().GetSampleSum()},
+			influxField{"count", float64(metric.GetHistogram().GetSampleCount())},
+		)
+		for _, bucket := range metric.GetHistogram().GetBucket() {
+			fields = append(fields, influxField{strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64), float64(bucket.GetCumulativeCount())})
+		}
+	}
+
+	return slices.DeleteFunc(fields, func(field influxField) bool {
+		return math.IsNaN(field.value) || math.IsInf(field.value, 0)
+	})
+}
+
+// NewInfluxWriter creates the writer for the InfluxDB write url (eg. http://influxdb:8086/api/v2/write?org=home&bucket=fenecon),
+// token is sent as "Authorization: Token ..." header (if set), precision is set to ns (timestamps of the lines) if not set
+func NewInfluxWriter(writeUrl, token string, timeout time.Duration) (*InfluxWriter, error) {
+	parsedUrl, err := url.Parse(writeUrl)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid influx write url "%v": %w`, writeUrl, err)
+	}
+	query := parsedUrl.Query()
+	if query.Get("precision") == "" {
+		query.Set("precision", "ns")
+	}
+	parsedUrl.RawQuery = query.Encode()
+
+	w := InfluxWriter{}
+	w.url = parsedUrl.String()
+	w.client = resty.New()
+	w.client.SetTimeout(timeout)
+	if token != "" {
+		w.client.SetHeader("Authorization", "Token "+token)
+	}
+	return &w, nil
+}
+
+// Write pushes the metrics, timestamp is used for metrics without timestamp
+func (w *InfluxWriter) Write(ctx context.Context, families []*dto.MetricFamily, timestamp time.Time) error {
+	body := bytes.Buffer{}
+	if err := w.output.Write(&body, families, timestamp); err != nil {
+		return err
+	}
+
+	if body.Len() == 0 {
+		return nil
+	}
+
+	response, err := w.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", w.output.ContentType()).
+		SetBody(body.Bytes()).
+		Post(w.url)
+	if err != nil {
+		return fmt.Errorf(`influx write failed: %w`, err)
+	}
+
+	if response.IsError() {
+		return fmt.Errorf(`%w: influx write failed with status %v: %v`, ErrHttpStatus, response.StatusCode(), strings.TrimSpace(response.String()))
+	}
+
+	return nil
+}
+
+// Close closes the http client
+func (w *InfluxWriter) Close() error {
+	return w.client.Close()
+}
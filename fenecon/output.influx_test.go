@@ -0,0 +1,176 @@
+package fenecon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestInfluxLine(t *testing.T) {
+	timestamp := time.Unix(1760000000, 123)
+
+	tests := []struct {
+		name   string
+		metric string
+		labels map[string]string
+		fields []influxField
+		line   string
+	}{
+		{
+			name:   "tags sorted",
+			metric: "fenecon_grid_power",
+			labels: map[string]string{"target": "home", "module": "_sum"},
+			fields: []influxField{{"value", 1200}},
+			line:   "fenecon_grid_power,module=_sum,target=home value=1200 1760000000000000123\n",
+		},
+		{
+			name:   "escaping of tags",
+			metric: "fenecon_component_info",
+			labels: map[string]string{"alias": "Heat pump, garage", "factory": "a=b"},
+			fields: []influxField{{"value", 1}},
+			line:   `fenecon_component_info,alias=Heat\ pump\,\ garage,factory=a\=b value=1 1760000000000000123` + "\n",
+		},
+		{
+			name:   "empty tags are skipped",
+			metric: "fenecon_info",
+			labels: map[string]string{"version": "", "target": "home"},
+			fields: []influxField{{"value", 1}},
+			line:   "fenecon_info,target=home value=1 1760000000000000123\n",
+		},
+		{
+			name:   "escaping of fields",
+			metric: "fenecon_probe_duration_seconds",
+			fields: []influxField{{"sum", 1.5}, {"count", 3}, {"0.5 q", 0.25}},
+			line:   `fenecon_probe_duration_seconds sum=1.5,count=3,0.5\ q=0.25 1760000000000000123` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			labels := []*dto.LabelPair{}
+			for name, value := range test.labels {
+				labels = append(labels, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+			}
+
+			if line := influxLine(test.metric, labels, test.fields, timestamp); line != test.line {
+				t.Errorf("expected %q, got %q", test.line, line)
+			}
+		})
+	}
+}
+
+func TestInfluxOutputTimestamps(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name: proto.String("fenecon_grid_power"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{
+				// timestamp of the metric (eg. poll time) takes precedence
+				{Gauge: &dto.Gauge{Value: proto.Float64(1200)}, TimestampMs: proto.Int64(1760000000000)},
+				{Gauge: &dto.Gauge{Value: proto.Float64(1300)}},
+			},
+		},
+	}
+
+	body := strings.Builder{}
+	output := influxOutput{}
+	if err := output.Write(&body, families, time.Unix(1760000060, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "fenecon_grid_power value=1200 1760000000000000000\nfenecon_grid_power value=1300 1760000060000000000\n"
+	if body.String() != expected {
+		t.Errorf("expected %q, got %q", expected, body.String())
+	}
+}
+
+func TestInfluxWriter(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name:   proto.String("fenecon_grid_power"),
+			Type:   dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(1200)}}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		token     string
+		status    int
+		precision string
+		fails     bool
+	}{
+		{name: "token and default precision", query: "?org=home&bucket=fenecon", token: "secret", status: http.StatusNoContent, precision: "ns"},
+		{name: "precision of url", query: "?org=home&bucket=fenecon&precision=ns", status: http.StatusNoContent, precision: "ns"},
+		{name: "error status", query: "?org=home&bucket=fenecon", status: http.StatusUnauthorized, precision: "ns", fails: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var request *http.Request
+			var body string
+			lock := sync.Mutex{}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				content, _ := io.ReadAll(r.Body)
+
+				lock.Lock()
+				defer lock.Unlock()
+				request = r
+				body = string(content)
+
+				w.WriteHeader(test.status)
+			}))
+			t.Cleanup(server.Close)
+
+			writer, err := NewInfluxWriter(server.URL+"/api/v2/write"+test.query, test.token, 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = writer.Close() })
+
+			err = writer.Write(context.Background(), families, time.Unix(1760000000, 0))
+			if test.fails {
+				if !errors.Is(err, ErrHttpStatus) {
+					t.Fatalf("expected http status error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if request.URL.Path != "/api/v2/write" {
+				t.Errorf("expected path /api/v2/write, got %v", request.URL.Path)
+			}
+
+			query := request.URL.Query()
+			if query.Get("org") != "home" || query.Get("bucket") != "fenecon" || query.Get("precision") != test.precision {
+				t.Errorf("unexpected query %v", query)
+			}
+
+			expectedAuthorization := ""
+			if test.token != "" {
+				expectedAuthorization = "Token " + test.token
+			}
+			if authorization := request.Header.Get("Authorization"); authorization != expectedAuthorization {
+				t.Errorf("expected authorization %q, got %q", expectedAuthorization, authorization)
+			}
+
+			if expected := "fenecon_grid_power value=1200 1760000000000000000\n"; body != expected {
+				t.Errorf("expected body %q, got %q", expected, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package fenecon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/webdevops/go-common/log/slogger"
+)
+
+const (
+	mqttAvailabilityOnline  = "online"
+	mqttAvailabilityOffline = "offline"
+
+	// topic level for metrics without module label
+	mqttNoModule = "_"
+)
+
+var (
+	mqttTopicSanitizeRegexp = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+type (
+	MqttOpts struct {
+		// broker url (eg. tcp://mosquitto:1883)
+		Url      string
+		ClientId string
+		Username string
+		Password string
+
+		// topic prefix, values are published to <prefix>/<target>/<module>/<metric>
+		Topic string
+
+		// Home Assistant discovery prefix (empty disables discovery)
+		DiscoveryPrefix string
+
+		Timeout time.Duration
+	}
+
+	// MqttPublisher publishes metric values to MQTT topics and Home Assistant discovery configs (retained),
+	// availability is published to <prefix>/status (offline as last will)
+	MqttPublisher struct {
+		opts   MqttOpts
+		client mqtt.Client
+		logger *slogger.Logger
+
+		// discovery config topics confirmed by the broker (published again after reconnect)
+		discovery     map[string]bool
+		discoveryLock sync.Mutex
+	}
+
+	mqttValue struct {
+		topic       string
+		metric      string
+		metricType  dto.MetricType
+		module      string
+		labelValues []string
+		value       float64
+	}
+
+	// mqttSensorClass is the Home Assistant sensor class of a metric
+	mqttSensorClass struct {
+		deviceClass string
+		stateClass  string
+		unit        string
+	}
+
+	mqttDiscoveryConfig struct {
+		Name              string              `json:"name"`
+		UniqueId          string              `json:"unique_id"`
+		ObjectId          string              `json:"object_id"`
+		StateTopic        string              `json:"state_topic"`
+		AvailabilityTopic string              `json:"availability_topic"`
+		DeviceClass       string              `json:"device_class,omitempty"`
+		StateClass        string              `json:"state_class,omitempty"`
+		UnitOfMeasurement string              `json:"unit_of_measurement,omitempty"`
+		Device            mqttDiscoveryDevice `json:"device"`
+	}
+
+	mqttDiscoveryDevice struct {
+		Identifiers  []string `json:"identifiers"`
+		Name         string   `json:"name"`
+		Manufacturer string   `json:"manufacturer"`
+		Model        string   `json:"model"`
+	}
+)
+
+// NewMqttPublisher connects to the broker, reconnects are handled by the client
+func NewMqttPublisher(opts MqttOpts, logger *slogger.Logger) (*MqttPublisher, error) {
+	p := MqttPublisher{}
+	p.opts = opts
+	p.logger = logger
+	p.discovery = map[string]bool{}
+
+	clientOpts := mqtt.NewClientOptions()
+	clientOpts.AddBroker(opts.Url)
+	clientOpts.SetClientID(opts.ClientId)
+	clientOpts.SetUsername(opts.Username)
+	clientOpts.SetPassword(opts.Password)
+	clientOpts.SetConnectTimeout(opts.Timeout)
+	clientOpts.SetWriteTimeout(opts.Timeout)
+	clientOpts.SetAutoReconnect(true)
+	clientOpts.SetConnectRetry(true)
+	clientOpts.SetMaxReconnectInterval(time.Minute)
+	clientOpts.SetWill(p.availabilityTopic(), mqttAvailabilityOffline, 1, true)
+	clientOpts.SetOnConnectHandler(p.onConnect)
+	clientOpts.SetConnectionLostHandler(func(client mqtt.Client, err error) {
+		p.logger.Warn(`lost connection to MQTT broker, reconnecting`, slog.Any("error", err))
+	})
+
+	p.client = mqtt.NewClient(clientOpts)
+
+	// connect is retried in background (SetConnectRetry), only invalid options fail here
+	token := p.client.Connect()
+	if token.WaitTimeout(opts.Timeout) && token.Error() != nil {
+		return nil, fmt.Errorf(`unable to connect to MQTT broker "%v": %w`, opts.Url, token.Error())
+	}
+
+	return &p, nil
+}
+
+func (p *MqttPublisher) onConnect(client mqtt.Client) {
+	p.logger.Info(`connected to MQTT broker`, slog.String("url", p.opts.Url))
+
+	// broker might have lost retained messages
+	p.discoveryLock.Lock()
+	p.discovery = map[string]bool{}
+	p.discoveryLock.Unlock()
+
+	client.Publish(p.availabilityTopic(), 1, true, mqttAvailabilityOnline)
+}
+
+func (p *MqttPublisher) availabilityTopic() string {
+	return p.opts.Topic + "/status"
+}
+
+// Publish publishes the values of the target (and discovery configs of new topics)
+func (p *MqttPublisher) Publish(target string, families []*dto.MetricFamily) error {
+	if !p.client.IsConnectionOpen() {
+		return fmt.Errorf(`not connected to MQTT broker "%v"`, p.opts.Url)
+	}
+
+	tokens := []mqtt.Token{}
+	discoveryTokens := map[string]mqtt.Token{}
+	for _, value := range p.values(target, families) {
+		if p.opts.DiscoveryPrefix != "" {
+			if topic, token := p.publishDiscovery(target, value); token != nil {
+				discoveryTokens[topic] = token
+			}
+		}
+
+		tokens = append(tokens, p.client.Publish(value.topic, 0, false, strconv.FormatFloat(value.value, 'f', -1, 64)))
+	}
+
+	// discovery configs are only marked as published if confirmed, failed configs are published again with the next values
+	var discoveryErr error
+	for topic, token := range discoveryTokens {
+		if err := p.waitToken(token); err != nil {
+			discoveryErr = err
+			continue
+		}
+
+		p.discoveryLock.Lock()
+		p.discovery[topic] = true
+		p.discoveryLock.Unlock()
+	}
+
+	for _, token := range tokens {
+		if err := p.waitToken(token); err != nil {
+			return err
+		}
+	}
+
+	return discoveryErr
+}
+
+func (p *MqttPublisher) waitToken(token mqtt.Token) error {
+	if !token.WaitTimeout(p.opts.Timeout) {
+		return fmt.Errorf(`MQTT publish timed out`)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf(`MQTT publish failed: %w`, err)
+	}
+	return nil
+}
+
+// values returns the values of gauges and counters with topics <prefix>/<target>/<module>/<metric>[/<label>...],
+// labels other than target and module are appended as topic levels (sorted by label name)
+func (p *MqttPublisher) values(target string, families []*dto.MetricFamily) []mqttValue {
+	ret := []mqttValue{}
+
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			value := mqttValue{
+				metric:     family.GetName(),
+				metricType: family.GetType(),
+				module:     mqttNoModule,
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				value.value = metric.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				value.value = metric.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				value.value = metric.GetUntyped().GetValue()
+			default:
+				continue
+			}
+
+			labels := slices.Clone(metric.Label)
+			slices.SortFunc(labels, func(a, b *dto.LabelPair) int { return strings.Compare(a.GetName(), b.GetName()) })
+			for _, label := range labels {
+				switch label.GetName() {
+				case "target":
+				case "module":
+					value.module = label.GetValue()
+				default:
+					value.labelValues = append(value.labelValues, label.GetValue())
+				}
+			}
+
+			levels := []string{p.opts.Topic, mqttTopicLevel(mqttTargetName(target)), mqttTopicLevel(value.module), value.metric}
+			for _, labelValue := range value.labelValues {
+				levels = append(levels, mqttTopicLevel(labelValue))
+			}
+			value.topic = strings.Join(levels, "/")
+
+			ret = append(ret, value)
+		}
+	}
+
+	return ret
+}
+
+// publishDiscovery publishes the Home Assistant discovery config (retained) of power, energy and battery state of charge
+// sensors if not published yet, returns the discovery topic and nil token if already published or not a sensor
+func (p *MqttPublisher) publishDiscovery(target string, value mqttValue) (string, mqtt.Token) {
+	sensorClass, isSensor := mqttSensorClassOf(value.metric, value.metricType)
+	if !isSensor {
+		return "", nil
+	}
+
+	deviceId := mqttTopicLevel("fenecon_" + mqttTargetName(target))
+	objectId := mqttTopicLevel(strings.Join(append([]string{deviceId, value.module, strings.TrimPrefix(value.metric, "fenecon_")}, value.labelValues...), "_"))
+	topic := fmt.Sprintf(`%s/sensor/%s/%s/config`, p.opts.DiscoveryPrefix, deviceId, objectId)
+
+	p.discoveryLock.Lock()
+	published := p.discovery[topic]
+	p.discoveryLock.Unlock()
+	if published {
+		return topic, nil
+	}
+
+	config := mqttDiscoveryConfig{
+		Name:              strings.Join(append([]string{value.module, strings.TrimPrefix(value.metric, "fenecon_")}, value.labelValues...), " "),
+		UniqueId:          objectId,
+		ObjectId:          objectId,
+		StateTopic:        value.topic,
+		AvailabilityTopic: p.availabilityTopic(),
+		DeviceClass:       sensorClass.deviceClass,
+		StateClass:        sensorClass.stateClass,
+		UnitOfMeasurement: sensorClass.unit,
+		Device: mqttDiscoveryDevice{
+			Identifiers:  []string{deviceId},
+			Name:         "FEMS " + mqttTargetName(target),
+			Manufacturer: "FENECON",
+			Model:        "FEMS (OpenEMS)",
+		},
+	}
+
+	payload, err := json.Marshal(config)
+	if err != nil {
+		p.logger.Warn(`unable to build discovery config`, slog.String("topic", topic), slog.Any("error", err))
+		return topic, nil
+	}
+
+	return topic, p.client.Publish(topic, 1, true, payload)
+}
+
+// Close publishes offline availability and disconnects
+func (p *MqttPublisher) Close() {
+	p.client.Publish(p.availabilityTopic(), 1, true, mqttAvailabilityOffline).WaitTimeout(p.opts.Timeout)
+	p.client.Disconnect(uint(p.opts.Timeout.Milliseconds()))
+}
+
+// mqttSensorClassOf returns the Home Assistant sensor class by metric name (unit suffix) and type, discovery configs
+// are only published for power, energy and battery state of charge (retained configs of all series would flood the broker)
+func mqttSensorClassOf(metric string, metricType dto.MetricType) (mqttSensorClass, bool) {
+	switch {
+	case strings.HasPrefix(metric, "fenecon_time_of_use_schedule_"):
+		// series per planned slot
+		return mqttSensorClass{}, false
+	case metricType == dto.MetricType_COUNTER && strings.HasSuffix(metric, "_watt_hours_total"):
+		return mqttSensorClass{deviceClass: "energy", stateClass: "total_increasing", unit: "Wh"}, true
+	case metric == "fenecon_energy_period_watt_hours":
+		// resets at start of period
+		return mqttSensorClass{deviceClass: "energy", stateClass: "total_increasing", unit: "Wh"}, true
+	case strings.Contains(metric, "_reactive_power"):
+		// reactive power is var, not W
+		return mqttSensorClass{}, false
+	case metricType == dto.MetricType_GAUGE && (strings.HasSuffix(metric, "_watts") || strings.HasSuffix(metric, "_power")):
+		return mqttSensorClass{deviceClass: "power", stateClass: "measurement", unit: "W"}, true
+	case metric == "fenecon_battery_charge_percent":
+		return mqttSensorClass{deviceClass: "battery", stateClass: "measurement", unit: "%"}, true
+	default:
+		return mqttSensorClass{}, false
+	}
+}
+
+// mqttTargetName returns the host of url targets (eg. http://fenecon -> fenecon) or the target name
+func mqttTargetName(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return target
+}
+
+// mqttTopicLevel sanitizes the value for usage as single topic level or object id (no "/", "+", "#")
+func mqttTopicLevel(val string) string {
+	if ret := mqttTopicSanitizeRegexp.ReplaceAllString(val, "_"); ret != "" {
+		return ret
+	}
+	return mqttNoModule
+}
@@ -0,0 +1,261 @@
+package fenecon
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type (
+	// fakeMqttBroker is a minimal MQTT 3.1.1 broker (connect, publish, ping) recording received messages
+	fakeMqttBroker struct {
+		listener net.Listener
+
+		// QoS 1 publishes to topics with this prefix are not acknowledged (empty acknowledges all)
+		withholdAckPrefix string
+
+		messages []fakeMqttMessage
+
+		lock sync.Mutex
+	}
+
+	fakeMqttMessage struct {
+		topic    string
+		payload  string
+		retained bool
+	}
+)
+
+func newFakeMqttBroker(t *testing.T) *fakeMqttBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() }) // nolint:errcheck
+
+	b := &fakeMqttBroker{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+
+	return b
+}
+
+func (b *fakeMqttBroker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func (b *fakeMqttBroker) withholdAcks(prefix string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.withholdAckPrefix = prefix
+}
+
+func (b *fakeMqttBroker) serve(conn net.Conn) {
+	defer conn.Close() // nolint:errcheck
+
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		var response packets.ControlPacket
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			response = packets.NewControlPacket(packets.Connack)
+		case *packets.PublishPacket:
+			b.lock.Lock()
+			b.messages = append(b.messages, fakeMqttMessage{topic: p.TopicName, payload: string(p.Payload), retained: p.Retain})
+			withhold := b.withholdAckPrefix != "" && strings.HasPrefix(p.TopicName, b.withholdAckPrefix)
+			b.lock.Unlock()
+
+			if p.Qos == 1 && !withhold {
+				puback := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				puback.MessageID = p.MessageID
+				response = puback
+			}
+		case *packets.PingreqPacket:
+			response = packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+
+		if response != nil {
+			if err := response.Write(conn); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// received returns the messages received for the topic
+func (b *fakeMqttBroker) received(topic string) []fakeMqttMessage {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	ret := []fakeMqttMessage{}
+	for _, message := range b.messages {
+		if message.topic == topic {
+			ret = append(ret, message)
+		}
+	}
+	return ret
+}
+
+// waitReceived waits until count messages were received for the topic (QoS 0 publishes are not confirmed)
+func (b *fakeMqttBroker) waitReceived(t *testing.T, topic string, count int) []fakeMqttMessage {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		messages := b.received(topic)
+		if len(messages) >= count {
+			return messages
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %v messages for topic %v, got %v", count, topic, len(messages))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMqttSensorClassOf(t *testing.T) {
+	tests := []struct {
+		metric      string
+		metricType  dto.MetricType
+		isSensor    bool
+		deviceClass string
+		stateClass  string
+	}{
+		{metric: "fenecon_grid_power", metricType: dto.MetricType_GAUGE, isSensor: true, deviceClass: "power", stateClass: "measurement"},
+		{metric: "fenecon_battery_allowed_charge_power", metricType: dto.MetricType_GAUGE, isSensor: true, deviceClass: "power", stateClass: "measurement"},
+		{metric: "fenecon_energy_flow_watts", metricType: dto.MetricType_GAUGE, isSensor: true, deviceClass: "power", stateClass: "measurement"},
+		{metric: "fenecon_grid_buy_energy_watt_hours_total", metricType: dto.MetricType_COUNTER, isSensor: true, deviceClass: "energy", stateClass: "total_increasing"},
+		{metric: "fenecon_energy_period_watt_hours", metricType: dto.MetricType_GAUGE, isSensor: true, deviceClass: "energy", stateClass: "total_increasing"},
+		{metric: "fenecon_battery_charge_percent", metricType: dto.MetricType_GAUGE, isSensor: true, deviceClass: "battery", stateClass: "measurement"},
+		{metric: "fenecon_meter_reactive_power", metricType: dto.MetricType_GAUGE},
+		{metric: "fenecon_meter_reactive_power_phase", metricType: dto.MetricType_GAUGE},
+		{metric: "fenecon_grid_power_buy_total", metricType: dto.MetricType_COUNTER},
+		{metric: "fenecon_battery_cell_voltage_volts", metricType: dto.MetricType_GAUGE},
+		{metric: "fenecon_battery_cell_temperature_celsius", metricType: dto.MetricType_GAUGE},
+		{metric: "fenecon_autarky_ratio", metricType: dto.MetricType_GAUGE},
+		{metric: "fenecon_time_of_use_schedule_grid_power_watts", metricType: dto.MetricType_GAUGE},
+		{metric: "fenecon_channel_value", metricType: dto.MetricType_GAUGE},
+	}
+
+	for _, test := range tests {
+		t.Run(test.metric, func(t *testing.T) {
+			sensorClass, isSensor := mqttSensorClassOf(test.metric, test.metricType)
+			if isSensor != test.isSensor {
+				t.Fatalf("expected sensor %v, got %v", test.isSensor, isSensor)
+			}
+			if sensorClass.deviceClass != test.deviceClass || sensorClass.stateClass != test.stateClass {
+				t.Errorf("expected %s/%s, got %s/%s", test.deviceClass, test.stateClass, sensorClass.deviceClass, sensorClass.stateClass)
+			}
+		})
+	}
+}
+
+func TestMqttPublish(t *testing.T) {
+	broker := newFakeMqttBroker(t)
+
+	publisher, err := NewMqttPublisher(MqttOpts{
+		Url:             broker.url(),
+		ClientId:        "fenecon-exporter-test",
+		Topic:           "fenecon",
+		DiscoveryPrefix: "homeassistant",
+		Timeout:         500 * time.Millisecond,
+	}, newTestLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(publisher.Close)
+
+	registry := prometheus.NewRegistry()
+	gridPower := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "fenecon_grid_power"}, []string{"target", "module"})
+	reactivePower := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "fenecon_meter_reactive_power"}, []string{"target", "module"})
+	gridBuy := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "fenecon_grid_buy_energy_watt_hours_total"}, []string{"target", "module"})
+	registry.MustRegister(gridPower, reactivePower, gridBuy)
+	gridPower.WithLabelValues("http://fenecon", "_sum").Set(1200)
+	reactivePower.WithLabelValues("http://fenecon", "meter0").Set(-30)
+	gridBuy.WithLabelValues("http://fenecon", "_sum").Add(123456)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gridPowerTopic := "fenecon/fenecon/_sum/fenecon_grid_power"
+	reactivePowerTopic := "fenecon/fenecon/meter0/fenecon_meter_reactive_power"
+	gridPowerDiscoveryTopic := "homeassistant/sensor/fenecon_fenecon/fenecon_fenecon__sum_grid_power/config"
+	gridBuyDiscoveryTopic := "homeassistant/sensor/fenecon_fenecon/fenecon_fenecon__sum_grid_buy_energy_watt_hours_total/config"
+	reactivePowerDiscoveryTopic := "homeassistant/sensor/fenecon_fenecon/fenecon_fenecon_meter0_meter_reactive_power/config"
+
+	// discovery configs not confirmed by the broker
+	broker.withholdAcks("homeassistant/")
+	if err := publisher.Publish("http://fenecon", families); err == nil {
+		t.Fatal("expected error for unconfirmed discovery configs")
+	}
+
+	if messages := broker.waitReceived(t, gridPowerTopic, 1); messages[0].payload != "1200" || messages[0].retained {
+		t.Errorf("unexpected value message %+v", messages[0])
+	}
+	if messages := broker.waitReceived(t, reactivePowerTopic, 1); messages[0].payload != "-30" {
+		t.Errorf("unexpected value message %+v", messages[0])
+	}
+	if messages := broker.received(gridPowerDiscoveryTopic); len(messages) != 1 {
+		t.Fatalf("expected 1 discovery config, got %v", len(messages))
+	}
+	if messages := broker.received(reactivePowerDiscoveryTopic); len(messages) != 0 {
+		t.Errorf("expected no discovery config for reactive power, got %v", len(messages))
+	}
+
+	// unconfirmed discovery configs are published again
+	broker.withholdAcks("")
+	if err := publisher.Publish("http://fenecon", families); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, topic := range []string{gridPowerDiscoveryTopic, gridBuyDiscoveryTopic} {
+		if messages := broker.received(topic); len(messages) != 2 {
+			t.Errorf("expected discovery config %v published again, got %v messages", topic, len(messages))
+		}
+	}
+
+	messages := broker.received(gridPowerDiscoveryTopic)
+	config := mqttDiscoveryConfig{}
+	if err := json.Unmarshal([]byte(messages[1].payload), &config); err != nil {
+		t.Fatal(err)
+	}
+	if !messages[1].retained {
+		t.Error("expected retained discovery config")
+	}
+	if config.StateTopic != gridPowerTopic || config.DeviceClass != "power" || config.UnitOfMeasurement != "W" || config.AvailabilityTopic != "fenecon/status" {
+		t.Errorf("unexpected discovery config %+v", config)
+	}
+
+	// confirmed discovery configs are not published again
+	if err := publisher.Publish("http://fenecon", families); err != nil {
+		t.Fatal(err)
+	}
+	broker.waitReceived(t, gridPowerTopic, 3)
+	if messages := broker.received(gridPowerDiscoveryTopic); len(messages) != 2 {
+		t.Errorf("expected confirmed discovery config not published again, got %v messages", len(messages))
+	}
+}
@@ -0,0 +1,199 @@
+package fenecon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	remoteWriteSegmentSuffix = ".seg"
+	remoteWriteTmpSuffix     = ".tmp"
+)
+
+type (
+	// RemoteWriteBuffer is a WAL-style buffer of encoded write requests (segments) which are kept until they are sent,
+	// segments are stored as files in the directory (in memory if no directory is set) to survive outages and restarts,
+	// oldest segments are dropped if the max size is exceeded
+	RemoteWriteBuffer struct {
+		dir     string
+		maxSize int64
+
+		segments []*remoteWriteSegment
+		size     int64
+		lastId   int64
+		lock     sync.Mutex
+	}
+
+	remoteWriteSegment struct {
+		name string
+		size int64
+
+		// only for in memory buffer
+		data []byte
+	}
+)
+
+// NewRemoteWriteBuffer creates the buffer and loads the segments of the directory (if set)
+func NewRemoteWriteBuffer(dir string, maxSize int64) (*RemoteWriteBuffer, error) {
+	b := RemoteWriteBuffer{}
+	b.dir = dir
+	b.maxSize = maxSize
+
+	if dir == "" {
+		return &b, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf(`unable to create remote write buffer directory "%v": %w`, dir, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to read remote write buffer directory "%v": %w`, dir, err)
+	}
+
+	// segment names are zero padded timestamps, directory entries are sorted by name
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		// incomplete segment of a crash while writing
+		if strings.HasSuffix(entry.Name(), remoteWriteTmpSuffix) {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf(`unable to remove remote write buffer temp file "%v": %w`, entry.Name(), err)
+			}
+			continue
+		}
+
+		if !strings.HasSuffix(entry.Name(), remoteWriteSegmentSuffix) {
+			continue
+		}
+
+		// new segments must be ordered after the loaded segments (eg. clock was set back)
+		id, err := strconv.ParseInt(strings.TrimSuffix(entry.Name(), remoteWriteSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		b.lastId = max(b.lastId, id)
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf(`unable to read remote write buffer directory "%v": %w`, dir, err)
+		}
+
+		b.segments = append(b.segments, &remoteWriteSegment{name: entry.Name(), size: info.Size()})
+		b.size += info.Size()
+	}
+
+	return &b, nil
+}
+
+// Append adds the segment and returns the number of dropped (oldest) segments if max size is exceeded
+func (b *RemoteWriteBuffer) Append(data []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	// unique and ordered segment names
+	b.lastId = max(time.Now().UnixNano(), b.lastId+1)
+	segment := &remoteWriteSegment{
+		name: fmt.Sprintf(`%020d%s`, b.lastId, remoteWriteSegmentSuffix),
+		size: int64(len(data)),
+	}
+
+	if b.dir == "" {
+		segment.data = data
+	} else if err := b.writeSegment(segment.name, data); err != nil {
+		return 0, err
+	}
+
+	b.segments = append(b.segments, segment)
+	b.size += segment.size
+
+	dropped := 0
+	for b.maxSize > 0 && b.size > b.maxSize && len(b.segments) > 1 {
+		if err := b.remove(b.segments[0].name); err != nil {
+			return dropped, err
+		}
+		dropped++
+	}
+
+	return dropped, nil
+}
+
+// writeSegment writes the segment to a temp file and renames it, segments must not be truncated on crash
+func (b *RemoteWriteBuffer) writeSegment(name string, data []byte) error {
+	path := filepath.Join(b.dir, name)
+	tmpPath := path + remoteWriteTmpSuffix
+
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf(`unable to write remote write buffer segment "%v": %w`, path, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf(`unable to write remote write buffer segment "%v": %w`, path, err)
+	}
+
+	return nil
+}
+
+// Oldest returns the name and data of the oldest segment, empty name if the buffer is empty
+func (b *RemoteWriteBuffer) Oldest() (string, []byte, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if len(b.segments) == 0 {
+		return "", nil, nil
+	}
+
+	segment := b.segments[0]
+	if b.dir == "" {
+		return segment.name, segment.data, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(b.dir, segment.name))
+	if err != nil {
+		return segment.name, nil, fmt.Errorf(`unable to read remote write buffer segment "%v": %w`, segment.name, err)
+	}
+
+	return segment.name, data, nil
+}
+
+// Remove removes the segment (after it was sent or if it cannot be sent)
+func (b *RemoteWriteBuffer) Remove(name string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.remove(name)
+}
+
+func (b *RemoteWriteBuffer) remove(name string) error {
+	index := slices.IndexFunc(b.segments, func(segment *remoteWriteSegment) bool { return segment.name == name })
+	if index < 0 {
+		return nil
+	}
+
+	b.size -= b.segments[index].size
+	b.segments = slices.Delete(b.segments, index, index+1)
+
+	if b.dir != "" {
+		if err := os.Remove(filepath.Join(b.dir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf(`unable to remove remote write buffer segment "%v": %w`, name, err)
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of buffered segments
+func (b *RemoteWriteBuffer) Len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.segments)
+}
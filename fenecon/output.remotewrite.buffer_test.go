@@ -0,0 +1,123 @@
+package fenecon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoteWriteBuffer(t *testing.T) {
+	for _, dir := range []string{"", t.TempDir()} {
+		t.Run("dir="+dir, func(t *testing.T) {
+			buffer, err := NewRemoteWriteBuffer(dir, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, data := range []string{"aaaa", "bbbb"} {
+				if dropped, err := buffer.Append([]byte(data)); err != nil || dropped != 0 {
+					t.Fatalf("unexpected append result %v, %v", dropped, err)
+				}
+			}
+
+			// max size exceeded, oldest segment is dropped
+			if dropped, err := buffer.Append([]byte("cccc")); err != nil || dropped != 1 {
+				t.Fatalf("expected 1 dropped segment, got %v, %v", dropped, err)
+			}
+
+			for _, expected := range []string{"bbbb", "cccc"} {
+				name, data, err := buffer.Oldest()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != expected {
+					t.Errorf("expected oldest segment %v, got %v", expected, string(data))
+				}
+				if err := buffer.Remove(name); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if name, _, err := buffer.Oldest(); err != nil || name != "" || buffer.Len() != 0 {
+				t.Errorf("expected empty buffer, got %v segments", buffer.Len())
+			}
+		})
+	}
+}
+
+func TestRemoteWriteBufferSegmentLargerThanMaxSize(t *testing.T) {
+	buffer, err := NewRemoteWriteBuffer("", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the newest segment is kept even if it exceeds the max size
+	if dropped, err := buffer.Append([]byte("aaaa")); err != nil || dropped != 0 {
+		t.Fatalf("unexpected append result %v, %v", dropped, err)
+	}
+	if buffer.Len() != 1 {
+		t.Errorf("expected 1 segment, got %v", buffer.Len())
+	}
+}
+
+func TestRemoteWriteBufferReload(t *testing.T) {
+	dir := t.TempDir()
+
+	// segment written with a clock ahead of the current time, incomplete segment of a crash
+	futureName := "09000000000000000000" + remoteWriteSegmentSuffix
+	if err := os.WriteFile(filepath.Join(dir, futureName), []byte("future"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001"+remoteWriteSegmentSuffix+remoteWriteTmpSuffix), []byte("incomplete"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	buffer, err := NewRemoteWriteBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buffer.Append([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), remoteWriteTmpSuffix) {
+			t.Errorf("expected temp file %v to be removed", entry.Name())
+		}
+	}
+
+	reloaded, err := NewRemoteWriteBuffer(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Len() != 2 {
+		t.Fatalf("expected 2 segments after reload, got %v", reloaded.Len())
+	}
+
+	// new segments are ordered after the loaded segments
+	for _, expected := range []string{"future", "new"} {
+		name, data, err := reloaded.Oldest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("expected segment %v, got %v", expected, string(data))
+		}
+		if err := reloaded.Remove(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ids continue after the loaded segments, not after the current time
+	if _, err := reloaded.Append([]byte("next")); err != nil {
+		t.Fatal(err)
+	}
+	if name, _, _ := reloaded.Oldest(); name <= futureName {
+		t.Errorf("expected segment after %v, got %v", futureName, name)
+	}
+}
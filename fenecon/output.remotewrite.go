@@ -0,0 +1,180 @@
+package fenecon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+	resty "resty.dev/v3"
+)
+
+var (
+	// ErrRemoteWriteRecoverable is returned if the remote write request should be retried (connection errors, 5xx, 429)
+	ErrRemoteWriteRecoverable = errors.New(`recoverable remote write error`)
+)
+
+type (
+	RemoteWriteOpts struct {
+		// remote write url (eg. https://prometheus/api/v1/write)
+		Url string
+
+		Username    string
+		Password    string
+		BearerToken string
+
+		// labels added to every series (eg. site)
+		ExternalLabels map[string]string
+
+		UserAgent string
+		Timeout   time.Duration
+	}
+
+	// RemoteWriter sends samples to a Prometheus remote write endpoint (remote write 1.0: snappy compressed protobuf WriteRequest)
+	RemoteWriter struct {
+		opts   RemoteWriteOpts
+		client *resty.Client
+	}
+
+	remoteWriteLabel struct {
+		name  string
+		value string
+	}
+)
+
+func NewRemoteWriter(opts RemoteWriteOpts) *RemoteWriter {
+	w := RemoteWriter{}
+	w.opts = opts
+	w.client = resty.New()
+	w.client.SetTimeout(opts.Timeout)
+	w.client.SetHeader("User-Agent", opts.UserAgent)
+
+	switch {
+	case opts.BearerToken != "":
+		w.client.SetAuthToken(opts.BearerToken)
+	case opts.Username != "" || opts.Password != "":
+		w.client.SetBasicAuth(opts.Username, opts.Password)
+	}
+
+	return &w
+}
+
+// Encode encodes the metrics as snappy compressed WriteRequest, timestamp is used for metrics without timestamp,
+// returns nil if there are no samples
+func (w *RemoteWriter) Encode(families []*dto.MetricFamily, timestamp time.Time) []byte {
+	request := []byte{}
+
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			var value float64
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				value = metric.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				value = metric.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				value = metric.GetUntyped().GetValue()
+			default:
+				continue
+			}
+
+			if math.IsNaN(value) {
+				continue
+			}
+
+			sampleTimestamp := timestamp.UnixMilli()
+			if metric.TimestampMs != nil {
+				sampleTimestamp = metric.GetTimestampMs()
+			}
+
+			// WriteRequest.timeseries = 1
+			request = protowire.AppendTag(request, 1, protowire.BytesType)
+			request = protowire.AppendBytes(request, w.encodeTimeSeries(w.labels(family.GetName(), metric), value, sampleTimestamp))
+		}
+	}
+
+	if len(request) == 0 {
+		return nil
+	}
+
+	return snappy.Encode(nil, request)
+}
+
+// labels returns the labels of the series including metric name and external labels (sorted by name, required by remote write)
+func (w *RemoteWriter) labels(name string, metric *dto.Metric) []remoteWriteLabel {
+	labels := []remoteWriteLabel{{name: "__name__", value: name}}
+	for _, label := range metric.Label {
+		labels = append(labels, remoteWriteLabel{name: label.GetName(), value: label.GetValue()})
+	}
+
+	for labelName, labelValue := range w.opts.ExternalLabels {
+		if !slices.ContainsFunc(labels, func(label remoteWriteLabel) bool { return label.name == labelName }) {
+			labels = append(labels, remoteWriteLabel{name: labelName, value: labelValue})
+		}
+	}
+
+	slices.SortFunc(labels, func(a, b remoteWriteLabel) int { return strings.Compare(a.name, b.name) })
+	return labels
+}
+
+func (w *RemoteWriter) encodeTimeSeries(labels []remoteWriteLabel, value float64, timestamp int64) []byte {
+	series := []byte{}
+
+	for _, label := range labels {
+		if label.value == "" {
+			continue
+		}
+
+		// Label.name = 1, Label.value = 2
+		encodedLabel := protowire.AppendTag(nil, 1, protowire.BytesType)
+		encodedLabel = protowire.AppendString(encodedLabel, label.name)
+		encodedLabel = protowire.AppendTag(encodedLabel, 2, protowire.BytesType)
+		encodedLabel = protowire.AppendString(encodedLabel, label.value)
+
+		// TimeSeries.labels = 1
+		series = protowire.AppendTag(series, 1, protowire.BytesType)
+		series = protowire.AppendBytes(series, encodedLabel)
+	}
+
+	// Sample.value = 1, Sample.timestamp = 2
+	sample := protowire.AppendTag(nil, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestamp)) // #nosec G115 -- negative timestamps are encoded as in protobuf int64
+
+	// TimeSeries.samples = 2
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+
+	return series
+}
+
+// Write sends the encoded WriteRequest, errors wrapping ErrRemoteWriteRecoverable should be retried
+func (w *RemoteWriter) Write(ctx context.Context, data []byte) error {
+	response, err := w.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/x-protobuf").
+		SetHeader("Content-Encoding", "snappy").
+		SetHeader("X-Prometheus-Remote-Write-Version", "0.1.0").
+		SetBody(data).
+		Post(w.opts.Url)
+	if err != nil {
+		return fmt.Errorf(`%w: %w`, ErrRemoteWriteRecoverable, err)
+	}
+
+	switch statusCode := response.StatusCode(); {
+	case statusCode >= 200 && statusCode < 300:
+		return nil
+	case statusCode >= 500, statusCode == http.StatusTooManyRequests:
+		return fmt.Errorf(`%w: remote write failed with status %v: %v`, ErrRemoteWriteRecoverable, statusCode, strings.TrimSpace(response.String()))
+	default:
+		return fmt.Errorf(`%w: remote write failed with status %v: %v`, ErrHttpStatus, statusCode, strings.TrimSpace(response.String()))
+	}
+}
@@ -0,0 +1,190 @@
+package fenecon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type testRemoteWriteSeries struct {
+	labels    string
+	value     float64
+	timestamp int64
+}
+
+// decodeTestWriteRequest decodes the snappy compressed WriteRequest, labels are joined as name=value (in encoded order)
+func decodeTestWriteRequest(t *testing.T, data []byte) []testRemoteWriteSeries {
+	t.Helper()
+
+	request, err := snappy.Decode(nil, data)
+	if err != nil {
+		t.Fatalf("invalid snappy data: %v", err)
+	}
+
+	ret := []testRemoteWriteSeries{}
+	for _, seriesData := range consumeTestMessage(t, request, 1) {
+		series := testRemoteWriteSeries{}
+		labels := []string{}
+
+		for _, labelData := range consumeTestMessage(t, seriesData, 1) {
+			fields := consumeTestMessage(t, labelData, 1, 2)
+			labels = append(labels, string(fields[0])+"="+string(fields[1]))
+		}
+		series.labels = strings.Join(labels, ",")
+
+		for _, sample := range consumeTestMessage(t, seriesData, 2) {
+			for len(sample) > 0 {
+				num, typ, n := protowire.ConsumeTag(sample)
+				sample = sample[n:]
+				switch {
+				case num == 1 && typ == protowire.Fixed64Type:
+					value, n := protowire.ConsumeFixed64(sample)
+					series.value = math.Float64frombits(value)
+					sample = sample[n:]
+				case num == 2 && typ == protowire.VarintType:
+					value, n := protowire.ConsumeVarint(sample)
+					series.timestamp = int64(value) // #nosec G115 -- test data
+					sample = sample[n:]
+				default:
+					t.Fatalf("unexpected sample field %v", num)
+				}
+			}
+		}
+
+		ret = append(ret, series)
+	}
+
+	return ret
+}
+
+// consumeTestMessage returns the bytes fields of the message with the field numbers (in message order)
+func consumeTestMessage(t *testing.T, data []byte, fieldNumbers ...protowire.Number) [][]byte {
+	t.Helper()
+
+	ret := [][]byte{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			t.Fatalf("invalid protobuf tag: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				t.Fatalf("invalid protobuf field: %v", protowire.ParseError(n))
+			}
+			data = data[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			t.Fatalf("invalid protobuf bytes: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		for _, fieldNumber := range fieldNumbers {
+			if num == fieldNumber {
+				ret = append(ret, value)
+			}
+		}
+	}
+
+	return ret
+}
+
+func TestRemoteWriterEncode(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gridPower := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "fenecon_grid_power"}, []string{"target", "module"})
+	gridBuy := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "fenecon_grid_buy_energy_watt_hours_total"}, []string{"target", "module"})
+	batteryCharge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "fenecon_battery_charge_percent"}, []string{"target", "module"})
+	duration := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "fenecon_test_duration_seconds"})
+	registry.MustRegister(gridPower, gridBuy, batteryCharge, duration)
+	gridPower.WithLabelValues("http://fenecon", "_sum").Set(-1200.5)
+	gridBuy.WithLabelValues("http://fenecon", "_sum").Add(123456)
+	batteryCharge.WithLabelValues("http://fenecon", "").Set(math.NaN())
+	duration.Observe(1)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	writer := NewRemoteWriter(RemoteWriteOpts{ExternalLabels: map[string]string{"site": "home", "module": "ignored"}})
+	series := decodeTestWriteRequest(t, writer.Encode(families, timestamp))
+
+	// labels sorted by name, empty labels, NaN and histograms skipped, external labels don't override series labels
+	expected := []testRemoteWriteSeries{
+		{labels: "__name__=fenecon_grid_buy_energy_watt_hours_total,module=_sum,site=home,target=http://fenecon", value: 123456, timestamp: timestamp.UnixMilli()},
+		{labels: "__name__=fenecon_grid_power,module=_sum,site=home,target=http://fenecon", value: -1200.5, timestamp: timestamp.UnixMilli()},
+	}
+	if len(series) != len(expected) {
+		t.Fatalf("expected %v series, got %+v", len(expected), series)
+	}
+	for i := range expected {
+		if series[i] != expected[i] {
+			t.Errorf("expected series %+v, got %+v", expected[i], series[i])
+		}
+	}
+
+	if data := writer.Encode(nil, timestamp); data != nil {
+		t.Errorf("expected nil for empty request, got %v bytes", len(data))
+	}
+}
+
+func TestRemoteWriterWrite(t *testing.T) {
+	statusCode := http.StatusNoContent
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+
+	writer := NewRemoteWriter(RemoteWriteOpts{Url: server.URL + "/api/v1/write", BearerToken: "secret", UserAgent: "fenecon-exporter", Timeout: 5 * time.Second})
+	data := snappy.Encode(nil, []byte("request"))
+
+	if err := writer.Write(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	if received.URL.Path != "/api/v1/write" || received.Header.Get("Authorization") != "Bearer secret" ||
+		received.Header.Get("Content-Encoding") != "snappy" || received.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		t.Errorf("unexpected request %v %v", received.URL, received.Header)
+	}
+	if string(body) != string(data) {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	tests := []struct {
+		statusCode  int
+		recoverable bool
+	}{
+		{statusCode: http.StatusInternalServerError, recoverable: true},
+		{statusCode: http.StatusTooManyRequests, recoverable: true},
+		{statusCode: http.StatusBadRequest, recoverable: false},
+	}
+	for _, test := range tests {
+		statusCode = test.statusCode
+		err := writer.Write(context.Background(), data)
+		if errors.Is(err, ErrRemoteWriteRecoverable) != test.recoverable {
+			t.Errorf("status %v: expected recoverable %v, got %v", test.statusCode, test.recoverable, err)
+		}
+		if !test.recoverable && !errors.Is(err, ErrHttpStatus) {
+			t.Errorf("status %v: expected ErrHttpStatus, got %v", test.statusCode, err)
+		}
+	}
+}
@@ -5,7 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"strings"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -21,10 +22,43 @@ type (
 		logger   *slogger.Logger
 		registry *prometheus.Registry
 
+		// metrics of initMetrics (reset between the timestamps of a backfill)
+		metricVecs []interface{ Reset() }
+
 		parallelRequests int
+		queryGroups      []string
+		userAgent        string
+		legacyMetrics    bool
+
+		transportName string
+		transport     transport
+		websocket     struct {
+			port     int
+			username string
+			password string
+			timeout  time.Duration
+		}
+
+		// protection of energy counters against lower readings (nil: values are exported as reported)
+		counters *counterStore
+
+		rawFilter  *RawFilter
+		aggregator *EnergyAggregator
+		edgeInfo   *EdgeInfo
+
+		componentCache *ComponentCache
+		components     map[string]ComponentInfo
+		costAccountant *CostAccountant
+
+		mapping        *Mapping
+		mappingMetrics []*constMetricVec
 
 		target FeneconProberTarget
 
+		errors            []error
+		successfulQueries int
+		errorsLock        sync.Mutex
+
 		prometheus feneconMetrics
 	}
 
@@ -39,6 +73,12 @@ func New(ctx context.Context, registry *prometheus.Registry, logger *slogger.Log
 	fp.registry = registry
 	fp.logger = logger
 	fp.parallelRequests = 5
+	fp.queryGroups = DefaultQueryGroups()
+	fp.legacyMetrics = true
+	fp.transportName = TransportRest
+	fp.websocket.port = DefaultWebsocketPort
+	fp.websocket.timeout = 10 * time.Second
+	fp.counters = &counters
 	fp.initResty()
 	fp.initMetrics()
 
@@ -71,7 +111,7 @@ func (fp *FeneconProber) initResty() {
 
 		switch statusCode := response.StatusCode(); statusCode {
 		case 401:
-			return errors.New(`fenecon requires authentication or credentials are invalid`)
+			return ErrAuthentication
 		case 404:
 			// ignore non existing endpoints
 			return nil
@@ -79,12 +119,13 @@ func (fp *FeneconProber) initResty() {
 			// all ok, proceed
 			return nil
 		default:
-			return fmt.Errorf(`expected http status 200, got %v`, response.StatusCode())
+			return fmt.Errorf(`%w: expected http status 200, got %v`, ErrHttpStatus, response.StatusCode())
 		}
 	})
 }
 
 func (fp *FeneconProber) SetUserAgent(val string) {
+	fp.userAgent = val
 	fp.client.SetHeader("User-Agent", val)
 }
 
@@ -100,194 +141,194 @@ func (fp *FeneconProber) SetRetry(retry int, waitTime, maxWaitTime time.Duration
 
 func (fp *FeneconProber) SetTimeout(timeout time.Duration) {
 	fp.client.SetTimeout(timeout)
+	fp.websocket.timeout = timeout
+}
+
+// SetStatusRegistry moves the probe metrics (probe success and duration, per query results) to a separate registry,
+// eg. to serve them even if the other metrics of a failed probe are dropped
+func (fp *FeneconProber) SetStatusRegistry(registry *prometheus.Registry) {
+	collectors := []prometheus.Collector{
+		fp.prometheus.probe.success,
+		fp.prometheus.probe.duration,
+		fp.prometheus.probe.querySuccess,
+		fp.prometheus.probe.queryDuration,
+		fp.prometheus.probe.queryHttpStatus,
+		fp.prometheus.probe.queryError,
+	}
+
+	for _, collector := range collectors {
+		fp.registry.Unregister(collector)
+		registry.MustRegister(collector)
+	}
 }
 
 func (fp *FeneconProber) SetHttpAuth(username, password string) {
 	fp.client.SetDisableWarn(true)
 	fp.client.SetBasicAuth(username, password)
+	fp.websocket.username = username
+	fp.websocket.password = password
 }
 
-func (fp *FeneconProber) Run(target FeneconProberTarget) {
+// Run probes the target and returns all errors which occurred while querying the Fenecon system
+func (fp *FeneconProber) Run(target FeneconProberTarget) error {
 	fp.target = target
 	fp.logger.With(slog.String("target", target.Target))
 
 	startTime := time.Now()
 	fp.logger.Info(`start probe`)
 
-	client := fp.client.SetBaseURL(
-		fmt.Sprintf(`%s/rest/channel/`, strings.TrimRight(target.Target, "/")),
-	)
-
-	commonLabels := prometheus.Labels{"target": target.Target, "module": "_sum"}
-	phase1Labels := prometheus.Labels{"target": target.Target, "module": "_sum", "phase": "1"}
-	phase2Labels := prometheus.Labels{"target": target.Target, "module": "_sum", "phase": "2"}
-	phase3Labels := prometheus.Labels{"target": target.Target, "module": "_sum", "phase": "3"}
-
-	fp.prometheus.info.With(commonLabels).Set(1)
-
-	wg := sizedwaitgroup.New(fp.parallelRequests)
+	connectStartTime := time.Now()
+	transport, err := fp.newTransport(target)
+	fp.recordQueryResult("connect", connectStartTime, 0, err)
+	if err == nil {
+		fp.transport = transport
+		defer func() {
+			if err := fp.transport.Close(); err != nil {
+				fp.logger.Warn(`failed to close transport`, slog.Any("error", err))
+			}
+		}()
+	} else {
+		fp.logger.Error(`failed to connect`, slog.String("transport", fp.transportName), slog.Any("error", err))
+		fp.recordProbeResult(startTime, err)
+		return err
+	}
 
-	// ------------------------------------------------------------------------
-	// SUM
-	wg.Add()
-	go func() {
-		defer wg.Done()
+	fp.collectEdgeInfo()
+	fp.collectComponents()
 
-		result, err := fp.queryWildcard(client, "_sum/.*")
+	if fp.rawFilter != nil {
+		// raw mode: all channels are queried once
+		result, err := fp.queryWildcard(RawQuery)
 		if err == nil {
-			// general
-			result.Address("_sum/State").SetGauge(commonLabels, fp.prometheus.status)
-
-			// battery
-			result.Address("_sum/EssSoc").SetGauge(commonLabels, fp.prometheus.battery.charge)
-			result.Address("_sum/EssCapacity").SetGauge(commonLabels, fp.prometheus.battery.capacity)
-			result.Address("_sum/EssActivePower").SetGauge(commonLabels, fp.prometheus.battery.power)
-			result.Address("_sum/EssActiveChargeEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.battery.powerChargeTotal)
-			result.Address("_sum/EssActiveDischargeEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.battery.powerDischargeTotal)
-			result.Address("_sum/EssDcChargeEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.battery.powerDcChargeTotal)
-			result.Address("_sum/EssDcDischargeEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.battery.powerDcDischargeTotal)
-			result.Address("_sum/EssActivePowerL1").SetGauge(phase1Labels, fp.prometheus.battery.powerPhase)
-			result.Address("_sum/EssActivePowerL2").SetGauge(phase2Labels, fp.prometheus.battery.powerPhase)
-			result.Address("_sum/EssActivePowerL3").SetGauge(phase3Labels, fp.prometheus.battery.powerPhase)
-
-			// grid
-			result.Address("_sum/GridMode").SetGauge(commonLabels, fp.prometheus.grid.mode)
-			result.Address("_sum/GridActivePower").SetGauge(commonLabels, fp.prometheus.grid.power)
-			result.Address("_sum/GridBuyActiveEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.grid.powerBuyTotal)
-			result.Address("_sum/GridSellActiveEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.grid.powerSellTotal)
-			result.Address("_sum/GridActivePowerL1").SetGauge(phase1Labels, fp.prometheus.grid.powerPhase)
-			result.Address("_sum/GridActivePowerL2").SetGauge(phase2Labels, fp.prometheus.grid.powerPhase)
-			result.Address("_sum/GridActivePowerL3").SetGauge(phase3Labels, fp.prometheus.grid.powerPhase)
-
-			// grid
-			result.Address("_sum/GridMode").SetGauge(commonLabels, fp.prometheus.grid.mode)
-			result.Address("_sum/GridActivePower").SetGauge(commonLabels, fp.prometheus.grid.power)
-			result.Address("_sum/GridBuyActiveEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.grid.powerBuyTotal)
-			result.Address("_sum/GridSellActiveEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.grid.powerSellTotal)
-			result.Address("_sum/GridActivePowerL1").SetGauge(phase1Labels, fp.prometheus.grid.powerPhase)
-			result.Address("_sum/GridActivePowerL2").SetGauge(phase2Labels, fp.prometheus.grid.powerPhase)
-			result.Address("_sum/GridActivePowerL3").SetGauge(phase3Labels, fp.prometheus.grid.powerPhase)
-
-			// production
-			result.Address("_sum/ProductionActivePower").SetGauge(commonLabels, fp.prometheus.production.power)
-			result.Address("_sum/ProductionAcActivePower").SetGauge(commonLabels, fp.prometheus.production.powerAc)
-			result.Address("_sum/ProductionDcActualPower").SetGauge(commonLabels, fp.prometheus.production.powerDc)
-			result.Address("_sum/ProductionActiveEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.production.powerTotal)
-			result.Address("_sum/ProductionAcActiveEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.production.powerAcTotal)
-			result.Address("_sum/ProductionDcActiveEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.production.powerDcTotal)
-			result.Address("_sum/ProductionAcActivePowerL1").SetGauge(phase1Labels, fp.prometheus.production.powerPhase)
-			result.Address("_sum/ProductionAcActivePowerL2").SetGauge(phase2Labels, fp.prometheus.production.powerPhase)
-			result.Address("_sum/ProductionAcActivePowerL3").SetGauge(phase3Labels, fp.prometheus.production.powerPhase)
-
-			// consumption
-			result.Address("_sum/ConsumptionActivePower").SetGauge(commonLabels, fp.prometheus.consumption.power)
-			result.Address("_sum/ConsumptionActiveEnergy").SetGaugeIfNotZero(commonLabels, fp.prometheus.consumption.powerTotal)
-			result.Address("_sum/ConsumptionActivePowerL1").SetGauge(phase1Labels, fp.prometheus.consumption.powerPhase)
-			result.Address("_sum/ConsumptionActivePowerL2").SetGauge(phase2Labels, fp.prometheus.consumption.powerPhase)
-			result.Address("_sum/ConsumptionActivePowerL3").SetGauge(phase3Labels, fp.prometheus.consumption.powerPhase)
+			fp.collectRaw(result)
 		}
-	}()
+	} else {
+		fp.runQueryGroups()
+	}
 
-	// ------------------------------------------------------------------------
-	// Ess (Batteries)
-	wg.Add()
-	go func() {
-		defer wg.Done()
+	if fp.aggregator != nil {
+		if err := fp.aggregator.Save(); err != nil {
+			fp.logger.Warn(`failed to save energy state`, slog.Any("error", err))
+		}
+	}
 
-		result, err := fp.queryWildcard(client, "ess.*/.*")
-		if err == nil {
-			for _, module := range result.AddressParts(0) {
-				batteryLabels := prometheus.Labels{"target": target.Target, "module": module}
-
-				result.Address(module, "State").SetGauge(batteryLabels, fp.prometheus.status)
-				result.Address(module, "GridMode").SetGauge(batteryLabels, fp.prometheus.grid.mode)
-				result.Address(module, "Soc").SetGauge(batteryLabels, fp.prometheus.battery.charge)
-				result.Address(module, "Capacity").SetGauge(batteryLabels, fp.prometheus.battery.capacity)
-				result.Address(module, "ActivePower").SetGauge(batteryLabels, fp.prometheus.battery.power)
-				result.Address(module, "ActiveChargeEnergy").SetGaugeIfNotZero(batteryLabels, fp.prometheus.battery.powerChargeTotal)
-				result.Address(module, "ActiveDischargeEnergy").SetGaugeIfNotZero(batteryLabels, fp.prometheus.battery.powerDischargeTotal)
-				result.Address(module, "AllowedChargePower").SetGauge(batteryLabels, fp.prometheus.battery.allowedChargePower)
-				result.Address(module, "AllowedDischargePower").SetGauge(batteryLabels, fp.prometheus.battery.allowedDischargePower)
-			}
+	if fp.costAccountant != nil {
+		if err := fp.costAccountant.Save(); err != nil {
+			fp.logger.Warn(`failed to save cost state`, slog.Any("error", err))
 		}
-	}()
+	}
 
-	// ------------------------------------------------------------------------
-	// Charger (eg. panels)
-	wg.Add()
-	go func() {
-		defer wg.Done()
+	fp.logger.Info(`finished probe`, slog.Duration("duration", time.Since(startTime)))
 
-		result, err := fp.queryWildcard(client, "charger.*/.*")
-		if err == nil {
-			for _, module := range result.AddressParts(0) {
-				chargerLabels := prometheus.Labels{"target": target.Target, "module": module}
+	err = errors.Join(fp.errors...)
+	fp.recordProbeResult(startTime, err)
+	return err
+}
 
-				result.Address(module, "State").SetGauge(chargerLabels, fp.prometheus.status)
-				result.Address(module, "ActualPower").SetGauge(chargerLabels, fp.prometheus.production.power)
-				result.Address(module, "ActualEnergy").SetGaugeIfNotZero(chargerLabels, fp.prometheus.production.powerTotal)
-				result.Address(module, "MaxActualPower").SetGauge(chargerLabels, fp.prometheus.production.maxActualPower)
-			}
+// HasResults returns true if at least one query of the probe was successful (metrics are usable even if the probe failed)
+func (fp *FeneconProber) HasResults() bool {
+	fp.errorsLock.Lock()
+	defer fp.errorsLock.Unlock()
+	return fp.successfulQueries >= 1
+}
+
+// runQueryGroups runs all enabled query groups and the additional queries of the mapping file in parallel
+func (fp *FeneconProber) runQueryGroups() {
+	wg := sizedwaitgroup.New(fp.parallelRequests)
+
+	groupQueries := []string{}
+	for _, group := range queryGroups {
+		if !fp.queryGroupEnabled(group.name) {
+			continue
 		}
-	}()
+		groupQueries = append(groupQueries, group.query)
 
-	// ------------------------------------------------------------------------
-	// Meter (eg. panels)
-	wg.Add()
-	go func() {
-		defer wg.Done()
+		wg.Add()
+		go func() {
+			defer wg.Done()
 
-		result, err := fp.queryWildcard(client, "meter.*/.*")
-		if err == nil {
-			for _, module := range result.AddressParts(0) {
-				meterLabels := prometheus.Labels{"target": target.Target, "module": module}
-				meterPhase1Labels := prometheus.Labels{"target": target.Target, "module": module, "phase": "1"}
-				meterPhase2Labels := prometheus.Labels{"target": target.Target, "module": module, "phase": "2"}
-				meterPhase3Labels := prometheus.Labels{"target": target.Target, "module": module, "phase": "3"}
-
-				result.Address(module, "State").SetGauge(meterLabels, fp.prometheus.status)
-				result.Address(module, "Frequency").SetGauge(meterLabels, fp.prometheus.meter.frequency)
-				result.Address(module, "Voltage").SetGauge(meterLabels, fp.prometheus.meter.voltage)
-				result.Address(module, "VoltageL1").SetGauge(meterPhase1Labels, fp.prometheus.meter.voltagePhase)
-				result.Address(module, "VoltageL2").SetGauge(meterPhase2Labels, fp.prometheus.meter.voltagePhase)
-				result.Address(module, "VoltageL3").SetGauge(meterPhase3Labels, fp.prometheus.meter.voltagePhase)
-				result.Address(module, "ActivePower").SetGauge(meterLabels, fp.prometheus.meter.power)
-				result.Address(module, "ActivePowerL1").SetGauge(meterPhase1Labels, fp.prometheus.meter.powerPhase)
-				result.Address(module, "ActivePowerL2").SetGauge(meterPhase2Labels, fp.prometheus.meter.powerPhase)
-				result.Address(module, "ActivePowerL3").SetGauge(meterPhase3Labels, fp.prometheus.meter.powerPhase)
-				result.Address(module, "ReactivePower").SetGauge(meterLabels, fp.prometheus.meter.reactivePower)
-				result.Address(module, "ReactivePowerL1").SetGauge(meterPhase1Labels, fp.prometheus.meter.reactivePowerPhase)
-				result.Address(module, "ReactivePowerL2").SetGauge(meterPhase2Labels, fp.prometheus.meter.reactivePowerPhase)
-				result.Address(module, "ReactivePowerL3").SetGauge(meterPhase3Labels, fp.prometheus.meter.reactivePowerPhase)
-				result.Address(module, "Current").SetGauge(meterLabels, fp.prometheus.meter.current)
-				result.Address(module, "CurrentL1").SetGauge(meterPhase1Labels, fp.prometheus.meter.currentPhase)
-				result.Address(module, "CurrentL2").SetGauge(meterPhase2Labels, fp.prometheus.meter.currentPhase)
-				result.Address(module, "CurrentL3").SetGauge(meterPhase3Labels, fp.prometheus.meter.currentPhase)
-				result.Address(module, "MinActivePower").SetGauge(meterLabels, fp.prometheus.meter.minActivePower)
-				result.Address(module, "MaxActivePower").SetGauge(meterLabels, fp.prometheus.meter.maxActivePower)
-				result.Address(module, "ActiveProductionEnergy").SetGauge(meterLabels, fp.prometheus.meter.powerProductionTotal)
-				result.Address(module, "ActiveConsumptionEnergy").SetGauge(meterLabels, fp.prometheus.meter.powerConsumptionTotal)
+			result, err := fp.queryWildcard(group.query)
+			if err == nil {
+				group.handler(fp, result)
+				fp.collectMapping(group.query, result)
 			}
+		}()
+	}
+
+	// additional queries of the mapping file (if not already queried by a query group)
+	if fp.mapping != nil {
+		for _, query := range fp.mapping.Queries() {
+			if slices.Contains(groupQueries, query) {
+				continue
+			}
+
+			wg.Add()
+			go func() {
+				defer wg.Done()
+
+				result, err := fp.queryWildcard(query)
+				if err == nil {
+					fp.collectMapping(query, result)
+				}
+			}()
 		}
-	}()
+	}
 
 	wg.Wait()
+}
 
-	fp.logger.Info(`finished probe`, slog.Duration("duration", time.Since(startTime)))
+// recordProbeResult sets the probe success and duration metrics
+func (fp *FeneconProber) recordProbeResult(startTime time.Time, err error) {
+	labels := prometheus.Labels{"target": fp.target.Target}
+
+	fp.prometheus.probe.duration.With(labels).Set(time.Since(startTime).Seconds())
+	if err == nil {
+		fp.prometheus.probe.success.With(labels).Set(1)
+	} else {
+		fp.prometheus.probe.success.With(labels).Set(0)
+	}
 }
 
-func (fp *FeneconProber) queryWildcard(client *resty.Client, url string) (*ResultWildcard, error) {
-	result := ResultWildcard{}
+// recordQueryResult sets the per query success, duration, http status and error metrics
+func (fp *FeneconProber) recordQueryResult(query string, startTime time.Time, statusCode int, err error) {
+	labels := prometheus.Labels{"target": fp.target.Target, "query": query}
+
+	fp.prometheus.probe.queryDuration.With(labels).Set(time.Since(startTime).Seconds())
+	if statusCode > 0 {
+		fp.prometheus.probe.queryHttpStatus.With(labels).Set(float64(statusCode))
+	}
+
+	if err == nil {
+		fp.prometheus.probe.querySuccess.With(labels).Set(1)
+	} else {
+		fp.prometheus.probe.querySuccess.With(labels).Set(0)
+		fp.prometheus.probe.queryError.With(prometheus.Labels{
+			"target": fp.target.Target,
+			"query":  query,
+			"class":  ErrorClass(err),
+		}).Set(1)
+	}
+}
 
+func (fp *FeneconProber) queryWildcard(url string) (*ResultWildcard, error) {
 	startTime := time.Now()
 	fp.logger.Debugf(`start query %v`, url)
 
-	_, err := client.R().SetContext(fp.ctx).SetResult(&result).Get(url)
+	result, statusCode, err := fp.transport.Query(fp.ctx, url)
+	fp.recordQueryResult(url, startTime, statusCode, err)
 	if err == nil {
 		fp.logger.Debugf(`finished query %v in %v`, url, time.Since(startTime).String())
+
+		fp.errorsLock.Lock()
+		fp.successfulQueries++
+		fp.errorsLock.Unlock()
 	} else {
 		fp.logger.Errorf(`failed query %v in %v: %v`, url, time.Since(startTime).String(), err)
+
+		fp.errorsLock.Lock()
+		fp.errors = append(fp.errors, fmt.Errorf(`query %v failed: %w`, url, err))
+		fp.errorsLock.Unlock()
 	}
 
-	return &result, err
+	return result, err
 }
@@ -0,0 +1,147 @@
+package fenecon
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// eg. battery0/Tower0Module1Cell012Voltage
+	batteryCellRegexp = regexp.MustCompile(`(?i)^Tower(\d+)Module(\d+)Cell(\d+)(Voltage|Temperature)$`)
+
+	// eg. battery0/Tower0NoOfCycles
+	batteryTowerCyclesRegexp = regexp.MustCompile(`(?i)^Tower(\d+)NoOfCycles$`)
+)
+
+type (
+	batteryCellStats struct {
+		min, max *float64
+	}
+)
+
+// collectBattery collects battery cell telemetry (eg. battery0 of FENECON Home)
+func (fp *FeneconProber) collectBattery(result *ResultWildcard) {
+	for _, module := range result.AddressParts(0) {
+		batteryLabels := prometheus.Labels{"target": fp.target.Target, "module": module}
+
+		result.Address(module, "State").SetGauge(batteryLabels, fp.prometheus.status)
+		result.Address(module, "Soh").SetGauge(batteryLabels, fp.prometheus.batteryCell.soh)
+		fp.setGaugeNormalized(result.Address(module, "ChargeMaxCurrent"), batteryLabels, fp.prometheus.batteryCell.chargeMaxCurrent, fp.prometheus.batteryCell.chargeMaxCurrentAmperes, "A")
+		fp.setGaugeNormalized(result.Address(module, "DischargeMaxCurrent"), batteryLabels, fp.prometheus.batteryCell.dischargeMaxCurrent, fp.prometheus.batteryCell.dischargeMaxCurrentAmperes, "A")
+
+		// stats in raw device units (legacy) and in base units
+		voltageStats := batteryCellStats{}
+		voltageStatsVolts := batteryCellStats{}
+		temperatureStats := batteryCellStats{}
+		temperatureStatsCelsius := batteryCellStats{}
+
+		for _, row := range *result {
+			component, channel := splitAddress(row.Address)
+			if component != module || row.Value.ValueNumeric == nil {
+				continue
+			}
+
+			if match := batteryCellRegexp.FindStringSubmatch(channel); match != nil {
+				cellLabels := prometheus.Labels{
+					"target":         fp.target.Target,
+					"module":         module,
+					"tower":          normalizeIndex(match[1]),
+					"battery_module": normalizeIndex(match[2]),
+					"cell":           normalizeIndex(match[3]),
+				}
+
+				switch strings.ToLower(match[4]) {
+				case "voltage":
+					fp.setGaugeNormalized(&row, cellLabels, fp.prometheus.batteryCell.voltage, fp.prometheus.batteryCell.voltageVolts, "mV")
+					voltageStats.add(*row.Value.ValueNumeric)
+					voltageStatsVolts.addNormalized(&row, "mV")
+				case "temperature":
+					fp.setGaugeNormalized(&row, cellLabels, fp.prometheus.batteryCell.temperature, fp.prometheus.batteryCell.temperatureCelsius, "C")
+					temperatureStats.add(*row.Value.ValueNumeric)
+					temperatureStatsCelsius.addNormalized(&row, "C")
+				}
+			} else if match := batteryTowerCyclesRegexp.FindStringSubmatch(channel); match != nil {
+				towerLabels := prometheus.Labels{
+					"target": fp.target.Target,
+					"module": module,
+					"tower":  normalizeIndex(match[1]),
+				}
+				row.SetGauge(towerLabels, fp.prometheus.batteryCell.towerCycles)
+			}
+		}
+
+		// min/max from battery, calculated from cells as fallback
+		minCellVoltage, maxCellVoltage := result.Address(module, "MinCellVoltage"), result.Address(module, "MaxCellVoltage")
+		minCellTemperature, maxCellTemperature := result.Address(module, "MinCellTemperature"), result.Address(module, "MaxCellTemperature")
+
+		if fp.legacyMetrics {
+			voltageStats.override(minCellVoltage, maxCellVoltage)
+			voltageStats.setGauges(batteryLabels, fp.prometheus.batteryCell.voltageMin, fp.prometheus.batteryCell.voltageMax, fp.prometheus.batteryCell.voltageSpread)
+
+			temperatureStats.override(minCellTemperature, maxCellTemperature)
+			temperatureStats.setGauges(batteryLabels, fp.prometheus.batteryCell.temperatureMin, fp.prometheus.batteryCell.temperatureMax, fp.prometheus.batteryCell.temperatureSpread)
+		}
+
+		voltageStatsVolts.overrideNormalized(minCellVoltage, maxCellVoltage, "mV")
+		voltageStatsVolts.setGauges(batteryLabels, fp.prometheus.batteryCell.voltageMinVolts, fp.prometheus.batteryCell.voltageMaxVolts, fp.prometheus.batteryCell.voltageSpreadVolts)
+
+		temperatureStatsCelsius.overrideNormalized(minCellTemperature, maxCellTemperature, "C")
+		temperatureStatsCelsius.setGauges(batteryLabels, fp.prometheus.batteryCell.temperatureMinCelsius, fp.prometheus.batteryCell.temperatureMaxCelsius, fp.prometheus.batteryCell.temperatureSpreadCelsius)
+	}
+}
+
+func (s *batteryCellStats) add(val float64) {
+	if s.min == nil || val < *s.min {
+		s.min = &val
+	}
+	if s.max == nil || val > *s.max {
+		s.max = &val
+	}
+}
+
+func (s *batteryCellStats) override(minRow, maxRow *ResultCommon) {
+	if minRow.Value.ValueNumeric != nil {
+		s.min = minRow.Value.ValueNumeric
+	}
+	if maxRow.Value.ValueNumeric != nil {
+		s.max = maxRow.Value.ValueNumeric
+	}
+}
+
+func (s *batteryCellStats) addNormalized(row *ResultCommon, defaultUnit string) {
+	if val, ok := row.NormalizedValue(defaultUnit); ok {
+		s.add(val)
+	}
+}
+
+func (s *batteryCellStats) overrideNormalized(minRow, maxRow *ResultCommon, defaultUnit string) {
+	if val, ok := minRow.NormalizedValue(defaultUnit); ok {
+		s.min = &val
+	}
+	if val, ok := maxRow.NormalizedValue(defaultUnit); ok {
+		s.max = &val
+	}
+}
+
+func (s *batteryCellStats) setGauges(labels prometheus.Labels, minGauge, maxGauge, spread *prometheus.GaugeVec) {
+	if s.min != nil {
+		minGauge.With(labels).Set(*s.min)
+	}
+	if s.max != nil {
+		maxGauge.With(labels).Set(*s.max)
+	}
+	if s.min != nil && s.max != nil {
+		spread.With(labels).Set(*s.max - *s.min)
+	}
+}
+
+// normalizeIndex removes leading zeros from indices (eg. cell 012 -> 12)
+func normalizeIndex(val string) string {
+	if i, err := strconv.Atoi(val); err == nil {
+		return strconv.Itoa(i)
+	}
+	return val
+}
@@ -0,0 +1,83 @@
+package fenecon
+
+import (
+	"testing"
+)
+
+func newFakeBattery() fakeComponent {
+	return fakeComponent{
+		FactoryId: "Battery.Fenecon.Home",
+		Channels: map[string]fakeChannel{
+			"Soh":                             {Type: "INTEGER", Unit: "%", Value: 98},
+			"Tower0NoOfCycles":                {Type: "INTEGER", Value: 120},
+			"Tower0Module1Cell000Voltage":     {Type: "INTEGER", Unit: "mV", Value: 3300},
+			"Tower0Module1Cell001Voltage":     {Type: "INTEGER", Unit: "mV", Value: 3350},
+			"Tower1Module0Cell012Voltage":     {Type: "INTEGER", Unit: "mV", Value: 3320},
+			"Tower0Module1Cell000Temperature": {Type: "INTEGER", Unit: "C", Value: 21},
+			"Tower0Module1Cell001Temperature": {Type: "INTEGER", Unit: "C", Value: 24},
+			"Tower1Module0Cell012Temperature": {Type: "INTEGER", Unit: "C", Value: 22},
+		},
+	}
+}
+
+func TestCollectBattery(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.components["battery0"] = newFakeBattery()
+
+	fp, registry := newTestProber(t, QueryGroupBattery)
+	if err := fp.Run(FeneconProberTarget{Target: fake.startRest(t).URL}); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+
+	// indices without leading zeros
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage_volts", map[string]float64{
+		"battery_module=1,cell=0,module=battery0,tower=0":  3.3,
+		"battery_module=1,cell=1,module=battery0,tower=0":  3.35,
+		"battery_module=0,cell=12,module=battery0,tower=1": 3.32,
+	})
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage", map[string]float64{
+		"battery_module=1,cell=0,module=battery0,tower=0":  3300,
+		"battery_module=1,cell=1,module=battery0,tower=0":  3350,
+		"battery_module=0,cell=12,module=battery0,tower=1": 3320,
+	})
+	assertMetrics(t, registry, "fenecon_battery_cell_temperature_celsius", map[string]float64{
+		"battery_module=1,cell=0,module=battery0,tower=0":  21,
+		"battery_module=1,cell=1,module=battery0,tower=0":  24,
+		"battery_module=0,cell=12,module=battery0,tower=1": 22,
+	})
+
+	// min/max calculated from cells (over all towers)
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage_min_volts", map[string]float64{"module=battery0": 3.3})
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage_max_volts", map[string]float64{"module=battery0": 3.35})
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage_spread_volts", map[string]float64{"module=battery0": 0.05})
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage_spread", map[string]float64{"module=battery0": 50})
+	assertMetrics(t, registry, "fenecon_battery_cell_temperature_spread_celsius", map[string]float64{"module=battery0": 3})
+
+	assertMetrics(t, registry, "fenecon_battery_tower_cycles", map[string]float64{"module=battery0,tower=0": 120})
+	assertMetrics(t, registry, "fenecon_battery_soh_percent", map[string]float64{"module=battery0": 98})
+}
+
+func TestCollectBatteryMinMaxChannels(t *testing.T) {
+	fake := newFakeOpenEMS()
+	battery := newFakeBattery()
+	battery.Channels["MinCellVoltage"] = fakeChannel{Type: "INTEGER", Unit: "mV", Value: 3200}
+	battery.Channels["MaxCellVoltage"] = fakeChannel{Type: "INTEGER", Unit: "mV", Value: 3400}
+	fake.components["battery0"] = battery
+
+	fp, registry := newTestProber(t, QueryGroupBattery)
+	if err := fp.SetMetricsCompat(MetricsCompatNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := fp.Run(FeneconProberTarget{Target: fake.startRest(t).URL}); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+
+	// min/max reported by the battery are preferred
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage_min_volts", map[string]float64{"module=battery0": 3.2})
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage_max_volts", map[string]float64{"module=battery0": 3.4})
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage_spread_volts", map[string]float64{"module=battery0": 0.2})
+
+	// no legacy metrics in raw device units
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage", map[string]float64{})
+	assertMetrics(t, registry, "fenecon_battery_cell_voltage_spread", map[string]float64{})
+}
@@ -0,0 +1,17 @@
+package fenecon
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectCharger collects charger (eg. panels)
+func (fp *FeneconProber) collectCharger(result *ResultWildcard) {
+	for _, module := range result.AddressParts(0) {
+		chargerLabels := prometheus.Labels{"target": fp.target.Target, "module": module}
+
+		result.Address(module, "State").SetGauge(chargerLabels, fp.prometheus.status)
+		result.Address(module, "ActualPower").SetGauge(chargerLabels, fp.prometheus.production.power)
+		fp.setEnergyCounter(result.Address(module, "ActualEnergy"), chargerLabels, fp.prometheus.production.powerTotal, fp.prometheus.production.energy)
+		result.Address(module, "MaxActualPower").SetGauge(chargerLabels, fp.prometheus.production.maxActualPower)
+	}
+}
@@ -0,0 +1,93 @@
+package fenecon
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// prefix of channels of component configuration properties (eg. _PropertyPeakShavingPower)
+	controllerPropertyPrefix = "_Property"
+
+	// string properties with longer values (eg. json schedules) are not exported
+	controllerPropertyMaxLength = 64
+)
+
+var (
+	// StateMachine of ctrlEssTimeOfUseTariff
+	controllerTimeOfUseStates = map[int]string{
+		0: "BALANCING",
+		1: "DELAY_DISCHARGE",
+		3: "CHARGE_GRID",
+	}
+)
+
+// collectController collects controllers (eg. emergency capacity reserve, grid optimized charge, time of use tariff,
+// peak shaving, ess limiter) with state, setpoints and configuration properties
+func (fp *FeneconProber) collectController(result *ResultWildcard) {
+	for _, module := range result.AddressParts(0) {
+		controllerLabels := prometheus.Labels{"target": fp.target.Target, "module": module}
+
+		result.Address(module, "State").SetGauge(controllerLabels, fp.prometheus.status)
+		result.Address(module, "RunFailed").SetGauge(controllerLabels, fp.prometheus.controller.runFailed)
+
+		stateMachine := result.Address(module, "StateMachine")
+		stateMachine.SetGauge(controllerLabels, fp.prometheus.controller.stateMachine)
+		if stateMachine.Value.ValueNumeric != nil && strings.HasPrefix(module, "ctrlEssTimeOfUseTariff") {
+			fp.prometheus.controller.timeOfUseState.With(prometheus.Labels{
+				"target": fp.target.Target,
+				"module": module,
+				"state":  timeOfUseStateName(int(*stateMachine.Value.ValueNumeric)),
+			}).Set(1)
+		}
+
+		// emergency capacity reserve
+		result.Address(module, "ActualReserveSoc").SetGauge(controllerLabels, fp.prometheus.controller.reserveSoc)
+
+		// grid optimized charge
+		result.Address(module, "DelayChargeState").SetGauge(controllerLabels, fp.prometheus.controller.delayChargeState)
+		result.Address(module, "DelayChargeMaximumChargeLimit").SetGauge(controllerLabels, fp.prometheus.controller.delayChargeLimit)
+		result.Address(module, "SellToGridLimitState").SetGauge(controllerLabels, fp.prometheus.controller.sellToGridLimitState)
+		result.Address(module, "SellToGridLimitMinimumChargeLimit").SetGauge(controllerLabels, fp.prometheus.controller.sellToGridLimit)
+	}
+
+	fp.collectControllerProperties(result)
+}
+
+// collectControllerProperties exports the configuration properties (eg. reserve soc, peak shaving power, mode)
+func (fp *FeneconProber) collectControllerProperties(result *ResultWildcard) {
+	for _, row := range *result {
+		module, channel := splitAddress(row.Address)
+		property, isProperty := strings.CutPrefix(channel, controllerPropertyPrefix)
+		if !isProperty || property == "Alias" {
+			continue
+		}
+
+		switch {
+		case row.Value.ValueNumeric != nil:
+			row.SetGauge(prometheus.Labels{
+				"target":   fp.target.Target,
+				"module":   module,
+				"property": property,
+				"unit":     row.Unit,
+			}, fp.prometheus.controller.property)
+		case row.Value.ValueString != nil && len(*row.Value.ValueString) <= controllerPropertyMaxLength:
+			fp.prometheus.controller.propertyInfo.With(prometheus.Labels{
+				"target":   fp.target.Target,
+				"module":   module,
+				"property": property,
+				"value":    *row.Value.ValueString,
+			}).Set(1)
+		}
+	}
+}
+
+// timeOfUseStateName returns the name of the time of use tariff state (eg. DELAY_DISCHARGE), the value for unknown states
+func timeOfUseStateName(value int) string {
+	if name, exists := controllerTimeOfUseStates[value]; exists {
+		return name
+	}
+	return strconv.Itoa(value)
+}
@@ -0,0 +1,73 @@
+package fenecon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollectController(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.components["ctrlEmergencyCapacityReserve0"] = fakeComponent{
+		Channels: map[string]fakeChannel{
+			"State":                        {Type: "INTEGER", Value: 0},
+			"RunFailed":                    {Type: "BOOLEAN", Value: 0},
+			"ActualReserveSoc":             {Type: "INTEGER", Unit: "%", Value: 20},
+			"_PropertyReserveSoc":          {Type: "INTEGER", Unit: "%", Value: 20},
+			"_PropertyIsReserveSocEnabled": {Type: "BOOLEAN", Value: 1},
+			"_PropertyAlias":               {Type: "STRING", Value: "Emergency reserve"},
+		},
+	}
+	fake.components["ctrlGridOptimizedCharge0"] = fakeComponent{
+		Channels: map[string]fakeChannel{
+			"DelayChargeState":                  {Type: "INTEGER", Value: 1},
+			"DelayChargeMaximumChargeLimit":     {Type: "INTEGER", Unit: "W", Value: 3000},
+			"SellToGridLimitState":              {Type: "INTEGER", Value: 2},
+			"SellToGridLimitMinimumChargeLimit": {Type: "INTEGER", Unit: "W", Value: 1500},
+			"_PropertyMode":                     {Type: "STRING", Value: "AUTOMATIC"},
+		},
+	}
+	fake.components["ctrlEssTimeOfUseTariff0"] = fakeComponent{
+		Channels: map[string]fakeChannel{
+			"StateMachine": {Type: "INTEGER", Value: 1},
+			// long string properties (eg. json schedules) are not exported
+			"_PropertyManualSchedule": {Type: "STRING", Value: "[" + strings.Repeat(`{"hour":0},`, 10) + "]"},
+		},
+	}
+	fake.components["ctrlEssTimeOfUseTariff1"] = fakeComponent{
+		Channels: map[string]fakeChannel{
+			"StateMachine": {Type: "INTEGER", Value: 7},
+		},
+	}
+
+	fp, registry := newTestProber(t, QueryGroupController)
+	if err := fp.Run(FeneconProberTarget{Target: fake.startRest(t).URL}); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+
+	assertMetrics(t, registry, "fenecon_status", map[string]float64{"module=ctrlEmergencyCapacityReserve0": 0})
+	assertMetrics(t, registry, "fenecon_controller_run_failed", map[string]float64{"module=ctrlEmergencyCapacityReserve0": 0})
+	assertMetrics(t, registry, "fenecon_controller_reserve_soc_percent", map[string]float64{"module=ctrlEmergencyCapacityReserve0": 20})
+	assertMetrics(t, registry, "fenecon_controller_delay_charge_state", map[string]float64{"module=ctrlGridOptimizedCharge0": 1})
+	assertMetrics(t, registry, "fenecon_controller_delay_charge_limit_watts", map[string]float64{"module=ctrlGridOptimizedCharge0": 3000})
+	assertMetrics(t, registry, "fenecon_controller_sell_to_grid_limit_state", map[string]float64{"module=ctrlGridOptimizedCharge0": 2})
+	assertMetrics(t, registry, "fenecon_controller_sell_to_grid_limit_watts", map[string]float64{"module=ctrlGridOptimizedCharge0": 1500})
+
+	// state names only for time of use tariff, unknown states by value
+	assertMetrics(t, registry, "fenecon_controller_state_machine", map[string]float64{
+		"module=ctrlEssTimeOfUseTariff0": 1,
+		"module=ctrlEssTimeOfUseTariff1": 7,
+	})
+	assertMetrics(t, registry, "fenecon_controller_time_of_use_state", map[string]float64{
+		"module=ctrlEssTimeOfUseTariff0,state=DELAY_DISCHARGE": 1,
+		"module=ctrlEssTimeOfUseTariff1,state=7":               1,
+	})
+
+	// configuration properties without alias
+	assertMetrics(t, registry, "fenecon_controller_property", map[string]float64{
+		"module=ctrlEmergencyCapacityReserve0,property=ReserveSoc,unit=%":         20,
+		"module=ctrlEmergencyCapacityReserve0,property=IsReserveSocEnabled,unit=": 1,
+	})
+	assertMetrics(t, registry, "fenecon_controller_property_info", map[string]float64{
+		"module=ctrlGridOptimizedCharge0,property=Mode,value=AUTOMATIC": 1,
+	})
+}
@@ -0,0 +1,22 @@
+package fenecon
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectEss collects ess (batteries)
+func (fp *FeneconProber) collectEss(result *ResultWildcard) {
+	for _, module := range result.AddressParts(0) {
+		batteryLabels := prometheus.Labels{"target": fp.target.Target, "module": module}
+
+		result.Address(module, "State").SetGauge(batteryLabels, fp.prometheus.status)
+		result.Address(module, "GridMode").SetGauge(batteryLabels, fp.prometheus.grid.mode)
+		result.Address(module, "Soc").SetGauge(batteryLabels, fp.prometheus.battery.charge)
+		fp.setGaugeNormalized(result.Address(module, "Capacity"), batteryLabels, fp.prometheus.battery.capacity, fp.prometheus.battery.capacityWattHours, "Wh")
+		result.Address(module, "ActivePower").SetGauge(batteryLabels, fp.prometheus.battery.power)
+		fp.setEnergyCounter(result.Address(module, "ActiveChargeEnergy"), batteryLabels, fp.prometheus.battery.powerChargeTotal, fp.prometheus.battery.chargeEnergy)
+		fp.setEnergyCounter(result.Address(module, "ActiveDischargeEnergy"), batteryLabels, fp.prometheus.battery.powerDischargeTotal, fp.prometheus.battery.dischargeEnergy)
+		result.Address(module, "AllowedChargePower").SetGauge(batteryLabels, fp.prometheus.battery.allowedChargePower)
+		result.Address(module, "AllowedDischargePower").SetGauge(batteryLabels, fp.prometheus.battery.allowedDischargePower)
+	}
+}
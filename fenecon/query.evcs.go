@@ -0,0 +1,23 @@
+package fenecon
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectEvcs collects evcs (electric vehicle charging stations, eg. KEBA, Hardy Barth or FENECON wallbox)
+func (fp *FeneconProber) collectEvcs(result *ResultWildcard) {
+	for _, module := range result.AddressParts(0) {
+		evcsLabels := prometheus.Labels{"target": fp.target.Target, "module": module}
+
+		result.Address(module, "State").SetGauge(evcsLabels, fp.prometheus.status)
+		result.Address(module, "ChargePower").SetGauge(evcsLabels, fp.prometheus.evcs.power)
+		fp.setEnergyCounter(result.Address(module, "ActiveConsumptionEnergy"), evcsLabels, fp.prometheus.evcs.powerConsumptionTotal, fp.prometheus.evcs.consumptionEnergy)
+		fp.setGaugeNormalized(result.Address(module, "EnergySession"), evcsLabels, fp.prometheus.evcs.energySession, fp.prometheus.evcs.energySessionWattHours, "Wh")
+		result.Address(module, "Status").SetGauge(evcsLabels, fp.prometheus.evcs.status)
+		result.Address(module, "Phases").SetGauge(evcsLabels, fp.prometheus.evcs.phases)
+		result.Address(module, "ChargingType").SetGauge(evcsLabels, fp.prometheus.evcs.chargingType)
+		result.Address(module, "SetChargePowerLimit").SetGauge(evcsLabels, fp.prometheus.evcs.setChargePowerLimit)
+		result.Address(module, "MinimumHardwarePower").SetGauge(evcsLabels, fp.prometheus.evcs.minHardwarePower)
+		result.Address(module, "MaximumHardwarePower").SetGauge(evcsLabels, fp.prometheus.evcs.maxHardwarePower)
+	}
+}
@@ -0,0 +1,55 @@
+package fenecon
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	ChannelCategoryState = "STATE"
+
+	FaultLevelUnknown = "unknown"
+)
+
+// collectFaults collects all active state channels (faults, warnings, infos) of all components,
+// category and level are taken from the edge config (websocket transport or component configuration)
+func (fp *FeneconProber) collectFaults(result *ResultWildcard) {
+	for _, row := range *result {
+		module, channel := splitAddress(row.Address)
+
+		if row.Category == "" {
+			if level, exists := fp.components[module].StateChannels[channel]; exists {
+				row.Category = ChannelCategoryState
+				row.Level = level
+			}
+		}
+
+		if !row.IsStateChannel() || !row.IsActive() {
+			continue
+		}
+
+		level := strings.ToLower(row.Level)
+		if level == "" {
+			level = FaultLevelUnknown
+		}
+
+		fp.prometheus.fault.With(prometheus.Labels{
+			"target":  fp.target.Target,
+			"module":  module,
+			"channel": channel,
+			"level":   level,
+			"text":    row.Text,
+		}).Set(1)
+	}
+}
+
+// IsStateChannel returns true if the channel is a state channel (fault, warning, info)
+func (r *ResultCommon) IsStateChannel() bool {
+	return strings.EqualFold(r.Category, ChannelCategoryState)
+}
+
+// IsActive returns true if the (boolean) channel value is set
+func (r *ResultCommon) IsActive() bool {
+	return r.Value.ValueNumeric != nil && *r.Value.ValueNumeric != 0
+}
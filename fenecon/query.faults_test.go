@@ -0,0 +1,44 @@
+package fenecon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectFaults(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.components["meter0"] = fakeComponent{
+		Channels: map[string]fakeChannel{
+			// read-only boolean channel with description, but no state channel
+			"Connected":           {Type: "BOOLEAN", Text: "Meter is connected", Value: 1},
+			"CommunicationFailed": {Type: "BOOLEAN", Text: "Communication failed", Category: "STATE", Level: "FAULT", Value: 1},
+		},
+	}
+
+	expected := map[string]float64{
+		"channel=CommunicationFailed,level=fault,module=meter0,text=Communication failed": 1,
+		"channel=SystemError,level=fault,module=ess0,text=System error":                   1,
+	}
+
+	for _, transportName := range []string{TransportRest, TransportWebsocket} {
+		t.Run(transportName, func(t *testing.T) {
+			target := fake.startRest(t).URL
+			fp, registry := newTestProber(t, QueryGroupFaults)
+			if err := fp.SetTransport(transportName); err != nil {
+				t.Fatal(err)
+			}
+			if transportName == TransportWebsocket {
+				target = fake.startWebsocket(t).URL
+				fp.SetWebsocketPort(testServerPort(t, target))
+			}
+
+			if err := fp.Run(FeneconProberTarget{Target: target}); err != nil {
+				t.Fatalf("probe failed: %v", err)
+			}
+
+			if got := gatherMetrics(t, registry, "fenecon_fault_active"); !reflect.DeepEqual(got, expected) {
+				t.Errorf("unexpected faults:\n got: %v\nwant: %v", got, expected)
+			}
+		})
+	}
+}
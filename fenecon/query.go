@@ -0,0 +1,93 @@
+package fenecon
+
+import (
+	"fmt"
+	"slices"
+)
+
+const (
+	QueryGroupSum        = "sum"
+	QueryGroupEss        = "ess"
+	QueryGroupCharger    = "charger"
+	QueryGroupMeter      = "meter"
+	QueryGroupEvcs       = "evcs"
+	QueryGroupBattery    = "battery"
+	QueryGroupFaults     = "faults"
+	QueryGroupController = "controller"
+	QueryGroupSchedule   = "schedule"
+)
+
+type (
+	// queryGroup is a wildcard query and the mapping of the result to metrics
+	queryGroup struct {
+		name    string
+		query   string
+		handler func(fp *FeneconProber, result *ResultWildcard)
+
+		// enabled if no query groups are configured
+		defaultEnabled bool
+	}
+)
+
+var (
+	queryGroups = []queryGroup{
+		{name: QueryGroupSum, query: "_sum/.*", handler: (*FeneconProber).collectSum, defaultEnabled: true},
+		{name: QueryGroupEss, query: "ess.*/.*", handler: (*FeneconProber).collectEss, defaultEnabled: true},
+		{name: QueryGroupCharger, query: "charger.*/.*", handler: (*FeneconProber).collectCharger, defaultEnabled: true},
+		{name: QueryGroupMeter, query: "meter.*/.*", handler: (*FeneconProber).collectMeter, defaultEnabled: true},
+		{name: QueryGroupEvcs, query: "evcs.*/.*", handler: (*FeneconProber).collectEvcs},
+		{name: QueryGroupBattery, query: "battery.*/.*", handler: (*FeneconProber).collectBattery},
+		{name: QueryGroupFaults, query: ".*/.*", handler: (*FeneconProber).collectFaults},
+		{name: QueryGroupController, query: "ctrl.*/.*", handler: (*FeneconProber).collectController},
+		{name: QueryGroupSchedule, query: "ctrlEssTimeOfUseTariff.*/.*", handler: (*FeneconProber).collectSchedule},
+	}
+)
+
+// QueryGroups returns the names of all available query groups
+func QueryGroups() []string {
+	ret := []string{}
+	for _, group := range queryGroups {
+		ret = append(ret, group.name)
+	}
+	return ret
+}
+
+// DefaultQueryGroups returns the names of all query groups which are enabled by default
+func DefaultQueryGroups() []string {
+	ret := []string{}
+	for _, group := range queryGroups {
+		if group.defaultEnabled {
+			ret = append(ret, group.name)
+		}
+	}
+	return ret
+}
+
+// ValidateQueryGroups checks if all query group names are valid
+func ValidateQueryGroups(val []string) error {
+	available := QueryGroups()
+	for _, name := range val {
+		if !slices.Contains(available, name) {
+			return fmt.Errorf(`unsupported query group "%v" (available: %v)`, name, available)
+		}
+	}
+	return nil
+}
+
+// SetQueryGroups sets the enabled query groups (eg. sum, ess, charger, meter, evcs), empty list enables default query groups
+func (fp *FeneconProber) SetQueryGroups(val []string) error {
+	if err := ValidateQueryGroups(val); err != nil {
+		return err
+	}
+
+	if len(val) == 0 {
+		val = DefaultQueryGroups()
+	}
+
+	fp.queryGroups = val
+	return nil
+}
+
+func (fp *FeneconProber) queryGroupEnabled(name string) bool {
+	return slices.Contains(fp.queryGroups, name)
+}
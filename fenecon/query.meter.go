@@ -0,0 +1,43 @@
+package fenecon
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectMeter collects meter (eg. grid meter)
+func (fp *FeneconProber) collectMeter(result *ResultWildcard) {
+	for _, module := range result.AddressParts(0) {
+		meterLabels := prometheus.Labels{"target": fp.target.Target, "module": module}
+		meterPhase1Labels := prometheus.Labels{"target": fp.target.Target, "module": module, "phase": "1"}
+		meterPhase2Labels := prometheus.Labels{"target": fp.target.Target, "module": module, "phase": "2"}
+		meterPhase3Labels := prometheus.Labels{"target": fp.target.Target, "module": module, "phase": "3"}
+
+		result.Address(module, "State").SetGauge(meterLabels, fp.prometheus.status)
+		fp.setGaugeNormalized(result.Address(module, "Frequency"), meterLabels, fp.prometheus.meter.frequency, fp.prometheus.meter.frequencyHertz, "mHz")
+		fp.setGaugeNormalized(result.Address(module, "Voltage"), meterLabels, fp.prometheus.meter.voltage, fp.prometheus.meter.voltageVolts, "mV")
+		fp.setGaugeNormalized(result.Address(module, "VoltageL1"), meterPhase1Labels, fp.prometheus.meter.voltagePhase, fp.prometheus.meter.voltagePhaseVolts, "mV")
+		fp.setGaugeNormalized(result.Address(module, "VoltageL2"), meterPhase2Labels, fp.prometheus.meter.voltagePhase, fp.prometheus.meter.voltagePhaseVolts, "mV")
+		fp.setGaugeNormalized(result.Address(module, "VoltageL3"), meterPhase3Labels, fp.prometheus.meter.voltagePhase, fp.prometheus.meter.voltagePhaseVolts, "mV")
+		result.Address(module, "ActivePower").SetGauge(meterLabels, fp.prometheus.meter.power)
+		result.Address(module, "ActivePowerL1").SetGauge(meterPhase1Labels, fp.prometheus.meter.powerPhase)
+		result.Address(module, "ActivePowerL2").SetGauge(meterPhase2Labels, fp.prometheus.meter.powerPhase)
+		result.Address(module, "ActivePowerL3").SetGauge(meterPhase3Labels, fp.prometheus.meter.powerPhase)
+		result.Address(module, "ReactivePower").SetGauge(meterLabels, fp.prometheus.meter.reactivePower)
+		result.Address(module, "ReactivePowerL1").SetGauge(meterPhase1Labels, fp.prometheus.meter.reactivePowerPhase)
+		result.Address(module, "ReactivePowerL2").SetGauge(meterPhase2Labels, fp.prometheus.meter.reactivePowerPhase)
+		result.Address(module, "ReactivePowerL3").SetGauge(meterPhase3Labels, fp.prometheus.meter.reactivePowerPhase)
+		fp.setGaugeNormalized(result.Address(module, "Current"), meterLabels, fp.prometheus.meter.current, fp.prometheus.meter.currentAmperes, "mA")
+		fp.setGaugeNormalized(result.Address(module, "CurrentL1"), meterPhase1Labels, fp.prometheus.meter.currentPhase, fp.prometheus.meter.currentPhaseAmperes, "mA")
+		fp.setGaugeNormalized(result.Address(module, "CurrentL2"), meterPhase2Labels, fp.prometheus.meter.currentPhase, fp.prometheus.meter.currentPhaseAmperes, "mA")
+		fp.setGaugeNormalized(result.Address(module, "CurrentL3"), meterPhase3Labels, fp.prometheus.meter.currentPhase, fp.prometheus.meter.currentPhaseAmperes, "mA")
+		result.Address(module, "MinActivePower").SetGauge(meterLabels, fp.prometheus.meter.minActivePower)
+		result.Address(module, "MaxActivePower").SetGauge(meterLabels, fp.prometheus.meter.maxActivePower)
+
+		if fp.legacyMetrics {
+			result.Address(module, "ActiveProductionEnergy").SetGauge(meterLabels, fp.prometheus.meter.powerProductionTotal)
+			result.Address(module, "ActiveConsumptionEnergy").SetGauge(meterLabels, fp.prometheus.meter.powerConsumptionTotal)
+		}
+		fp.setEnergyCounter(result.Address(module, "ActiveProductionEnergy"), meterLabels, nil, fp.prometheus.meter.productionEnergy)
+		fp.setEnergyCounter(result.Address(module, "ActiveConsumptionEnergy"), meterLabels, nil, fp.prometheus.meter.consumptionEnergy)
+	}
+}
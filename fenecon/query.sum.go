@@ -0,0 +1,67 @@
+package fenecon
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectSum collects the summary of all components (_sum)
+func (fp *FeneconProber) collectSum(result *ResultWildcard) {
+	commonLabels := prometheus.Labels{"target": fp.target.Target, "module": "_sum"}
+	phase1Labels := prometheus.Labels{"target": fp.target.Target, "module": "_sum", "phase": "1"}
+	phase2Labels := prometheus.Labels{"target": fp.target.Target, "module": "_sum", "phase": "2"}
+	phase3Labels := prometheus.Labels{"target": fp.target.Target, "module": "_sum", "phase": "3"}
+
+	// general
+	result.Address("_sum/State").SetGauge(commonLabels, fp.prometheus.status)
+
+	// battery
+	result.Address("_sum/EssSoc").SetGauge(commonLabels, fp.prometheus.battery.charge)
+	fp.setGaugeNormalized(result.Address("_sum/EssCapacity"), commonLabels, fp.prometheus.battery.capacity, fp.prometheus.battery.capacityWattHours, "Wh")
+	result.Address("_sum/EssActivePower").SetGauge(commonLabels, fp.prometheus.battery.power)
+	fp.aggregateEnergy(EnergyKindCharge, fp.setEnergyCounter(result.Address("_sum/EssActiveChargeEnergy"), commonLabels, fp.prometheus.battery.powerChargeTotal, fp.prometheus.battery.chargeEnergy))
+	fp.aggregateEnergy(EnergyKindDischarge, fp.setEnergyCounter(result.Address("_sum/EssActiveDischargeEnergy"), commonLabels, fp.prometheus.battery.powerDischargeTotal, fp.prometheus.battery.dischargeEnergy))
+	fp.setEnergyCounter(result.Address("_sum/EssDcChargeEnergy"), commonLabels, fp.prometheus.battery.powerDcChargeTotal, fp.prometheus.battery.dcChargeEnergy)
+	fp.setEnergyCounter(result.Address("_sum/EssDcDischargeEnergy"), commonLabels, fp.prometheus.battery.powerDcDischargeTotal, fp.prometheus.battery.dcDischargeEnergy)
+	result.Address("_sum/EssActivePowerL1").SetGauge(phase1Labels, fp.prometheus.battery.powerPhase)
+	result.Address("_sum/EssActivePowerL2").SetGauge(phase2Labels, fp.prometheus.battery.powerPhase)
+	result.Address("_sum/EssActivePowerL3").SetGauge(phase3Labels, fp.prometheus.battery.powerPhase)
+
+	// grid
+	result.Address("_sum/GridMode").SetGauge(commonLabels, fp.prometheus.grid.mode)
+	result.Address("_sum/GridActivePower").SetGauge(commonLabels, fp.prometheus.grid.power)
+	gridBuyEnergy := fp.setEnergyCounter(result.Address("_sum/GridBuyActiveEnergy"), commonLabels, fp.prometheus.grid.powerBuyTotal, fp.prometheus.grid.buyEnergy)
+	gridSellEnergy := fp.setEnergyCounter(result.Address("_sum/GridSellActiveEnergy"), commonLabels, fp.prometheus.grid.powerSellTotal, fp.prometheus.grid.sellEnergy)
+	fp.aggregateEnergy(EnergyKindGridBuy, gridBuyEnergy)
+	fp.aggregateEnergy(EnergyKindGridSell, gridSellEnergy)
+	result.Address("_sum/GridActivePowerL1").SetGauge(phase1Labels, fp.prometheus.grid.powerPhase)
+	result.Address("_sum/GridActivePowerL2").SetGauge(phase2Labels, fp.prometheus.grid.powerPhase)
+	result.Address("_sum/GridActivePowerL3").SetGauge(phase3Labels, fp.prometheus.grid.powerPhase)
+
+	// production
+	result.Address("_sum/ProductionActivePower").SetGauge(commonLabels, fp.prometheus.production.power)
+	result.Address("_sum/ProductionAcActivePower").SetGauge(commonLabels, fp.prometheus.production.powerAc)
+	result.Address("_sum/ProductionDcActualPower").SetGauge(commonLabels, fp.prometheus.production.powerDc)
+	fp.aggregateEnergy(EnergyKindProduction, fp.setEnergyCounter(result.Address("_sum/ProductionActiveEnergy"), commonLabels, fp.prometheus.production.powerTotal, fp.prometheus.production.energy))
+	fp.setEnergyCounter(result.Address("_sum/ProductionAcActiveEnergy"), commonLabels, fp.prometheus.production.powerAcTotal, fp.prometheus.production.energyAc)
+	fp.setEnergyCounter(result.Address("_sum/ProductionDcActiveEnergy"), commonLabels, fp.prometheus.production.powerDcTotal, fp.prometheus.production.energyDc)
+	result.Address("_sum/ProductionAcActivePowerL1").SetGauge(phase1Labels, fp.prometheus.production.powerPhase)
+	result.Address("_sum/ProductionAcActivePowerL2").SetGauge(phase2Labels, fp.prometheus.production.powerPhase)
+	result.Address("_sum/ProductionAcActivePowerL3").SetGauge(phase3Labels, fp.prometheus.production.powerPhase)
+
+	// consumption
+	result.Address("_sum/ConsumptionActivePower").SetGauge(commonLabels, fp.prometheus.consumption.power)
+	consumptionEnergy := fp.setEnergyCounter(result.Address("_sum/ConsumptionActiveEnergy"), commonLabels, fp.prometheus.consumption.powerTotal, fp.prometheus.consumption.energy)
+	fp.aggregateEnergy(EnergyKindConsumption, consumptionEnergy)
+	result.Address("_sum/ConsumptionActivePowerL1").SetGauge(phase1Labels, fp.prometheus.consumption.powerPhase)
+	result.Address("_sum/ConsumptionActivePowerL2").SetGauge(phase2Labels, fp.prometheus.consumption.powerPhase)
+	result.Address("_sum/ConsumptionActivePowerL3").SetGauge(phase3Labels, fp.prometheus.consumption.powerPhase)
+
+	// evcs
+	result.Address("_sum/EvcsActivePower").SetGauge(commonLabels, fp.prometheus.evcs.power)
+
+	// grid cost and feed-in revenue
+	fp.accountCost(gridBuyEnergy, gridSellEnergy, consumptionEnergy)
+
+	// autarky, self-consumption and energy flow
+	fp.collectDerived(result)
+}
@@ -0,0 +1,111 @@
+package fenecon
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// RawQuery queries all channels of all components
+	RawQuery = ".*/.*"
+)
+
+type (
+	// RawFilter filters the channels which are exported in raw mode, empty regexes match everything
+	RawFilter struct {
+		componentInclude *regexp.Regexp
+		componentExclude *regexp.Regexp
+		channelInclude   *regexp.Regexp
+		channelExclude   *regexp.Regexp
+	}
+)
+
+// NewRawFilter builds the raw mode filter from include/exclude regexes (anchored), empty regexes are ignored
+func NewRawFilter(componentInclude, componentExclude, channelInclude, channelExclude string) (*RawFilter, error) {
+	var err error
+	filter := RawFilter{}
+
+	if filter.componentInclude, err = compileRawFilterRegexp("component include", componentInclude); err != nil {
+		return nil, err
+	}
+	if filter.componentExclude, err = compileRawFilterRegexp("component exclude", componentExclude); err != nil {
+		return nil, err
+	}
+	if filter.channelInclude, err = compileRawFilterRegexp("channel include", channelInclude); err != nil {
+		return nil, err
+	}
+	if filter.channelExclude, err = compileRawFilterRegexp("channel exclude", channelExclude); err != nil {
+		return nil, err
+	}
+
+	return &filter, nil
+}
+
+func compileRawFilterRegexp(name, val string) (*regexp.Regexp, error) {
+	if val == "" {
+		return nil, nil
+	}
+
+	ret, err := regexp.Compile(`^(?:` + val + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf(`%v filter "%v" is not a valid regex: %w`, name, val, err)
+	}
+	return ret, nil
+}
+
+// Match checks if the channel passes the filter
+func (f *RawFilter) Match(component, channel string) bool {
+	if f.componentInclude != nil && !f.componentInclude.MatchString(component) {
+		return false
+	}
+	if f.componentExclude != nil && f.componentExclude.MatchString(component) {
+		return false
+	}
+	if f.channelInclude != nil && !f.channelInclude.MatchString(channel) {
+		return false
+	}
+	if f.channelExclude != nil && f.channelExclude.MatchString(channel) {
+		return false
+	}
+	return true
+}
+
+// SetRawMode enables the raw mode, all channels passing the filter are exported instead of the query groups
+func (fp *FeneconProber) SetRawMode(filter *RawFilter) {
+	fp.rawFilter = filter
+}
+
+// collectRaw exports every numeric channel as fenecon_channel_value and string channels (and descriptions) as fenecon_channel_info
+func (fp *FeneconProber) collectRaw(result *ResultWildcard) {
+	for _, row := range *result {
+		component, channel := splitAddress(row.Address)
+		if !fp.rawFilter.Match(component, channel) {
+			continue
+		}
+
+		if row.Value.ValueNumeric != nil {
+			row.SetGauge(prometheus.Labels{
+				"target":    fp.target.Target,
+				"component": component,
+				"channel":   channel,
+				"unit":      row.Unit,
+			}, fp.prometheus.channel.value)
+		}
+
+		infoLabels := prometheus.Labels{
+			"target":    fp.target.Target,
+			"component": component,
+			"channel":   channel,
+			"text":      row.Text,
+			"value":     "",
+		}
+		if row.Value.ValueString != nil {
+			infoLabels["value"] = *row.Value.ValueString
+			fp.prometheus.channel.info.With(infoLabels).Set(1)
+		} else if row.Text != "" {
+			fp.prometheus.channel.info.With(infoLabels).Set(1)
+		}
+	}
+}
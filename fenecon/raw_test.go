@@ -0,0 +1,89 @@
+package fenecon
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewRawFilter(t *testing.T) {
+	if _, err := NewRawFilter("", "", "", ""); err != nil {
+		t.Errorf("unexpected error for empty filter: %v", err)
+	}
+	if _, err := NewRawFilter("ess(", "", "", ""); err == nil {
+		t.Error("expected error for invalid component regex")
+	}
+	if _, err := NewRawFilter("", "", "", "Soc["); err == nil {
+		t.Error("expected error for invalid channel regex")
+	}
+}
+
+func TestRawFilterMatch(t *testing.T) {
+	tests := []struct {
+		name                                                               string
+		componentInclude, componentExclude, channelInclude, channelExclude string
+		component, channel                                                 string
+		match                                                              bool
+	}{
+		{name: "empty filter", component: "ess0", channel: "Soc", match: true},
+		{name: "component included", componentInclude: "ess.*", component: "ess0", channel: "Soc", match: true},
+		{name: "component not included", componentInclude: "ess.*", component: "meter0", channel: "Soc", match: false},
+		{name: "component include is anchored", componentInclude: "ess", component: "ess0", channel: "Soc", match: false},
+		{name: "component excluded", componentExclude: "_meta|_sum", component: "_sum", channel: "State", match: false},
+		{name: "channel included", channelInclude: ".*Power", component: "_sum", channel: "GridActivePower", match: true},
+		{name: "channel not included", channelInclude: ".*Power", component: "_sum", channel: "GridActivePowerL1", match: false},
+		{name: "channel excluded", channelExclude: "Debug.*", component: "ess0", channel: "DebugSetActivePower", match: false},
+		{name: "exclude wins over include", componentInclude: "ess.*", componentExclude: "ess1", component: "ess1", channel: "Soc", match: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := NewRawFilter(test.componentInclude, test.componentExclude, test.channelInclude, test.channelExclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match := filter.Match(test.component, test.channel); match != test.match {
+				t.Errorf("expected match %v for %v/%v, got %v", test.match, test.component, test.channel, match)
+			}
+		})
+	}
+}
+
+func TestCollectRaw(t *testing.T) {
+	fake := newFakeOpenEMS()
+
+	filter, err := NewRawFilter("", "", "", "LowSoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fp, registry := newTestProber(t, QueryGroupSum, QueryGroupEss)
+	fp.SetRawMode(filter)
+	if err := fp.Run(FeneconProberTarget{Target: fake.startRest(t).URL}); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+
+	assertMetrics(t, registry, "fenecon_channel_value", map[string]float64{
+		"channel=State,component=_sum,unit=":                     0,
+		"channel=EssSoc,component=_sum,unit=%":                   55,
+		"channel=GridActivePower,component=_sum,unit=W":          1200,
+		"channel=EssActivePower,component=_sum,unit=W":           -300,
+		"channel=ProductionActivePower,component=_sum,unit=W":    2000,
+		"channel=ConsumptionActivePower,component=_sum,unit=W":   2900,
+		"channel=GridBuyActiveEnergy,component=_sum,unit=Wh":     123456,
+		"channel=ConsumptionActiveEnergy,component=_sum,unit=Wh": 199999,
+		"channel=Soc,component=ess0,unit=%":                      55,
+		"channel=SystemError,component=ess0,unit=":               1,
+	})
+
+	// string channels and channels with descriptions
+	assertMetrics(t, registry, "fenecon_channel_info", map[string]float64{
+		"channel=Version,component=_meta,text=,value=2024.10.1":       1,
+		"channel=SystemError,component=ess0,text=System error,value=": 1,
+	})
+
+	// query groups are not used in raw mode
+	assertMetrics(t, registry, "fenecon_grid_power", map[string]float64{})
+	if queries := fake.receivedQueries(); slices.Contains(queries, "_sum/.*") || !slices.Contains(queries, RawQuery) {
+		t.Errorf("expected raw query instead of query groups, got %v", queries)
+	}
+}
@@ -17,6 +17,10 @@ type (
 		Text       string      `json:"text"`
 		Unit       string      `json:"unit"`
 		Value      ResultValue `json:"value"`
+
+		// only available for websocket transport (from edge config)
+		Category string `json:"category"`
+		Level    string `json:"level"`
 	}
 
 	ResultValue struct {
@@ -31,6 +35,11 @@ func (v *ResultValue) UnmarshalJSON(data []byte) error {
 		valString string
 	)
 
+	// channel value not available (eg. device not connected), null would be unmarshaled as 0
+	if string(data) == "null" {
+		return nil
+	}
+
 	// as numeric
 	if err := json.Unmarshal(data, &valFloat); err == nil {
 		v.ValueNumeric = &valFloat
@@ -66,6 +75,12 @@ func (r *ResultWildcard) AddressParts(part int) []string {
 	return ret
 }
 
+// splitAddress splits the channel address (eg. "ess0/Soc") into component and channel id
+func splitAddress(address string) (component, channel string) {
+	component, channel, _ = strings.Cut(address, "/")
+	return
+}
+
 func (r *ResultWildcard) Address(val ...string) *ResultCommon {
 	address := strings.Join(val, "/")
 	for _, row := range *r {
@@ -0,0 +1,132 @@
+package fenecon
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultScheduleComponent is the time of use tariff controller which plans the quarter-hourly schedule
+	DefaultScheduleComponent = "ctrlEssTimeOfUseTariff0"
+)
+
+type (
+	// Schedule is the quarter-hourly schedule of the time of use tariff controller (componentJsonApi getSchedule)
+	Schedule struct {
+		Schedule []ScheduleSlot `json:"schedule"`
+	}
+
+	// ScheduleSlot is a planned 15 minute slot, values are nil if not available
+	ScheduleSlot struct {
+		Timestamp time.Time `json:"timestamp"`
+
+		// price in currency per MWh (eg. €/MWh)
+		Price *float64 `json:"price"`
+
+		// planned state (StateMachine of the controller, eg. 1 = DELAY_DISCHARGE)
+		State     *int   `json:"state"`
+		StateName string `json:"stateName,omitempty"`
+
+		// predicted power in Watt
+		Grid        *float64 `json:"grid"`
+		Production  *float64 `json:"production"`
+		Consumption *float64 `json:"consumption"`
+		Ess         *float64 `json:"ess"`
+
+		// predicted state of charge in percent
+		Soc *float64 `json:"soc"`
+	}
+
+	componentJsonApiRequest struct {
+		ComponentId string         `json:"componentId"`
+		Payload     JsonRpcRequest `json:"payload"`
+	}
+)
+
+// Schedule connects to the target and fetches the schedule of the time of use tariff controller
+func (fp *FeneconProber) Schedule(target FeneconProberTarget, componentId string) (*Schedule, error) {
+	fp.target = target
+
+	transport, err := fp.newTransport(target)
+	if err != nil {
+		return nil, err
+	}
+	fp.transport = transport
+	defer func() {
+		if err := fp.transport.Close(); err != nil {
+			fp.logger.Warn(`failed to close transport`, slog.Any("error", err))
+		}
+	}()
+
+	return fp.fetchSchedule(componentId)
+}
+
+func (fp *FeneconProber) fetchSchedule(componentId string) (*Schedule, error) {
+	request := componentJsonApiRequest{
+		ComponentId: componentId,
+		Payload:     newJsonRpcRequest("getSchedule", nil),
+	}
+
+	schedule := Schedule{}
+	if err := fp.transport.EdgeRequest(fp.ctx, "componentJsonApi", request, &schedule); err != nil {
+		return nil, fmt.Errorf(`getSchedule of "%v" failed: %w`, componentId, err)
+	}
+
+	for i, slot := range schedule.Schedule {
+		if slot.State != nil {
+			schedule.Schedule[i].StateName = timeOfUseStateName(*slot.State)
+		}
+	}
+
+	return &schedule, nil
+}
+
+// collectSchedule collects the current price and the planned schedule (per slot with slot_start label)
+// of the time of use tariff controllers
+func (fp *FeneconProber) collectSchedule(result *ResultWildcard) {
+	for _, module := range result.AddressParts(0) {
+		if !strings.HasPrefix(module, "ctrlEssTimeOfUseTariff") {
+			continue
+		}
+
+		price := result.Address(module, "QuarterlyPrices")
+		price.SetGauge(prometheus.Labels{"target": fp.target.Target, "module": module, "unit": price.Unit}, fp.prometheus.schedule.price)
+
+		startTime := time.Now()
+		schedule, err := fp.fetchSchedule(module)
+		fp.recordQueryResult(module+"/getSchedule", startTime, 0, err)
+		if err != nil {
+			fp.logger.Error(`failed to fetch schedule`, slog.String("module", module), slog.Any("error", err))
+			fp.errorsLock.Lock()
+			fp.errors = append(fp.errors, err)
+			fp.errorsLock.Unlock()
+			continue
+		}
+
+		for _, slot := range schedule.Schedule {
+			slotLabels := prometheus.Labels{"target": fp.target.Target, "module": module, "slot_start": slot.Timestamp.UTC().Format(time.RFC3339)}
+
+			setGaugeIfSet(slot.Price, slotLabels, fp.prometheus.schedule.slotPrice)
+			setGaugeIfSet(slot.Grid, slotLabels, fp.prometheus.schedule.slotGridPower)
+			setGaugeIfSet(slot.Production, slotLabels, fp.prometheus.schedule.slotProductionPower)
+			setGaugeIfSet(slot.Consumption, slotLabels, fp.prometheus.schedule.slotConsumptionPower)
+			setGaugeIfSet(slot.Ess, slotLabels, fp.prometheus.schedule.slotEssPower)
+			setGaugeIfSet(slot.Soc, slotLabels, fp.prometheus.schedule.slotSoc)
+
+			if slot.State != nil {
+				stateLabels := prometheus.Labels{"target": fp.target.Target, "module": module, "slot_start": slotLabels["slot_start"], "state": slot.StateName}
+				fp.prometheus.schedule.slotState.With(stateLabels).Set(float64(*slot.State))
+			}
+		}
+	}
+}
+
+func setGaugeIfSet(value *float64, labels prometheus.Labels, gaugeVec *prometheus.GaugeVec) {
+	if value != nil {
+		gaugeVec.With(labels).Set(*value)
+	}
+}
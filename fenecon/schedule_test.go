@@ -0,0 +1,109 @@
+package fenecon
+
+import (
+	"testing"
+)
+
+func newFakeSchedule() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"timestamp": "2024-10-16T12:00:00Z", "price": 120.5, "state": 0, "grid": 0, "production": 2000, "consumption": 1500, "ess": -500, "soc": 50},
+		{"timestamp": "2024-10-16T12:15:00Z", "price": 250.1, "state": 1, "grid": 800, "production": 1000, "consumption": 1800, "ess": 0, "soc": 52},
+		// values are not available for all slots
+		{"timestamp": "2024-10-16T12:30:00+02:00", "price": 90, "state": 3},
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.schedules = map[string][]map[string]interface{}{DefaultScheduleComponent: newFakeSchedule()}
+
+	fp, _ := newTestProber(t)
+	if err := fp.SetTransport(TransportWebsocket); err != nil {
+		t.Fatal(err)
+	}
+	fp.SetWebsocketPort(testServerPort(t, fake.startWebsocket(t).URL))
+
+	schedule, err := fp.Schedule(FeneconProberTarget{Target: "http://127.0.0.1"}, DefaultScheduleComponent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(schedule.Schedule) != 3 {
+		t.Fatalf("expected 3 slots, got %+v", schedule.Schedule)
+	}
+	first, last := schedule.Schedule[0], schedule.Schedule[2]
+	if first.Price == nil || *first.Price != 120.5 || first.Soc == nil || *first.Soc != 50 || first.StateName != "BALANCING" {
+		t.Errorf("unexpected first slot %+v", first)
+	}
+	if last.Grid != nil || last.Soc != nil || last.StateName != "CHARGE_GRID" {
+		t.Errorf("expected last slot without predictions, got %+v", last)
+	}
+
+	// unknown component
+	fp, _ = newTestProber(t)
+	if _, err := fp.Schedule(FeneconProberTarget{Target: fake.startRest(t).URL}, "ctrlEssTimeOfUseTariff1"); err == nil {
+		t.Error("expected error for unknown component")
+	}
+}
+
+func TestCollectSchedule(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.components[DefaultScheduleComponent] = fakeComponent{
+		Channels: map[string]fakeChannel{
+			"QuarterlyPrices": {Type: "DOUBLE", Unit: "€/MWh", Value: 120.5},
+			"StateMachine":    {Type: "INTEGER", Value: 0},
+		},
+	}
+	fake.schedules = map[string][]map[string]interface{}{DefaultScheduleComponent: newFakeSchedule()}
+
+	fp, registry := newTestProber(t, QueryGroupSchedule)
+	if err := fp.Run(FeneconProberTarget{Target: fake.startRest(t).URL}); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+
+	assertMetrics(t, registry, "fenecon_time_of_use_price", map[string]float64{"module=ctrlEssTimeOfUseTariff0,unit=€/MWh": 120.5})
+
+	// slot start in UTC
+	assertMetrics(t, registry, "fenecon_time_of_use_schedule_price", map[string]float64{
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:00:00Z": 120.5,
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:15:00Z": 250.1,
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T10:30:00Z": 90,
+	})
+	assertMetrics(t, registry, "fenecon_time_of_use_schedule_state", map[string]float64{
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:00:00Z,state=BALANCING":       0,
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:15:00Z,state=DELAY_DISCHARGE": 1,
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T10:30:00Z,state=CHARGE_GRID":     3,
+	})
+
+	// predictions only for slots with values
+	assertMetrics(t, registry, "fenecon_time_of_use_schedule_grid_power_watts", map[string]float64{
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:00:00Z": 0,
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:15:00Z": 800,
+	})
+	assertMetrics(t, registry, "fenecon_time_of_use_schedule_ess_power_watts", map[string]float64{
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:00:00Z": -500,
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:15:00Z": 0,
+	})
+	assertMetrics(t, registry, "fenecon_time_of_use_schedule_soc_percent", map[string]float64{
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:00:00Z": 50,
+		"module=ctrlEssTimeOfUseTariff0,slot_start=2024-10-16T12:15:00Z": 52,
+	})
+}
+
+func TestCollectScheduleFailed(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.components["ctrlEssTimeOfUseTariff1"] = fakeComponent{
+		Channels: map[string]fakeChannel{
+			"QuarterlyPrices": {Type: "DOUBLE", Unit: "€/MWh", Value: 80},
+		},
+	}
+
+	fp, registry := newTestProber(t, QueryGroupSchedule)
+
+	// failed getSchedule fails the probe, the current price is still exported
+	if err := fp.Run(FeneconProberTarget{Target: fake.startRest(t).URL}); err == nil {
+		t.Error("expected probe error for failed schedule")
+	}
+	assertMetrics(t, registry, "fenecon_time_of_use_price", map[string]float64{"module=ctrlEssTimeOfUseTariff1,unit=€/MWh": 80})
+	assertMetrics(t, registry, "fenecon_time_of_use_schedule_price", map[string]float64{})
+}
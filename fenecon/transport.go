@@ -0,0 +1,98 @@
+package fenecon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	TransportRest      = "rest"
+	TransportWebsocket = "websocket"
+
+	DefaultWebsocketPort = 8085
+)
+
+type (
+	// transport fetches channel values from an OpenEMS edge
+	transport interface {
+		// Query fetches all channels matching the wildcard pattern (eg. "ess.*/.*"),
+		// statusCode is the http status code of the response (0 if not available)
+		Query(ctx context.Context, pattern string) (result *ResultWildcard, statusCode int, err error)
+
+		// EdgeRequest sends a JSON-RPC request to the edge (eg. getEdgeConfig, queryHistoricTimeseriesData) and decodes the result
+		EdgeRequest(ctx context.Context, method string, params interface{}, result interface{}) error
+
+		// Close releases all resources of the transport
+		Close() error
+	}
+)
+
+func (fp *FeneconProber) SetTransport(val string) error {
+	switch val {
+	case "", TransportRest:
+		fp.transportName = TransportRest
+	case TransportWebsocket:
+		fp.transportName = TransportWebsocket
+	default:
+		return fmt.Errorf(`unsupported transport "%v"`, val)
+	}
+
+	return nil
+}
+
+func (fp *FeneconProber) SetWebsocketPort(val int) {
+	fp.websocket.port = val
+}
+
+func (fp *FeneconProber) newTransport(target FeneconProberTarget) (transport, error) {
+	switch fp.transportName {
+	case TransportWebsocket:
+		websocketUrl, err := buildWebsocketUrl(target.Target, fp.websocket.port)
+		if err != nil {
+			return nil, err
+		}
+		return newWebsocketTransport(fp.ctx, fp.logger, websocketUrl, fp.userAgent, fp.websocket.username, fp.websocket.password, fp.websocket.timeout)
+	default:
+		return newRestTransport(fp.client, target.Target), nil
+	}
+}
+
+// buildWebsocketUrl converts the target url (eg. http://fenecon) to the OpenEMS JSON-RPC websocket url (eg. ws://fenecon:8085/)
+func buildWebsocketUrl(target string, port int) (string, error) {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf(`unable to parse target "%v": %w`, target, err)
+	}
+
+	switch u.Scheme {
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+
+	if port <= 0 {
+		port = DefaultWebsocketPort
+	}
+
+	u.Host = fmt.Sprintf("%s:%d", u.Hostname(), port)
+	u.Path = "/"
+	u.RawQuery = ""
+
+	return u.String(), nil
+}
+
+// splitWildcardPattern splits a wildcard pattern (eg. "ess.*/.*") into component and channel pattern
+func splitWildcardPattern(pattern string) (component, channel string) {
+	component, channel, found := strings.Cut(pattern, "/")
+	if !found {
+		channel = ".*"
+	}
+	return
+}
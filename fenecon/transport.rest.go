@@ -0,0 +1,67 @@
+package fenecon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	resty "resty.dev/v3"
+)
+
+type (
+	// restTransport queries the OpenEMS REST-Api (/rest/channel/...)
+	restTransport struct {
+		client *resty.Client
+		target string
+	}
+)
+
+func newRestTransport(client *resty.Client, target string) *restTransport {
+	t := restTransport{}
+	t.target = strings.TrimRight(target, "/")
+	t.client = client.SetBaseURL(
+		fmt.Sprintf(`%s/rest/channel/`, strings.TrimRight(target, "/")),
+	)
+	return &t
+}
+
+func (t *restTransport) Query(ctx context.Context, pattern string) (*ResultWildcard, int, error) {
+	result := ResultWildcard{}
+	statusCode := 0
+	response, err := t.client.R().SetContext(ctx).SetResult(&result).Get(pattern)
+	if response != nil {
+		statusCode = response.StatusCode()
+	}
+	return &result, statusCode, err
+}
+
+// EdgeRequest sends the JSON-RPC request to the REST-Api (POST /jsonrpc)
+func (t *restTransport) EdgeRequest(ctx context.Context, method string, params interface{}, result interface{}) error {
+	response := JsonRpcMessage{}
+	resp, err := t.client.R().SetContext(ctx).SetBody(newJsonRpcRequest(method, params)).SetResult(&response).Post(t.target + "/jsonrpc")
+	if err != nil {
+		return err
+	}
+
+	// not found is ignored for channel queries, but JSON-RPC is not available then
+	if resp.StatusCode() == 404 {
+		return fmt.Errorf(`%w: JSON-RPC endpoint not found (404)`, ErrHttpStatus)
+	}
+
+	if response.Error != nil {
+		return response.Error
+	}
+
+	if result != nil && len(response.Result) > 0 {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf(`unable to decode "%v" response: %w`, method, err)
+		}
+	}
+
+	return nil
+}
+
+func (t *restTransport) Close() error {
+	return nil
+}
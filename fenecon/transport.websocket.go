@@ -0,0 +1,392 @@
+package fenecon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/webdevops/go-common/log/slogger"
+)
+
+type (
+	// websocketTransport queries the OpenEMS JSON-RPC websocket (as used by the FEMS UI)
+	websocketTransport struct {
+		conn    *websocket.Conn
+		logger  *slogger.Logger
+		timeout time.Duration
+
+		writeLock sync.Mutex
+		queryLock sync.Mutex
+
+		pending     map[string]chan JsonRpcMessage
+		pendingLock sync.Mutex
+
+		currentData chan map[string]ResultValue
+		done        chan struct{}
+		err         error
+
+		edgeId         string
+		edgeConfig     *EdgeConfig
+		subscribeCount int
+	}
+
+	websocketAuthResult struct {
+		Token string `json:"token"`
+		Edges []struct {
+			Id      string `json:"id"`
+			Version string `json:"version"`
+		} `json:"edges"`
+	}
+)
+
+func newWebsocketTransport(ctx context.Context, logger *slogger.Logger, url, userAgent, username, password string, timeout time.Duration) (*websocketTransport, error) {
+	t := websocketTransport{}
+	t.logger = logger.With(slog.String("websocket", url))
+	t.timeout = timeout
+	if t.timeout <= 0 {
+		t.timeout = 30 * time.Second
+	}
+	t.pending = map[string]chan JsonRpcMessage{}
+	t.currentData = make(chan map[string]ResultValue, 1)
+	t.done = make(chan struct{})
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: timeout,
+	}
+
+	header := http.Header{}
+	if userAgent != "" {
+		header.Set("User-Agent", userAgent)
+	}
+
+	t.logger.Debug(`connecting websocket`)
+	conn, resp, err := dialer.DialContext(ctx, url, header)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf(`unable to connect to websocket "%v": %w`, url, err)
+	}
+	t.conn = conn
+
+	go t.readLoop()
+
+	if err := t.authenticate(ctx, username, password); err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+
+	if err := t.fetchEdgeConfig(ctx); err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (t *websocketTransport) authenticate(ctx context.Context, username, password string) error {
+	params := map[string]string{"password": password}
+	if username != "" {
+		params["username"] = username
+	}
+
+	result := websocketAuthResult{}
+	if err := t.request(ctx, newJsonRpcRequest("authenticateWithPassword", params), &result); err != nil {
+		return fmt.Errorf(`%w: %w`, ErrAuthentication, err)
+	}
+
+	// local edge is always available as edge "0"
+	t.edgeId = "0"
+	if len(result.Edges) >= 1 && result.Edges[0].Id != "" {
+		t.edgeId = result.Edges[0].Id
+	}
+
+	return nil
+}
+
+func (t *websocketTransport) fetchEdgeConfig(ctx context.Context) error {
+	config := EdgeConfig{}
+	if err := t.edgeRequest(ctx, newJsonRpcRequest("getEdgeConfig", nil), &config); err != nil {
+		return fmt.Errorf(`unable to fetch edge config: %w`, err)
+	}
+	t.edgeConfig = &config
+	return nil
+}
+
+func (t *websocketTransport) Query(ctx context.Context, pattern string) (*ResultWildcard, int, error) {
+	result, err := t.query(ctx, pattern)
+	return result, 0, err
+}
+
+func (t *websocketTransport) query(ctx context.Context, pattern string) (*ResultWildcard, error) {
+	t.queryLock.Lock()
+	defer t.queryLock.Unlock()
+
+	result := ResultWildcard{}
+
+	channels, err := t.matchChannels(pattern)
+	if err != nil {
+		return &result, err
+	}
+
+	if len(channels) == 0 {
+		return &result, nil
+	}
+
+	// drop outdated data from previous subscription
+	select {
+	case <-t.currentData:
+	default:
+	}
+
+	addresses := make([]string, 0, len(channels))
+	for address := range channels {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	t.subscribeCount++
+	subscribeRequest := newJsonRpcRequest("subscribeChannels", map[string]interface{}{
+		"count":    t.subscribeCount,
+		"channels": addresses,
+	})
+	if err := t.edgeRequest(ctx, subscribeRequest, nil); err != nil {
+		return &result, fmt.Errorf(`unable to subscribe channels: %w`, err)
+	}
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case data := <-t.currentData:
+			found := false
+			for _, address := range addresses {
+				if value, exists := data[address]; exists {
+					found = true
+					row := channels[address]
+					row.Value = value
+					result = append(result, row)
+				}
+			}
+
+			if found {
+				return &result, nil
+			}
+		case <-ctx.Done():
+			return &result, ctx.Err()
+		case <-t.done:
+			return &result, t.err
+		case <-timer.C:
+			return &result, fmt.Errorf(`%w waiting for currentData of "%v"`, ErrTimeout, pattern)
+		}
+	}
+}
+
+// matchChannels returns all channels of the edge config matching the wildcard pattern
+func (t *websocketTransport) matchChannels(pattern string) (map[string]ResultCommon, error) {
+	ret := map[string]ResultCommon{}
+
+	componentPattern, channelPattern := splitWildcardPattern(pattern)
+
+	componentRegexp, err := regexp.Compile(`(?i)^(?:` + componentPattern + `)$`)
+	if err != nil {
+		return ret, fmt.Errorf(`invalid component pattern "%v": %w`, componentPattern, err)
+	}
+
+	channelRegexp, err := regexp.Compile(`(?i)^(?:` + channelPattern + `)$`)
+	if err != nil {
+		return ret, fmt.Errorf(`invalid channel pattern "%v": %w`, channelPattern, err)
+	}
+
+	for componentId, component := range t.edgeConfig.Components {
+		if !componentRegexp.MatchString(componentId) {
+			continue
+		}
+
+		for channelId, channel := range component.Channels {
+			if !channelRegexp.MatchString(channelId) {
+				continue
+			}
+
+			address := componentId + "/" + channelId
+			ret[address] = ResultCommon{
+				Address:    address,
+				Type:       channel.Type,
+				AccessMode: channel.AccessMode,
+				Text:       channel.Text,
+				Unit:       channel.Unit,
+				Category:   channel.Category,
+				Level:      channel.Level,
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// EdgeRequest sends the JSON-RPC request to the edge (wrapped into an "edgeRpc" request)
+func (t *websocketTransport) EdgeRequest(ctx context.Context, method string, params interface{}, result interface{}) error {
+	return t.edgeRequest(ctx, newJsonRpcRequest(method, params), result)
+}
+
+// edgeRequest sends the payload wrapped into an "edgeRpc" request and decodes the result of the payload response
+func (t *websocketTransport) edgeRequest(ctx context.Context, payload JsonRpcRequest, result interface{}) error {
+	response := struct {
+		Payload JsonRpcMessage `json:"payload"`
+	}{}
+
+	request := newJsonRpcRequest("edgeRpc", EdgeRpcParams{EdgeId: t.edgeId, Payload: payload})
+	if err := t.request(ctx, request, &response); err != nil {
+		return err
+	}
+
+	if response.Payload.Error != nil {
+		return response.Payload.Error
+	}
+
+	if result != nil && len(response.Payload.Result) > 0 {
+		if err := json.Unmarshal(response.Payload.Result, result); err != nil {
+			return fmt.Errorf(`unable to decode "%v" response: %w`, payload.Method, err)
+		}
+	}
+
+	return nil
+}
+
+// request sends the request and waits for the response
+func (t *websocketTransport) request(ctx context.Context, request JsonRpcRequest, result interface{}) error {
+	responseChan := make(chan JsonRpcMessage, 1)
+
+	t.pendingLock.Lock()
+	t.pending[request.Id] = responseChan
+	t.pendingLock.Unlock()
+
+	defer func() {
+		t.pendingLock.Lock()
+		delete(t.pending, request.Id)
+		t.pendingLock.Unlock()
+	}()
+
+	t.logger.Debugf(`sending jsonrpc request %v`, request.Method)
+
+	t.writeLock.Lock()
+	if t.timeout > 0 {
+		_ = t.conn.SetWriteDeadline(time.Now().Add(t.timeout))
+	}
+	err := t.conn.WriteJSON(request)
+	t.writeLock.Unlock()
+	if err != nil {
+		return fmt.Errorf(`unable to send "%v" request: %w`, request.Method, err)
+	}
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-responseChan:
+		if response.Error != nil {
+			return response.Error
+		}
+
+		if result != nil && len(response.Result) > 0 {
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				return fmt.Errorf(`unable to decode "%v" response: %w`, request.Method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.done:
+		return t.err
+	case <-timer.C:
+		return fmt.Errorf(`%w waiting for "%v" response`, ErrTimeout, request.Method)
+	}
+}
+
+func (t *websocketTransport) readLoop() {
+	defer close(t.done)
+
+	for {
+		_, data, err := t.conn.ReadMessage()
+		if err != nil {
+			t.err = fmt.Errorf(`websocket connection closed: %w`, err)
+			return
+		}
+
+		message := JsonRpcMessage{}
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.logger.Warn(`unable to decode websocket message`, slog.Any("error", err))
+			continue
+		}
+
+		switch {
+		case message.Method == "" && message.Id != "":
+			// response
+			t.pendingLock.Lock()
+			if responseChan, exists := t.pending[message.Id]; exists {
+				responseChan <- message
+			}
+			t.pendingLock.Unlock()
+		case message.Method == "edgeRpc":
+			// notification from edge
+			params := struct {
+				EdgeId  string         `json:"edgeId"`
+				Payload JsonRpcMessage `json:"payload"`
+			}{}
+			if err := json.Unmarshal(message.Params, &params); err != nil {
+				t.logger.Warn(`unable to decode edgeRpc notification`, slog.Any("error", err))
+				continue
+			}
+			t.handleNotification(params.Payload)
+		default:
+			t.handleNotification(message)
+		}
+	}
+}
+
+func (t *websocketTransport) handleNotification(message JsonRpcMessage) {
+	if message.Method != "currentData" {
+		return
+	}
+
+	data := map[string]ResultValue{}
+	if err := json.Unmarshal(message.Params, &data); err != nil {
+		t.logger.Warn(`unable to decode currentData notification`, slog.Any("error", err))
+		return
+	}
+
+	// keep only latest data
+	select {
+	case <-t.currentData:
+	default:
+	}
+	t.currentData <- data
+}
+
+func (t *websocketTransport) Close() error {
+	t.writeLock.Lock()
+	_ = t.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second),
+	)
+	t.writeLock.Unlock()
+
+	err := t.conn.Close()
+	<-t.done
+
+	if errors.Is(err, websocket.ErrCloseSent) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package fenecon
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	resty "resty.dev/v3"
+)
+
+func newTestWebsocketTransport(t *testing.T, fake *fakeOpenEMS, password string) (*websocketTransport, error) {
+	t.Helper()
+
+	server := fake.startWebsocket(t)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/"
+	return newWebsocketTransport(context.Background(), newTestLogger(), url, "test", "x", password, 5*time.Second)
+}
+
+func TestWebsocketTransportQuery(t *testing.T) {
+	fake := newFakeOpenEMS()
+
+	ws, err := newTestWebsocketTransport(t, fake, "user")
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	defer func() {
+		_ = ws.Close()
+	}()
+
+	server := fake.startRest(t)
+	rest := newRestTransport(resty.New().SetBasicAuth("x", "user"), server.URL)
+
+	for _, pattern := range []string{"_sum/.*", "ess.*/.*", "ess0/Soc", "_sum/Grid.*"} {
+		t.Run(pattern, func(t *testing.T) {
+			wsResult, _, err := ws.Query(context.Background(), pattern)
+			if err != nil {
+				t.Fatalf("websocket query failed: %v", err)
+			}
+
+			restResult, _, err := rest.Query(context.Background(), pattern)
+			if err != nil {
+				t.Fatalf("rest query failed: %v", err)
+			}
+
+			if len(*wsResult) == 0 {
+				t.Fatalf("websocket query returned no channels")
+			}
+
+			// category and level are only available from the edge config
+			for _, row := range *wsResult {
+				expected := fake.matchChannels(row.Address)[row.Address]
+				if row.Category != expected.Category || row.Level != expected.Level {
+					t.Errorf("%v: expected category %q and level %q, got %q and %q", row.Address, expected.Category, expected.Level, row.Category, row.Level)
+				}
+			}
+
+			if got, expected := normalizeResult(wsResult), normalizeResult(restResult); !reflect.DeepEqual(got, expected) {
+				t.Errorf("websocket result differs from rest result:\n got: %+v\nwant: %+v", got, expected)
+			}
+		})
+	}
+
+	if methods := fake.receivedMethods(); !slices.Contains(methods, "getEdgeConfig") || !slices.Contains(methods, "subscribeChannels") {
+		t.Errorf("expected getEdgeConfig and subscribeChannels requests, got %v", methods)
+	}
+}
+
+func TestWebsocketTransportAuthentication(t *testing.T) {
+	_, err := newTestWebsocketTransport(t, newFakeOpenEMS(), "wrong")
+	if !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("expected authentication error, got %v", err)
+	}
+}
+
+func TestWebsocketTransportEdgeRequest(t *testing.T) {
+	ws, err := newTestWebsocketTransport(t, newFakeOpenEMS(), "user")
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	defer func() {
+		_ = ws.Close()
+	}()
+
+	config := EdgeConfig{}
+	if err := ws.EdgeRequest(context.Background(), "getEdgeConfig", nil, &config); err != nil {
+		t.Fatalf("getEdgeConfig failed: %v", err)
+	}
+	if config.Components["ess0"].Alias != "Battery" {
+		t.Errorf("expected alias of ess0, got %+v", config.Components["ess0"])
+	}
+
+	var rpcErr *JsonRpcError
+	if err := ws.EdgeRequest(context.Background(), "unknownMethod", nil, nil); !errors.As(err, &rpcErr) {
+		t.Errorf("expected jsonrpc error, got %v", err)
+	}
+}
+
+// normalizeResult sorts the result by address and drops the fields which are only available from the edge config
+func normalizeResult(result *ResultWildcard) []ResultCommon {
+	ret := []ResultCommon{}
+	for _, row := range *result {
+		row.Category = ""
+		row.Level = ""
+		ret = append(ret, row)
+	}
+	slices.SortFunc(ret, func(a, b ResultCommon) int { return strings.Compare(a.Address, b.Address) })
+	return ret
+}
@@ -0,0 +1,94 @@
+package fenecon
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// export legacy metrics (raw device units) in addition to the normalized metrics
+	MetricsCompatLegacy = "legacy"
+
+	// only export normalized metrics
+	MetricsCompatNone = "none"
+)
+
+type (
+	// unitConversion converts an OpenEMS unit (eg. mV) to the Prometheus base unit (eg. V)
+	unitConversion struct {
+		base   string
+		factor float64
+	}
+)
+
+var (
+	// OpenEMS unit symbols (io.openems.common.channel.Unit)
+	unitConversions = map[string]unitConversion{
+		"W":     {base: "W", factor: 1},
+		"mW":    {base: "W", factor: 0.001},
+		"kW":    {base: "W", factor: 1000},
+		"Wh":    {base: "Wh", factor: 1},
+		"kWh":   {base: "Wh", factor: 1000},
+		"Wh_Σ":  {base: "Wh", factor: 1},
+		"kWh_Σ": {base: "Wh", factor: 1000},
+		"A":     {base: "A", factor: 1},
+		"mA":    {base: "A", factor: 0.001},
+		"V":     {base: "V", factor: 1},
+		"mV":    {base: "V", factor: 0.001},
+		"Hz":    {base: "Hz", factor: 1},
+		"mHz":   {base: "Hz", factor: 0.001},
+		"C":     {base: "C", factor: 1},
+		"°C":    {base: "C", factor: 1},
+		"dC":    {base: "C", factor: 0.1},
+		"mC":    {base: "C", factor: 0.001},
+	}
+)
+
+// SetMetricsCompat sets the compatibility mode (legacy: legacy and normalized metrics, none: normalized metrics only)
+func (fp *FeneconProber) SetMetricsCompat(val string) error {
+	switch val {
+	case "", MetricsCompatLegacy:
+		fp.legacyMetrics = true
+	case MetricsCompatNone:
+		fp.legacyMetrics = false
+	default:
+		return fmt.Errorf(`unsupported metrics compat "%v"`, val)
+	}
+
+	return nil
+}
+
+// NormalizedValue returns the value converted to the base unit (eg. mV -> V),
+// defaultUnit is the OpenEMS unit of the channel which is used if the unit is not reported
+func (r *ResultCommon) NormalizedValue(defaultUnit string) (float64, bool) {
+	if r.Value.ValueNumeric == nil {
+		return 0, false
+	}
+
+	unit := r.Unit
+	if unit == "" {
+		unit = defaultUnit
+	}
+
+	conversion, exists := unitConversions[unit]
+	if !exists || conversion.base != unitConversions[defaultUnit].base {
+		return 0, false
+	}
+
+	return *r.Value.ValueNumeric * conversion.factor, true
+}
+
+// setGaugeNormalized sets the normalized gauge (base unit) and the legacy gauge (raw device unit) if legacy metrics are enabled
+func (fp *FeneconProber) setGaugeNormalized(row *ResultCommon, labels prometheus.Labels, legacy, normalized *prometheus.GaugeVec, defaultUnit string) {
+	if fp.legacyMetrics {
+		row.SetGauge(labels, legacy)
+	}
+
+	if value, ok := row.NormalizedValue(defaultUnit); ok {
+		normalized.With(labels).Set(value)
+	} else if row.Value.ValueNumeric != nil {
+		fp.logger.Debug(`unable to normalize channel value`, slog.String("address", row.Address), slog.String("unit", row.Unit))
+	}
+}
@@ -0,0 +1,104 @@
+package fenecon
+
+import (
+	"testing"
+)
+
+func TestNormalizedValue(t *testing.T) {
+	value := func(val float64) *float64 { return &val }
+
+	tests := []struct {
+		name        string
+		unit        string
+		value       *float64
+		defaultUnit string
+		expected    float64
+		ok          bool
+	}{
+		{name: "millivolt", unit: "mV", value: value(3300), defaultUnit: "mV", expected: 3.3, ok: true},
+		{name: "volt reported for millivolt channel", unit: "V", value: value(230), defaultUnit: "mV", expected: 230, ok: true},
+		{name: "missing unit uses default unit", value: value(50010), defaultUnit: "mHz", expected: 50.01, ok: true},
+		{name: "kilowatt hours", unit: "kWh_Σ", value: value(12.5), defaultUnit: "Wh", expected: 12500, ok: true},
+		{name: "decidegree", unit: "dC", value: value(215), defaultUnit: "C", expected: 21.5, ok: true},
+		{name: "other base unit", unit: "A", value: value(10), defaultUnit: "mV", ok: false},
+		{name: "unknown unit", unit: "%", value: value(55), defaultUnit: "mV", ok: false},
+		{name: "no value", unit: "mV", defaultUnit: "mV", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			row := ResultCommon{Unit: test.unit, Value: ResultValue{ValueNumeric: test.value}}
+
+			normalized, ok := row.NormalizedValue(test.defaultUnit)
+			if ok != test.ok {
+				t.Fatalf("expected ok %v, got %v", test.ok, ok)
+			}
+			if ok && !floatEqual(normalized, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, normalized)
+			}
+		})
+	}
+}
+
+func TestSetMetricsCompat(t *testing.T) {
+	fp, _ := newTestProber(t)
+	for _, val := range []string{"", MetricsCompatLegacy, MetricsCompatNone} {
+		if err := fp.SetMetricsCompat(val); err != nil {
+			t.Errorf("unexpected error for %q: %v", val, err)
+		}
+	}
+	if err := fp.SetMetricsCompat("old"); err == nil {
+		t.Error("expected error for unsupported compat mode")
+	}
+}
+
+func TestCollectNormalizedMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		compat string
+		legacy bool
+	}{
+		{name: "legacy", compat: MetricsCompatLegacy, legacy: true},
+		{name: "none", compat: MetricsCompatNone, legacy: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newFakeOpenEMS()
+			fake.components["meter0"] = fakeComponent{
+				Channels: map[string]fakeChannel{
+					"Frequency": {Type: "INTEGER", Unit: "mHz", Value: 50010},
+					"VoltageL1": {Type: "INTEGER", Unit: "mV", Value: 230100},
+					"Current":   {Type: "INTEGER", Unit: "mA", Value: 5200},
+					// reported in other base unit, only exported as legacy metric
+					"VoltageL2": {Type: "INTEGER", Unit: "A", Value: 1},
+				},
+			}
+
+			fp, registry := newTestProber(t, QueryGroupMeter)
+			if err := fp.SetMetricsCompat(test.compat); err != nil {
+				t.Fatal(err)
+			}
+			if err := fp.Run(FeneconProberTarget{Target: fake.startRest(t).URL}); err != nil {
+				t.Fatalf("probe failed: %v", err)
+			}
+
+			// normalized metrics are always exported
+			assertMetrics(t, registry, "fenecon_meter_frequency_hertz", map[string]float64{"module=meter0": 50.01})
+			assertMetrics(t, registry, "fenecon_meter_voltage_phase_volts", map[string]float64{"module=meter0,phase=1": 230.1})
+			assertMetrics(t, registry, "fenecon_meter_current_amperes", map[string]float64{"module=meter0": 5.2})
+
+			legacyFrequency := map[string]float64{}
+			legacyVoltage := map[string]float64{}
+			legacyCurrent := map[string]float64{}
+			if test.legacy {
+				legacyFrequency = map[string]float64{"module=meter0": 50010}
+				legacyVoltage = map[string]float64{"module=meter0,phase=1": 230100, "module=meter0,phase=2": 1}
+				legacyCurrent = map[string]float64{"module=meter0": 5200}
+			}
+			assertMetrics(t, registry, "fenecon_meter_frequency", legacyFrequency)
+			assertMetrics(t, registry, "fenecon_meter_voltage_phase", legacyVoltage)
+			assertMetrics(t, registry, "fenecon_meter_current", legacyCurrent)
+		})
+	}
+}
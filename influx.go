@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+var (
+	influxPushFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fenecon_influx_push_failures_total",
+			Help: "Fenecon number of failed InfluxDB pushes",
+		},
+	)
+
+	influxPushLastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fenecon_influx_push_last_success_timestamp_seconds",
+			Help: "Fenecon timestamp of last successful InfluxDB push",
+		},
+	)
+)
+
+// initInfluxPush starts pushing the cached metrics of background polled targets to InfluxDB
+func initInfluxPush() {
+	if Opts.Influx.Push.Url == "" {
+		return
+	}
+
+	prometheus.MustRegister(influxPushFailures, influxPushLastSuccess)
+
+	if len(pollTargets()) == 0 {
+		logger.Warn(`InfluxDB push is enabled but no targets are polled in background (--poll.target or poll: true in config file)`)
+	}
+
+	writer, err := fenecon.NewInfluxWriter(Opts.Influx.Push.Url, Opts.Influx.Push.Token, Opts.Influx.Push.Timeout)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	logger.Info(`starting InfluxDB push`, slog.Duration("interval", Opts.Influx.Push.Interval))
+	go func() {
+		ticker := time.NewTicker(Opts.Influx.Push.Interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			pushInflux(writer)
+		}
+	}()
+}
+
+func pushInflux(writer *fenecon.InfluxWriter) {
+	families, err := poller.GatherWithTimestamps()
+	if err != nil {
+		logger.Warn(`failed to gather metrics for InfluxDB push`, slog.Any("error", err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Opts.Influx.Push.Timeout)
+	defer cancel()
+
+	if err := writer.Write(ctx, families, time.Now()); err != nil {
+		logger.Warn(`InfluxDB push failed`, slog.Any("error", err))
+		influxPushFailures.Inc()
+		return
+	}
+
+	influxPushLastSuccess.SetToCurrentTime()
+}
@@ -8,6 +8,7 @@ import (
 	"runtime"
 
 	flags "github.com/jessevdk/go-flags"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/webdevops/fenecon-exporter/config"
@@ -35,6 +36,32 @@ func main() {
 	logger.Infof("starting fenecon-exporter v%s (%s; %s; by %v at %v)", gitTag, gitCommit, runtime.Version(), Author, buildDate)
 	logger.Info(string(Opts.GetJson()))
 	initSystem()
+	initConfigFile()
+
+	if argparser.Active != nil && argparser.Active.Name == BackfillCommand {
+		if err := runBackfill(); err != nil {
+			logger.Fatal(err.Error())
+		}
+		return
+	}
+
+	if argparser.Active != nil && argparser.Active.Name == DiscoverCommand {
+		if err := runDiscover(); err != nil {
+			logger.Fatal(err.Error())
+		}
+		return
+	}
+
+	initMapping()
+	initEnergyAggregator()
+	initCostAccountant()
+	initComponentCache()
+	initPoller()
+	initDiscovery()
+	initInfluxPush()
+	initMqttPublisher()
+	initOtlpExporter()
+	initRemoteWrite()
 
 	logger.Infof("starting http server on %s", Opts.Server.Bind)
 	startHttpServer()
@@ -42,6 +69,8 @@ func main() {
 
 func initArgparser() {
 	argparser = flags.NewParser(&Opts, flags.Default)
+	initBackfillCommand()
+	initDiscoverCommand()
 	_, err := argparser.Parse()
 
 	// check if there is an parse error
@@ -75,8 +104,13 @@ func startHttpServer() {
 		}
 	})
 
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.Gatherers{prometheus.DefaultGatherer, poller}, probeHandlerOpts),
+	))
 	mux.HandleFunc("/probe", probeFenecon)
+	mux.HandleFunc("/sd", serviceDiscovery)
+	mux.HandleFunc("/schedule", serveSchedule)
 
 	srv := &http.Server{
 		Addr:         Opts.Server.Bind,
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/webdevops/go-common/log/slogger"
+
+	"github.com/webdevops/fenecon-exporter/config"
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+// writeTestFile writes the content to a temp file and returns the path
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setupTest resets the global state of the exporter, parses the options (defaults and args) and loads the config
+// file (content written to a temp file, empty: no config file)
+func setupTest(t *testing.T, configContent string, args ...string) {
+	t.Helper()
+
+	if configContent != "" {
+		args = append(args, "--config.file="+writeTestFile(t, "config.yaml", configContent))
+	}
+
+	Opts = config.Opts{}
+	if _, err := flags.NewParser(&Opts, flags.None).ParseArgs(args); err != nil {
+		t.Fatalf("invalid options: %v", err)
+	}
+
+	logger = slogger.New(slog.NewTextHandler(io.Discard, nil))
+	energyAggregator = nil
+	costAccountant = nil
+	mapping = nil
+	componentCache = fenecon.NewComponentCache(Opts.Fenecon.Components.CacheTtl)
+
+	discoveredTargetsLock.Lock()
+	discoveredTargets = nil
+	discoveredTargetsLock.Unlock()
+
+	conf, err := loadConfigFile()
+	if err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+	setConfigFile(conf)
+
+	poller = NewPoller(Opts.Poll.Interval, Opts.Poll.Timeout, Opts.Poll.StaleAfter)
+	t.Cleanup(func() {
+		poller.SetTargets(nil)
+		poller.running.Wait()
+	})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+var (
+	mapping *fenecon.Mapping
+)
+
+func initMapping() {
+	if Opts.Fenecon.Mapping.File == "" {
+		return
+	}
+
+	conf, err := fenecon.LoadMappingFile(Opts.Fenecon.Mapping.File)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	// check mapped metrics against built-in metrics (duplicate names, inconsistent labels)
+	prober := fenecon.New(context.Background(), prometheus.NewRegistry(), logger)
+	if err := prober.SetMapping(conf); err != nil {
+		logger.Fatal(`invalid mapping file`, slog.String("path", Opts.Fenecon.Mapping.File), slog.Any("error", err))
+	}
+
+	mapping = conf
+	logger.Info(`loaded mapping file`, slog.String("path", Opts.Fenecon.Mapping.File), slog.Int("metrics", len(conf.Metrics)))
+}
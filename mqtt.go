@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+var (
+	mqttPublishFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fenecon_mqtt_publish_failures_total",
+			Help: "Fenecon number of failed MQTT publishes",
+		},
+	)
+
+	mqttPublishLastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fenecon_mqtt_publish_last_success_timestamp_seconds",
+			Help: "Fenecon timestamp of last successful MQTT publish",
+		},
+	)
+)
+
+// initMqttPublisher starts publishing the cached metrics of background polled targets to MQTT
+func initMqttPublisher() {
+	if Opts.Mqtt.Url == "" {
+		return
+	}
+
+	prometheus.MustRegister(mqttPublishFailures, mqttPublishLastSuccess)
+
+	if len(pollTargets()) == 0 {
+		logger.Warn(`MQTT publishing is enabled but no targets are polled in background (--poll.target or poll: true in config file)`)
+	}
+
+	publisher, err := fenecon.NewMqttPublisher(fenecon.MqttOpts{
+		Url:             Opts.Mqtt.Url,
+		ClientId:        Opts.Mqtt.ClientId,
+		Username:        Opts.Mqtt.Username,
+		Password:        Opts.Mqtt.Password,
+		Topic:           Opts.Mqtt.Topic,
+		DiscoveryPrefix: Opts.Mqtt.Discovery.Prefix,
+		Timeout:         Opts.Mqtt.Timeout,
+	}, logger)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	logger.Info(`starting MQTT publishing`, slog.String("url", Opts.Mqtt.Url), slog.Duration("interval", Opts.Mqtt.Interval))
+	go func() {
+		ticker := time.NewTicker(Opts.Mqtt.Interval)
+		defer ticker.Stop()
+
+		publishMqtt(publisher)
+		for range ticker.C {
+			publishMqtt(publisher)
+		}
+	}()
+}
+
+// publishMqtt publishes all polled targets, failed targets don't stop publishing of the other targets
+func publishMqtt(publisher *fenecon.MqttPublisher) {
+	failed := false
+	for _, name := range pollTargets() {
+		pt := poller.Target(name)
+		if pt == nil {
+			continue
+		}
+
+		families, err := pt.Gather()
+		if err != nil {
+			logger.Warn(`failed to gather metrics for MQTT publishing`, slog.String("target", name), slog.Any("error", err))
+		}
+
+		if err := publisher.Publish(name, families); err != nil {
+			logger.Warn(`MQTT publish failed`, slog.String("target", name), slog.Any("error", err))
+			mqttPublishFailures.Inc()
+			failed = true
+		}
+	}
+
+	if !failed {
+		mqttPublishLastSuccess.SetToCurrentTime()
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+type (
+	// fakeOpenEMS is a minimal OpenEMS edge serving the REST-Api (/rest/channel, /jsonrpc) for tests
+	fakeOpenEMS struct {
+		// required password (empty allows anonymous access)
+		password string
+
+		// address (eg. _sum/GridActivePower) -> channel
+		channels map[string]fakeChannel
+
+		// all requests fail with http status 500 (eg. system is down)
+		failing bool
+
+		// channel queries (eg. meter.*/.*) failing with http status 500
+		failingQueries map[string]bool
+
+		// schedule slots of the time of use tariff controllers (componentJsonApi getSchedule)
+		schedules map[string][]map[string]interface{}
+
+		// number of received requests
+		requests int
+
+		lock sync.Mutex
+	}
+
+	fakeChannel struct {
+		Type  string
+		Unit  string
+		Value interface{}
+	}
+)
+
+func newFakeOpenEMS() *fakeOpenEMS {
+	return &fakeOpenEMS{
+		password: "user",
+		channels: map[string]fakeChannel{
+			"_sum/State":                   {Type: "INTEGER", Value: 0},
+			"_sum/EssSoc":                  {Type: "INTEGER", Unit: "%", Value: 55},
+			"_sum/GridActivePower":         {Type: "INTEGER", Unit: "W", Value: 1200},
+			"_sum/ProductionActivePower":   {Type: "INTEGER", Unit: "W", Value: 2000},
+			"_sum/ConsumptionActivePower":  {Type: "INTEGER", Unit: "W", Value: 3200},
+			"_sum/GridBuyActiveEnergy":     {Type: "LONG", Unit: "Wh", Value: 123456},
+			"_sum/ConsumptionActiveEnergy": {Type: "LONG", Unit: "Wh", Value: 199999},
+			"_meta/Version":                {Type: "STRING", Value: "2024.10.1"},
+			"_host/Hostname":               {Type: "STRING", Value: "fems12345"},
+		},
+		failingQueries: map[string]bool{},
+	}
+}
+
+// setFailing lets all requests fail (or succeed again)
+func (f *fakeOpenEMS) setFailing(failing bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failing = failing
+}
+
+// setFailingQuery lets the channel query fail
+func (f *fakeOpenEMS) setFailingQuery(query string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failingQueries[query] = true
+}
+
+// receivedRequests returns the number of received requests
+func (f *fakeOpenEMS) receivedRequests() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.requests
+}
+
+// setChannel sets the channel value
+func (f *fakeOpenEMS) setChannel(address string, channel fakeChannel) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.channels[address] = channel
+}
+
+// start starts the REST-Api (/rest/channel/<pattern> and /jsonrpc)
+func (f *fakeOpenEMS) start(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/channel/", f.serveRestChannel)
+	mux.HandleFunc("/jsonrpc", f.serveRestJsonRpc)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// serve checks failure and authentication, returns false if the request was answered
+func (f *fakeOpenEMS) serve(w http.ResponseWriter, r *http.Request) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.requests++
+	if f.failing || f.failingQueries[strings.TrimPrefix(r.URL.Path, "/rest/channel/")] {
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	if _, password, _ := r.BasicAuth(); f.password != "" && password != f.password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+func (f *fakeOpenEMS) serveRestChannel(w http.ResponseWriter, r *http.Request) {
+	if !f.serve(w, r) {
+		return
+	}
+
+	pattern := regexp.MustCompile("^(?:" + strings.TrimPrefix(r.URL.Path, "/rest/channel/") + ")$")
+
+	f.lock.Lock()
+	rows := []map[string]interface{}{}
+	for address, channel := range f.channels {
+		if pattern.MatchString(address) {
+			rows = append(rows, map[string]interface{}{
+				"address":    address,
+				"type":       channel.Type,
+				"accessMode": "RO",
+				"unit":       channel.Unit,
+				"value":      channel.Value,
+			})
+		}
+	}
+	f.lock.Unlock()
+
+	if len(rows) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rows)
+}
+
+func (f *fakeOpenEMS) serveRestJsonRpc(w http.ResponseWriter, r *http.Request) {
+	if !f.serve(w, r) {
+		return
+	}
+
+	request := fenecon.JsonRpcMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	response := fenecon.JsonRpcMessage{JsonRpc: fenecon.JsonRpcVersion, Id: request.Id}
+	switch request.Method {
+	case "getEdgeConfig":
+		response.Result = json.RawMessage(`{"components": {}}`)
+	case "componentJsonApi":
+		params := struct {
+			ComponentId string                 `json:"componentId"`
+			Payload     fenecon.JsonRpcMessage `json:"payload"`
+		}{}
+		_ = json.Unmarshal(request.Params, &params)
+
+		f.lock.Lock()
+		schedule, exists := f.schedules[params.ComponentId]
+		f.lock.Unlock()
+		if params.Payload.Method != "getSchedule" || !exists {
+			response.Error = &fenecon.JsonRpcError{Code: 1001, Message: "Unhandled request"}
+			break
+		}
+		response.Result, _ = json.Marshal(map[string]interface{}{"schedule": schedule})
+	default:
+		response.Error = &fenecon.JsonRpcError{Code: 1001, Message: "Unhandled request"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// gatherMetrics returns the label sets and values of all series of the metric
+func gatherMetrics(t *testing.T, gatherer prometheus.Gatherer, name string) map[string]float64 {
+	t.Helper()
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("unable to gather metrics: %v", err)
+	}
+
+	ret := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			labels := []string{}
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetName()+"="+label.GetValue())
+			}
+
+			value := metric.GetGauge().GetValue()
+			if metric.GetCounter() != nil {
+				value = metric.GetCounter().GetValue()
+			}
+			ret[strings.Join(labels, ",")] = value
+		}
+	}
+	return ret
+}
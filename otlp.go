@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelprometheus "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+type (
+	// otlpTarget is the meter provider of a background polled target,
+	// the resource attributes (edge id, version) are only known after the first successful poll
+	otlpTarget struct {
+		provider   *sdkmetric.MeterProvider
+		attributes attribute.Distinct
+	}
+)
+
+var (
+	otlpTargets     = map[string]*otlpTarget{}
+	otlpTargetsLock sync.Mutex
+
+	otlpExportFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fenecon_otlp_export_failures_total",
+			Help: "Fenecon number of failed OTLP exports",
+		},
+	)
+)
+
+func otlpEnabled() bool {
+	return Opts.Otlp.Endpoint != ""
+}
+
+// initOtlpExporter starts exporting the cached metrics of background polled targets via OTLP (one meter provider per target)
+func initOtlpExporter() {
+	if !otlpEnabled() {
+		return
+	}
+
+	prometheus.MustRegister(otlpExportFailures)
+
+	if len(pollTargets()) == 0 {
+		logger.Warn(`OTLP export is enabled but no targets are polled in background (--poll.target or poll: true in config file)`)
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn(`OTLP export failed`, slog.Any("error", err))
+		otlpExportFailures.Inc()
+	}))
+
+	logger.Info(`starting OTLP export`, slog.String("endpoint", Opts.Otlp.Endpoint), slog.String("protocol", Opts.Otlp.Protocol), slog.Duration("interval", Opts.Otlp.Interval))
+	go func() {
+		ticker := time.NewTicker(Opts.Poll.Interval)
+		defer ticker.Stop()
+
+		for {
+			syncOtlpTargets()
+			<-ticker.C
+		}
+	}()
+}
+
+// syncOtlpTargets creates meter providers for new targets (after first successful poll),
+// recreates them if resource attributes have been changed and removes them for targets which are not polled anymore
+func syncOtlpTargets() {
+	otlpTargetsLock.Lock()
+	defer otlpTargetsLock.Unlock()
+
+	names := map[string]bool{}
+	for _, name := range pollTargets() {
+		names[name] = true
+
+		pt := poller.Target(name)
+		if pt == nil || pt.EdgeInfo() == nil {
+			continue
+		}
+
+		attributes := otlpResourceAttributes(pt)
+		if ot, exists := otlpTargets[name]; exists {
+			if ot.attributes == attributes.Equivalent() {
+				continue
+			}
+			shutdownOtlpTarget(name, ot)
+		}
+
+		provider, err := newOtlpMeterProvider(pt, attributes)
+		if err != nil {
+			logger.Error(`failed to setup OTLP export`, slog.String("target", name), slog.Any("error", err))
+			continue
+		}
+
+		logger.Info(`starting OTLP export of target`, slog.String("target", name))
+		otlpTargets[name] = &otlpTarget{provider: provider, attributes: attributes.Equivalent()}
+	}
+
+	for name, ot := range otlpTargets {
+		if !names[name] {
+			shutdownOtlpTarget(name, ot)
+			delete(otlpTargets, name)
+		}
+	}
+}
+
+func otlpResourceAttributes(pt *PollerTarget) attribute.Set {
+	attributes := []attribute.KeyValue{
+		attribute.String("service.name", "fenecon-exporter"),
+		attribute.String("service.version", gitTag),
+		attribute.String("fenecon.target", pt.target.Target),
+		attribute.String("fenecon.target.name", pt.name),
+	}
+
+	if edgeInfo := pt.EdgeInfo(); edgeInfo != nil {
+		if edgeInfo.Id != "" {
+			attributes = append(attributes, attribute.String("fenecon.edge.id", edgeInfo.Id))
+		}
+		if edgeInfo.Version != "" {
+			attributes = append(attributes, attribute.String("fenecon.version", edgeInfo.Version))
+		}
+		if edgeInfo.Hostname != "" {
+			attributes = append(attributes, attribute.String("host.name", edgeInfo.Hostname))
+		}
+	}
+
+	return attribute.NewSet(attributes...)
+}
+
+// newOtlpMeterProvider creates the meter provider which exports the cached metrics of the target (Prometheus bridge):
+// counters are exported as cumulative monotonic sums and gauges as gauges
+func newOtlpMeterProvider(pt *PollerTarget, attributes attribute.Set) (*sdkmetric.MeterProvider, error) {
+	exporter, err := newOtlpMetricExporter(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	reader := sdkmetric.NewPeriodicReader(
+		exporter,
+		sdkmetric.WithProducer(otelprometheus.NewMetricProducer(otelprometheus.WithGatherer(pt))),
+		sdkmetric.WithInterval(Opts.Otlp.Interval),
+		sdkmetric.WithTimeout(Opts.Otlp.Timeout),
+	)
+
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(attributes.ToSlice()...)),
+	), nil
+}
+
+func newOtlpMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	endpointIsUrl := strings.Contains(Opts.Otlp.Endpoint, "://")
+
+	switch Opts.Otlp.Protocol {
+	case "http":
+		opts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithHeaders(Opts.Otlp.Headers),
+			otlpmetrichttp.WithTimeout(Opts.Otlp.Timeout),
+		}
+		if endpointIsUrl {
+			opts = append(opts, otlpmetrichttp.WithEndpointURL(Opts.Otlp.Endpoint))
+		} else {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(Opts.Otlp.Endpoint))
+		}
+		if Opts.Otlp.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	case "grpc":
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithHeaders(Opts.Otlp.Headers),
+			otlpmetricgrpc.WithTimeout(Opts.Otlp.Timeout),
+		}
+		if endpointIsUrl {
+			opts = append(opts, otlpmetricgrpc.WithEndpointURL(Opts.Otlp.Endpoint))
+		} else {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(Opts.Otlp.Endpoint))
+		}
+		if Opts.Otlp.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf(`unsupported OTLP protocol "%v"`, Opts.Otlp.Protocol)
+	}
+}
+
+func shutdownOtlpTarget(name string, ot *otlpTarget) {
+	ctx, cancel := context.WithTimeout(context.Background(), Opts.Otlp.Timeout)
+	defer cancel()
+
+	logger.Info(`stopping OTLP export of target`, slog.String("target", name))
+	if err := ot.provider.Shutdown(ctx); err != nil {
+		logger.Warn(`failed to stop OTLP export`, slog.String("target", name), slog.Any("error", err))
+	}
+}
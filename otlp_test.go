@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricsv1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+type (
+	// fakeOtlpCollector receives OTLP/HTTP metric exports
+	fakeOtlpCollector struct {
+		requests []*collectormetrics.ExportMetricsServiceRequest
+		lock     sync.Mutex
+	}
+)
+
+func (c *fakeOtlpCollector) start(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil || r.URL.Path != "/v1/metrics" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		request := collectormetrics.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, &request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		c.lock.Lock()
+		c.requests = append(c.requests, &request)
+		c.lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		response, _ := proto.Marshal(&collectormetrics.ExportMetricsServiceResponse{})
+		_, _ = w.Write(response)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// lastRequest returns the last received export (nil if nothing was received)
+func (c *fakeOtlpCollector) lastRequest() *collectormetrics.ExportMetricsServiceRequest {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.requests) == 0 {
+		return nil
+	}
+	return c.requests[len(c.requests)-1]
+}
+
+// resetOtlpTargets stops and removes all meter providers of the OTLP export
+func resetOtlpTargets(t *testing.T) {
+	t.Helper()
+
+	otlpTargetsLock.Lock()
+	defer otlpTargetsLock.Unlock()
+	for name, ot := range otlpTargets {
+		shutdownOtlpTarget(name, ot)
+	}
+	otlpTargets = map[string]*otlpTarget{}
+}
+
+func otlpTargetOf(name string) *otlpTarget {
+	otlpTargetsLock.Lock()
+	defer otlpTargetsLock.Unlock()
+	return otlpTargets[name]
+}
+
+func TestOtlpResourceAttributes(t *testing.T) {
+	setupTest(t, "targets:\n  - name: home\n    url: http://fenecon\n")
+
+	pt := addTestPollerTarget(t, "home")
+	pt.edgeInfo = &fenecon.EdgeInfo{Id: "fems12345", Version: "2024.10.1"}
+
+	attributes := otlpResourceAttributes(pt)
+	expected := map[string]string{
+		"service.name":        "fenecon-exporter",
+		"fenecon.target":      "http://fenecon",
+		"fenecon.target.name": "home",
+		"fenecon.edge.id":     "fems12345",
+		"fenecon.version":     "2024.10.1",
+	}
+	for key, value := range expected {
+		if got, exists := attributes.Value(attribute.Key(key)); !exists || got.AsString() != value {
+			t.Errorf("expected attribute %v=%v, got %v", key, value, got.AsString())
+		}
+	}
+
+	// unknown edge info is not exported
+	if _, exists := attributes.Value("host.name"); exists {
+		t.Error("expected no host.name attribute without hostname")
+	}
+}
+
+func TestNewOtlpMetricExporter(t *testing.T) {
+	setupTest(t, "", "--otlp.endpoint=localhost:4318")
+
+	for _, protocol := range []string{"http", "grpc"} {
+		Opts.Otlp.Protocol = protocol
+		exporter, err := newOtlpMetricExporter(context.Background())
+		if err != nil {
+			t.Errorf("unexpected error for protocol %v: %v", protocol, err)
+			continue
+		}
+		_ = exporter.Shutdown(context.Background())
+	}
+
+	Opts.Otlp.Protocol = "mqtt"
+	if _, err := newOtlpMetricExporter(context.Background()); err == nil {
+		t.Error("expected error for unsupported protocol")
+	}
+}
+
+func TestSyncOtlpTargets(t *testing.T) {
+	fake := newFakeOpenEMS()
+	server := fake.start(t)
+	collector := fakeOtlpCollector{}
+	collectorServer := collector.start(t)
+
+	setupTest(t, fmt.Sprintf("targets:\n  - name: home\n    url: %v\n    poll: true\n", server.URL),
+		"--fenecon.request.retries=0", "--fenecon.components.ttl=0", "--otlp.endpoint="+strings.TrimPrefix(collectorServer.URL, "http://"), "--otlp.protocol=http", "--otlp.insecure", "--otlp.interval=1h")
+	resetOtlpTargets(t)
+	t.Cleanup(func() { resetOtlpTargets(t) })
+
+	pt := addTestPollerTarget(t, "home")
+
+	// resource attributes are not known before the first successful poll
+	syncOtlpTargets()
+	if otlpTargetOf("home") != nil {
+		t.Fatal("expected no OTLP export before first poll")
+	}
+
+	poller.poll(context.Background(), pt)
+	syncOtlpTargets()
+	ot := otlpTargetOf("home")
+	if ot == nil {
+		t.Fatal("expected OTLP export after first poll")
+	}
+
+	if err := ot.provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	request := collector.lastRequest()
+	if request == nil || len(request.GetResourceMetrics()) != 1 {
+		t.Fatalf("expected export of one resource, got %v", request)
+	}
+
+	resourceAttributes := map[string]string{}
+	for _, kv := range request.GetResourceMetrics()[0].GetResource().GetAttributes() {
+		resourceAttributes[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	if resourceAttributes["fenecon.target.name"] != "home" || resourceAttributes["fenecon.edge.id"] != "fems12345" || resourceAttributes["fenecon.version"] != "2024.10.1" {
+		t.Errorf("unexpected resource attributes %v", resourceAttributes)
+	}
+
+	metrics := map[string]*metricsv1.Metric{}
+	for _, scope := range request.GetResourceMetrics()[0].GetScopeMetrics() {
+		for _, metric := range scope.GetMetrics() {
+			metrics[metric.GetName()] = metric
+		}
+	}
+
+	// gauges are exported as gauges, counters as cumulative monotonic sums
+	if gauge := metrics["fenecon_grid_power"].GetGauge(); gauge == nil || len(gauge.GetDataPoints()) != 1 || gauge.GetDataPoints()[0].GetAsDouble() != 1200 {
+		t.Errorf("expected gauge fenecon_grid_power 1200, got %v", metrics["fenecon_grid_power"])
+	}
+	sum := metrics["fenecon_grid_buy_energy_watt_hours_total"].GetSum()
+	if sum == nil || !sum.GetIsMonotonic() || sum.GetAggregationTemporality() != metricsv1.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Errorf("expected cumulative monotonic sum, got %v", metrics["fenecon_grid_buy_energy_watt_hours_total"])
+	} else if len(sum.GetDataPoints()) != 1 || sum.GetDataPoints()[0].GetAsDouble() != 123456 {
+		t.Errorf("expected fenecon_grid_buy_energy_watt_hours_total 123456, got %v", sum.GetDataPoints())
+	}
+
+	// unchanged resource attributes keep the meter provider
+	syncOtlpTargets()
+	if otlpTargetOf("home") != ot {
+		t.Error("expected meter provider to be kept")
+	}
+
+	// changed resource attributes (eg. firmware update) recreate the meter provider
+	fake.setChannel("_meta/Version", fakeChannel{Type: "STRING", Value: "2024.11.0"})
+	poller.poll(context.Background(), pt)
+	syncOtlpTargets()
+	if recreated := otlpTargetOf("home"); recreated == nil || recreated == ot {
+		t.Error("expected meter provider to be recreated after version change")
+	}
+
+	// targets which are not polled anymore are removed
+	if err := os.WriteFile(Opts.Config.File, []byte("targets: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfigFile()
+	syncOtlpTargets()
+	if otlpTargetOf("home") != nil {
+		t.Error("expected OTLP export of removed target to be stopped")
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+type (
+	// Poller polls targets in background and caches the resulting metrics per target
+	Poller struct {
+		interval   time.Duration
+		timeout    time.Duration
+		staleAfter time.Duration
+
+		targets     map[string]*PollerTarget
+		targetsLock sync.RWMutex
+
+		// background polling goroutines (also of removed targets until the running poll is finished)
+		running sync.WaitGroup
+	}
+
+	PollerTarget struct {
+		// name of configured target or url
+		name       string
+		target     fenecon.FeneconProberTarget
+		cancel     context.CancelFunc
+		staleAfter time.Duration
+
+		// registry of last poll with results
+		registry   *prometheus.Registry
+		lastUpdate time.Time
+		edgeInfo   *fenecon.EdgeInfo
+		lock       sync.RWMutex
+
+		// probe metrics (probe success, per query results) of last poll, also served if the poll failed
+		probeStatus *prometheus.Registry
+		lastPoll    time.Time
+
+		// status registry, always served (even if metrics are stale)
+		status     *prometheus.Registry
+		prometheus struct {
+			lastUpdate *prometheus.GaugeVec
+			failures   *prometheus.CounterVec
+		}
+	}
+)
+
+var (
+	poller *Poller
+)
+
+func initPoller() {
+	poller = NewPoller(Opts.Poll.Interval, Opts.Poll.Timeout, Opts.Poll.StaleAfter)
+	poller.SetTargets(pollTargets())
+}
+
+func NewPoller(interval, timeout, staleAfter time.Duration) *Poller {
+	p := Poller{}
+	p.interval = interval
+	p.timeout = timeout
+	p.staleAfter = staleAfter
+	p.targets = map[string]*PollerTarget{}
+	return &p
+}
+
+// SetTargets starts background polling of new targets and stops polling of removed (or changed) targets
+func (p *Poller) SetTargets(names []string) {
+	targets := map[string]fenecon.FeneconProberTarget{}
+	for _, name := range names {
+		target, err := resolveProbeTarget(name, "", "")
+		if err != nil {
+			logger.Error(`failed to resolve poll target`, slog.String("target", name), slog.Any("error", err))
+			continue
+		}
+		targets[name] = target.target
+	}
+
+	removedTargets := []string{}
+	p.targetsLock.RLock()
+	for name, pt := range p.targets {
+		if target, exists := targets[name]; !exists || target != pt.target {
+			removedTargets = append(removedTargets, name)
+		}
+	}
+	p.targetsLock.RUnlock()
+
+	for _, name := range removedTargets {
+		p.RemoveTarget(name)
+	}
+
+	for name, target := range targets {
+		p.AddTarget(name, target)
+	}
+}
+
+// AddTarget adds the target and starts background polling
+func (p *Poller) AddTarget(name string, target fenecon.FeneconProberTarget) {
+	p.targetsLock.Lock()
+	defer p.targetsLock.Unlock()
+
+	if _, exists := p.targets[name]; exists {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pt := &PollerTarget{}
+	pt.name = name
+	pt.target = target
+	pt.cancel = cancel
+	pt.staleAfter = p.staleAfter
+	pt.initMetrics()
+	p.targets[name] = pt
+
+	logger.Info(`starting background polling`, slog.String("target", name), slog.Duration("interval", p.interval))
+	p.running.Go(func() {
+		p.run(ctx, pt)
+	})
+}
+
+// RemoveTarget stops background polling of the target
+func (p *Poller) RemoveTarget(target string) {
+	p.targetsLock.Lock()
+	defer p.targetsLock.Unlock()
+
+	if pt, exists := p.targets[target]; exists {
+		logger.Info(`stopping background polling`, slog.String("target", target))
+		pt.cancel()
+		delete(p.targets, target)
+	}
+}
+
+// Target returns the polled target (by name) or nil if the target is not polled
+func (p *Poller) Target(name string) *PollerTarget {
+	p.targetsLock.RLock()
+	defer p.targetsLock.RUnlock()
+	return p.targets[name]
+}
+
+// Gather gathers the cached metrics of all targets
+func (p *Poller) Gather() ([]*dto.MetricFamily, error) {
+	p.targetsLock.RLock()
+	gatherers := prometheus.Gatherers{}
+	for _, pt := range p.targets {
+		gatherers = append(gatherers, pt)
+	}
+	p.targetsLock.RUnlock()
+
+	return gatherers.Gather()
+}
+
+// GatherWithTimestamps gathers the cached metrics of all targets with the time of the poll as timestamp (for push outputs),
+// so stale metrics are not pushed and unchanged metrics are not pushed as new samples
+func (p *Poller) GatherWithTimestamps() ([]*dto.MetricFamily, error) {
+	p.targetsLock.RLock()
+	gatherers := prometheus.Gatherers{}
+	for _, pt := range p.targets {
+		gatherers = append(gatherers, prometheus.GathererFunc(pt.GatherWithTimestamps))
+	}
+	p.targetsLock.RUnlock()
+
+	return gatherers.Gather()
+}
+
+func (p *Poller) run(ctx context.Context, pt *PollerTarget) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.poll(ctx, pt)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Poller) poll(ctx context.Context, pt *PollerTarget) {
+	contextLogger := logger.With(slog.String("target", pt.name))
+
+	pollCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	// resolve on every poll, module settings might have been changed by config reload
+	target, err := resolveProbeTarget(pt.name, "", "")
+	if err != nil {
+		contextLogger.Error(`failed to resolve target`, slog.Any("error", err))
+		pt.prometheus.failures.With(prometheus.Labels{"target": pt.target.Target}).Inc()
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	prober, err := newFeneconProber(pollCtx, registry, contextLogger, target.module)
+	if err != nil {
+		contextLogger.Error(`failed to setup prober`, slog.Any("error", err))
+		pt.prometheus.failures.With(prometheus.Labels{"target": pt.target.Target}).Inc()
+		return
+	}
+
+	probeStatus := prometheus.NewRegistry()
+	prober.SetStatusRegistry(probeStatus)
+
+	if energyAggregator != nil {
+		prober.SetEnergyAggregator(energyAggregator)
+	}
+	if costAccountant != nil {
+		prober.SetCostAccountant(costAccountant)
+	}
+
+	err = prober.Run(pt.target)
+	if ctx.Err() != nil {
+		// target was removed
+		return
+	}
+
+	pt.lock.Lock()
+	pt.probeStatus = probeStatus
+	pt.lastPoll = time.Now()
+	pt.lock.Unlock()
+
+	if err != nil {
+		pt.prometheus.failures.With(prometheus.Labels{"target": pt.target.Target}).Inc()
+
+		// partial results (eg. an optional query group failed) replace the previous metrics, the failed queries
+		// are reported by the probe metrics
+		if !prober.HasResults() {
+			contextLogger.Warn(`background poll failed, keeping previous metrics`, slog.Any("error", err))
+			return
+		}
+		contextLogger.Warn(`background poll partially failed`, slog.Any("error", err))
+	}
+
+	pt.lock.Lock()
+	pt.registry = registry
+	pt.lastUpdate = time.Now()
+	pt.edgeInfo = prober.EdgeInfo()
+	pt.lock.Unlock()
+
+	pt.prometheus.lastUpdate.With(prometheus.Labels{"target": pt.target.Target}).Set(float64(pt.lastUpdate.Unix()))
+}
+
+func (pt *PollerTarget) initMetrics() {
+	pt.status = prometheus.NewRegistry()
+
+	pt.prometheus.lastUpdate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fenecon_last_update_timestamp_seconds",
+			Help: "Fenecon timestamp of last background poll with results (successful or partially failed)",
+		},
+		[]string{"target"},
+	)
+	pt.status.MustRegister(pt.prometheus.lastUpdate)
+
+	pt.prometheus.failures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fenecon_poll_failures_total",
+			Help: "Fenecon number of failed background polls",
+		},
+		[]string{"target"},
+	)
+	pt.status.MustRegister(pt.prometheus.failures)
+
+	pt.prometheus.failures.With(prometheus.Labels{"target": pt.target.Target}).Add(0)
+}
+
+// Gather gathers the cached metrics of the target, metrics are dropped if they are stale (probe metrics of the last poll are always served)
+func (pt *PollerTarget) Gather() ([]*dto.MetricFamily, error) {
+	gatherers := prometheus.Gatherers{pt.status}
+
+	pt.lock.RLock()
+	if pt.probeStatus != nil {
+		gatherers = append(gatherers, pt.probeStatus)
+	}
+	if pt.registry != nil && time.Since(pt.lastUpdate) <= pt.staleAfter {
+		gatherers = append(gatherers, pt.registry)
+	}
+	pt.lock.RUnlock()
+
+	return gatherers.Gather()
+}
+
+// GatherWithTimestamps gathers the cached metrics of the target like Gather, the metrics of the poll are timestamped
+// with the time of the poll (status metrics of the poller are not timestamped)
+func (pt *PollerTarget) GatherWithTimestamps() ([]*dto.MetricFamily, error) {
+	pt.lock.RLock()
+	gatherers := prometheus.Gatherers{pt.status}
+	if pt.probeStatus != nil {
+		gatherers = append(gatherers, timestampedGatherer(pt.probeStatus, pt.lastPoll))
+	}
+	if pt.registry != nil && time.Since(pt.lastUpdate) <= pt.staleAfter {
+		gatherers = append(gatherers, timestampedGatherer(pt.registry, pt.lastUpdate))
+	}
+	pt.lock.RUnlock()
+
+	return gatherers.Gather()
+}
+
+// timestampedGatherer sets the timestamp of all metrics (without timestamp) of the gatherer
+func timestampedGatherer(gatherer prometheus.Gatherer, timestamp time.Time) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := gatherer.Gather()
+		timestampMs := timestamp.UnixMilli()
+		for _, family := range families {
+			for _, metric := range family.Metric {
+				if metric.TimestampMs == nil {
+					metric.TimestampMs = &timestampMs
+				}
+			}
+		}
+		return families, err
+	})
+}
+
+// EdgeInfo returns the edge id and firmware version of the last successful poll (nil if not available)
+func (pt *PollerTarget) EdgeInfo() *fenecon.EdgeInfo {
+	pt.lock.RLock()
+	defer pt.lock.RUnlock()
+	return pt.edgeInfo
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// addTestPollerTarget adds the target to the poller without background polling, polls are run by the test
+func addTestPollerTarget(t *testing.T, name string) *PollerTarget {
+	t.Helper()
+
+	target, err := resolveProbeTarget(name, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pt := &PollerTarget{}
+	pt.name = name
+	pt.target = target.target
+	pt.cancel = func() {}
+	pt.staleAfter = poller.staleAfter
+	pt.initMetrics()
+
+	poller.targetsLock.Lock()
+	poller.targets[name] = pt
+	poller.targetsLock.Unlock()
+
+	return pt
+}
+
+func TestPollerPoll(t *testing.T) {
+	fake := newFakeOpenEMS()
+	server := fake.start(t)
+	setupTest(t, fmt.Sprintf("targets:\n  - name: home\n    url: %v\n    poll: true\n", server.URL), "--fenecon.request.retries=0")
+
+	pt := addTestPollerTarget(t, "home")
+	series := "module=_sum,target=" + server.URL
+
+	steps := []struct {
+		name         string
+		failing      bool
+		failingQuery string
+		gridPower    int
+
+		// served grid power (-1: not served)
+		servedGridPower float64
+		probeSuccess    float64
+		failures        float64
+		updated         bool
+	}{
+		{name: "successful poll", gridPower: 1200, servedGridPower: 1200, probeSuccess: 1, failures: 0, updated: true},
+		{name: "failed poll keeps previous metrics", failing: true, gridPower: 1500, servedGridPower: 1200, probeSuccess: 0, failures: 1, updated: false},
+		{name: "partial results replace previous metrics", failingQuery: "meter.*/.*", gridPower: 1500, servedGridPower: 1500, probeSuccess: 0, failures: 2, updated: true},
+	}
+
+	for _, step := range steps {
+		fake.setFailing(step.failing)
+		if step.failingQuery != "" {
+			fake.setFailingQuery(step.failingQuery)
+		}
+		fake.setChannel("_sum/GridActivePower", fakeChannel{Type: "INTEGER", Unit: "W", Value: step.gridPower})
+
+		lastUpdate := pt.lastUpdate
+		poller.poll(context.Background(), pt)
+
+		if updated := pt.lastUpdate.After(lastUpdate); updated != step.updated {
+			t.Errorf("%v: expected updated %v, got %v", step.name, step.updated, updated)
+		}
+
+		if value := gatherMetrics(t, pt, "fenecon_grid_power")[series]; value != step.servedGridPower {
+			t.Errorf("%v: expected grid power %v, got %v", step.name, step.servedGridPower, value)
+		}
+		if value := gatherMetrics(t, pt, "fenecon_probe_success")["target="+server.URL]; value != step.probeSuccess {
+			t.Errorf("%v: expected probe success %v, got %v", step.name, step.probeSuccess, value)
+		}
+		if value := gatherMetrics(t, pt, "fenecon_poll_failures_total")["target="+server.URL]; value != step.failures {
+			t.Errorf("%v: expected %v failures, got %v", step.name, step.failures, value)
+		}
+		if value := gatherMetrics(t, pt, "fenecon_last_update_timestamp_seconds")["target="+server.URL]; value != float64(pt.lastUpdate.Unix()) {
+			t.Errorf("%v: expected last update %v, got %v", step.name, pt.lastUpdate.Unix(), value)
+		}
+	}
+}
+
+func TestPollerStale(t *testing.T) {
+	fake := newFakeOpenEMS()
+	server := fake.start(t)
+	setupTest(t, fmt.Sprintf("targets:\n  - name: home\n    url: %v\n", server.URL), "--poll.stale=1m")
+
+	pt := addTestPollerTarget(t, "home")
+	poller.poll(context.Background(), pt)
+
+	if len(gatherMetrics(t, poller, "fenecon_grid_power")) != 1 {
+		t.Fatal("expected grid power of successful poll")
+	}
+
+	// no successful poll within the stale duration, metrics are dropped but status and probe metrics are served
+	pt.lock.Lock()
+	pt.lastUpdate = time.Now().Add(-2 * time.Minute)
+	pt.lock.Unlock()
+
+	if metrics := gatherMetrics(t, poller, "fenecon_grid_power"); len(metrics) != 0 {
+		t.Errorf("expected stale metrics to be dropped, got %v", metrics)
+	}
+	for _, name := range []string{"fenecon_last_update_timestamp_seconds", "fenecon_poll_failures_total", "fenecon_probe_success"} {
+		if metrics := gatherMetrics(t, poller, name); len(metrics) != 1 {
+			t.Errorf("expected %v of stale target, got %v", name, metrics)
+		}
+	}
+}
+
+func TestPollerSetTargets(t *testing.T) {
+	setupTest(t, "targets:\n  - name: home\n    url: http://127.0.0.1:1\n  - name: office\n    url: http://127.0.0.1:2\n", "--poll.interval=1h")
+
+	poller.SetTargets([]string{"home", "office"})
+	home := poller.Target("home")
+	if home == nil || poller.Target("office") == nil {
+		t.Fatal("expected home and office to be polled")
+	}
+
+	// unchanged targets keep polling, removed targets are stopped
+	poller.SetTargets([]string{"home"})
+	if poller.Target("home") != home {
+		t.Error("expected unchanged target to keep polling")
+	}
+	if poller.Target("office") != nil {
+		t.Error("expected removed target to be stopped")
+	}
+}
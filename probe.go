@@ -11,35 +11,77 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/webdevops/go-common/log/slogger"
 
+	"github.com/webdevops/fenecon-exporter/config"
 	"github.com/webdevops/fenecon-exporter/fenecon"
 )
 
 const (
 	DefaultTimeout = 30
+
+	ProbeModeRaw = "raw"
 )
 
-func newFeneconProber(ctx context.Context, registry *prometheus.Registry, logger *slogger.Logger) *fenecon.FeneconProber {
+var (
+	// OpenMetrics for created timestamps of counters
+	probeHandlerOpts = promhttp.HandlerOpts{
+		EnableOpenMetrics:                   true,
+		EnableOpenMetricsTextCreatedSamples: true,
+	}
+
+	// component configuration (alias, factory) per target
+	componentCache *fenecon.ComponentCache
+)
+
+func initComponentCache() {
+	componentCache = fenecon.NewComponentCache(Opts.Fenecon.Components.CacheTtl)
+}
+
+func newFeneconProber(ctx context.Context, registry *prometheus.Registry, logger *slogger.Logger, module *config.Module) (*fenecon.FeneconProber, error) {
 	sp := fenecon.New(ctx, registry, logger)
 	sp.SetUserAgent(UserAgent + gitTag)
-	sp.SetTimeout(Opts.Fenecon.Request.Timeout)
-	sp.SetParallelRequests(Opts.Fenecon.Request.Parallel)
+	sp.SetTimeout(module.Request.Timeout)
+	sp.SetParallelRequests(module.Request.Parallel)
+	sp.SetWebsocketPort(module.Websocket.Port)
 	sp.SetRetry(
-		Opts.Fenecon.Request.RetryCount,
-		Opts.Fenecon.Request.RetryWaitTime,
-		Opts.Fenecon.Request.RetryMaxWaitTime,
+		module.Request.RetryCount,
+		module.Request.RetryWaitTime,
+		module.Request.RetryMaxWaitTime,
 	)
-	if len(Opts.Fenecon.Auth.Password) >= 1 {
-		sp.SetHttpAuth(Opts.Fenecon.Auth.Username, Opts.Fenecon.Auth.Password)
+	if len(module.Auth.Password) >= 1 {
+		sp.SetHttpAuth(module.Auth.Username, module.Auth.Password)
+	}
+
+	if err := sp.SetTransport(module.Transport); err != nil {
+		return nil, err
+	}
+
+	if err := sp.SetQueryGroups(module.Queries); err != nil {
+		return nil, err
+	}
+
+	if err := sp.SetMetricsCompat(module.Metrics.Compat); err != nil {
+		return nil, err
 	}
 
-	return sp
+	if componentCache != nil {
+		sp.SetComponentCache(componentCache)
+	}
+
+	if mapping != nil {
+		if err := sp.SetMapping(mapping); err != nil {
+			return nil, err
+		}
+	}
+
+	return sp, nil
 }
 
 func probeFenecon(w http.ResponseWriter, r *http.Request) {
 	var (
 		err            error
 		timeoutSeconds float64
-		target         fenecon.FeneconProberTarget
+		target         *probeTarget
+		rawFilter      *fenecon.RawFilter
 	)
 
 	// startTime := time.Now()
@@ -54,24 +96,110 @@ func probeFenecon(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// param: target
+	// param: target, module, transport
 	if val, err := paramsGetRequired(r.URL.Query(), "target"); err == nil {
-		target.Target = val
+		target, err = resolveProbeTarget(val, r.URL.Query().Get("module"), r.URL.Query().Get("transport"))
+		if err != nil {
+			contextLogger.Warn("failed to resolve target", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	} else {
 		contextLogger.Warn("failed to parse target", slog.Any("error", err))
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// param: format
+	output, err := newProbeOutput(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// param: mode, component.include, component.exclude, channel.include, channel.exclude
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "":
+	case ProbeModeRaw:
+		rawFilter, err = fenecon.NewRawFilter(
+			r.URL.Query().Get("component.include"),
+			r.URL.Query().Get("component.exclude"),
+			r.URL.Query().Get("channel.include"),
+			r.URL.Query().Get("channel.exclude"),
+		)
+		if err != nil {
+			contextLogger.Warn("failed to parse raw filter", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, fmt.Sprintf(`unsupported mode "%v"`, mode), http.StatusBadRequest)
+		return
+	}
+
+	// serve from cache if target is polled in background (and module settings or mode are not overridden)
+	if pt := poller.Target(target.name); pt != nil && !target.overridden && rawFilter == nil {
+		serveProbeMetrics(w, r, pt, output)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds*float64(time.Second)))
 	defer cancel()
 	r = r.WithContext(ctx)
 
-	prober := newFeneconProber(ctx, registry, contextLogger)
-	prober.Run(target)
+	prober, err := newFeneconProber(ctx, registry, contextLogger, target.module)
+	if err != nil {
+		contextLogger.Warn("failed to setup prober", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if rawFilter != nil {
+		prober.SetRawMode(rawFilter)
+	}
+
+	// energy and costs are only aggregated for configured and polled targets, the state is kept per target
+	if target.configured || poller.Target(target.name) != nil {
+		if energyAggregator != nil {
+			prober.SetEnergyAggregator(energyAggregator)
+		}
+		if costAccountant != nil {
+			prober.SetCostAccountant(costAccountant)
+		}
+	}
+
+	_ = prober.Run(target.target)
 
-	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-	h.ServeHTTP(w, r)
+	serveProbeMetrics(w, r, registry, output)
+}
+
+// newProbeOutput returns the output for the format, nil for Prometheus exposition format (served by promhttp)
+func newProbeOutput(format string) (fenecon.Output, error) {
+	switch format {
+	case "", fenecon.OutputFormatPrometheus:
+		return nil, nil
+	default:
+		return fenecon.NewOutput(format)
+	}
+}
+
+// serveProbeMetrics serves the metrics via promhttp or renders them with the output (eg. InfluxDB line protocol)
+func serveProbeMetrics(w http.ResponseWriter, r *http.Request, gatherer prometheus.Gatherer, output fenecon.Output) {
+	if output == nil {
+		h := promhttp.HandlerFor(gatherer, probeHandlerOpts)
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		logger.Warn(`failed to gather metrics`, slog.Any("error", err))
+	}
+
+	w.Header().Set("Content-Type", output.ContentType())
+	if err := output.Write(w, families, time.Now()); err != nil {
+		logger.Error(`failed to write metrics`, slog.Any("error", err))
+	}
 }
 
 func buildContextLoggerFromRequest(req *http.Request) *slogger.Logger {
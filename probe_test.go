@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// probe runs the probe handler with the query parameters
+func probe(t *testing.T, params url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	probeFenecon(recorder, httptest.NewRequest(http.MethodGet, "/probe?"+params.Encode(), nil))
+	return recorder
+}
+
+func TestProbe(t *testing.T) {
+	fake := newFakeOpenEMS()
+	server := fake.start(t)
+	setupTest(t, fmt.Sprintf("targets:\n  - name: home\n    url: %v\n    poll: true\n  - name: office\n    url: %v\n", server.URL, server.URL+"/"), "--fenecon.request.retries=0")
+
+	pt := addTestPollerTarget(t, "home")
+	poller.poll(context.Background(), pt)
+
+	tests := []struct {
+		name       string
+		params     url.Values
+		statusCode int
+
+		// served from background poll cache (no request to the device)
+		cached   bool
+		contains []string
+	}{
+		{
+			name:       "polled target served from cache",
+			params:     url.Values{"target": {"home"}},
+			statusCode: http.StatusOK,
+			cached:     true,
+			contains:   []string{`fenecon_grid_power{module="_sum",target="` + server.URL + `"} 1200`, "fenecon_last_update_timestamp_seconds"},
+		},
+		{
+			name:       "polled target by url served from cache",
+			params:     url.Values{"target": {server.URL}},
+			statusCode: http.StatusOK,
+			cached:     true,
+			contains:   []string{"fenecon_last_update_timestamp_seconds"},
+		},
+		{
+			name:       "module of polled target served from cache",
+			params:     url.Values{"target": {"home"}, "module": {"default"}},
+			statusCode: http.StatusOK,
+			cached:     true,
+		},
+		{
+			name:       "overridden transport is probed",
+			params:     url.Values{"target": {"home"}, "transport": {"rest"}},
+			statusCode: http.StatusOK,
+			contains:   []string{`fenecon_probe_success{target="` + server.URL + `"} 1`},
+		},
+		{
+			name:       "raw mode is probed",
+			params:     url.Values{"target": {"home"}, "mode": {"raw"}},
+			statusCode: http.StatusOK,
+			contains:   []string{"fenecon_channel_value"},
+		},
+		{
+			name:       "configured target is probed",
+			params:     url.Values{"target": {"office"}},
+			statusCode: http.StatusOK,
+			contains:   []string{`fenecon_probe_success{target="` + server.URL + `/"} 1`},
+		},
+		{
+			name:       "ad-hoc target is probed",
+			params:     url.Values{"target": {strings.Replace(server.URL, "127.0.0.1", "localhost", 1)}},
+			statusCode: http.StatusOK,
+			contains:   []string{"fenecon_probe_success{target=\"" + strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "\"} 1"},
+		},
+		{
+			name:       "missing target",
+			params:     url.Values{},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown module",
+			params:     url.Values{"target": {"home"}, "module": {"unknown"}},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "unsupported mode",
+			params:     url.Values{"target": {"home"}, "mode": {"unknown"}},
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := fake.receivedRequests()
+			response := probe(t, test.params)
+
+			if response.Code != test.statusCode {
+				t.Fatalf("expected status %v, got %v: %v", test.statusCode, response.Code, response.Body.String())
+			}
+			if test.statusCode != http.StatusOK {
+				return
+			}
+
+			if cached := fake.receivedRequests() == requests; cached != test.cached {
+				t.Errorf("expected served from cache %v, got %v", test.cached, cached)
+			}
+			for _, expected := range test.contains {
+				if !strings.Contains(response.Body.String(), expected) {
+					t.Errorf("expected %q in response:\n%v", expected, response.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestProbeFailedTarget(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.setFailing(true)
+	server := fake.start(t)
+	setupTest(t, "", "--fenecon.request.retries=0")
+
+	// failures are reported by the probe metrics, not by the http status
+	response := probe(t, url.Values{"target": {server.URL}})
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %v", response.Code)
+	}
+	if expected := `fenecon_probe_success{target="` + server.URL + `"} 0`; !strings.Contains(response.Body.String(), expected) {
+		t.Errorf("expected %q in response:\n%v", expected, response.Body.String())
+	}
+}
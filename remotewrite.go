@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+var (
+	remoteWriteFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fenecon_remote_write_failures_total",
+			Help: "Fenecon number of failed remote write requests",
+		},
+	)
+
+	remoteWriteDropped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fenecon_remote_write_dropped_total",
+			Help: "Fenecon number of dropped remote write requests (buffer full or not recoverable errors)",
+		},
+	)
+
+	remoteWriteBuffered = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fenecon_remote_write_buffered_requests",
+			Help: "Fenecon number of buffered remote write requests",
+		},
+	)
+
+	remoteWriteLastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fenecon_remote_write_last_success_timestamp_seconds",
+			Help: "Fenecon timestamp of last successful remote write request",
+		},
+	)
+)
+
+// initRemoteWrite starts pushing the cached metrics of background polled targets to the remote write endpoint,
+// write requests are buffered until they are sent (retried with backoff)
+func initRemoteWrite() {
+	if Opts.RemoteWrite.Url == "" {
+		return
+	}
+
+	prometheus.MustRegister(remoteWriteFailures, remoteWriteDropped, remoteWriteBuffered, remoteWriteLastSuccess)
+
+	if len(pollTargets()) == 0 {
+		logger.Warn(`remote write is enabled but no targets are polled in background (--poll.target or poll: true in config file)`)
+	}
+
+	buffer, err := fenecon.NewRemoteWriteBuffer(Opts.RemoteWrite.Buffer.Path, Opts.RemoteWrite.Buffer.MaxSize*1024*1024)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+	remoteWriteBuffered.Set(float64(buffer.Len()))
+
+	writer := fenecon.NewRemoteWriter(fenecon.RemoteWriteOpts{
+		Url:            Opts.RemoteWrite.Url,
+		Username:       Opts.RemoteWrite.Auth.Username,
+		Password:       Opts.RemoteWrite.Auth.Password,
+		BearerToken:    Opts.RemoteWrite.Auth.BearerToken,
+		ExternalLabels: Opts.RemoteWrite.Labels,
+		UserAgent:      UserAgent + gitTag,
+		Timeout:        Opts.RemoteWrite.Timeout,
+	})
+
+	logger.Info(`starting remote write`, slog.Duration("interval", Opts.RemoteWrite.Interval), slog.Int("buffered", buffer.Len()))
+
+	notify := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(Opts.RemoteWrite.Interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			bufferRemoteWrite(writer, buffer)
+
+			select {
+			case notify <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	go sendRemoteWrite(writer, buffer, notify)
+}
+
+// bufferRemoteWrite encodes the cached metrics of all background polled targets and appends them to the buffer
+func bufferRemoteWrite(writer *fenecon.RemoteWriter, buffer *fenecon.RemoteWriteBuffer) {
+	families, err := poller.GatherWithTimestamps()
+	if err != nil {
+		logger.Warn(`failed to gather metrics for remote write`, slog.Any("error", err))
+	}
+
+	data := writer.Encode(families, time.Now())
+	if data == nil {
+		return
+	}
+
+	dropped, err := buffer.Append(data)
+	if err != nil {
+		logger.Error(`failed to buffer remote write request`, slog.Any("error", err))
+	}
+	if dropped > 0 {
+		logger.Warn(`remote write buffer is full, dropped oldest requests`, slog.Int("dropped", dropped))
+		remoteWriteDropped.Add(float64(dropped))
+	}
+	remoteWriteBuffered.Set(float64(buffer.Len()))
+}
+
+// sendRemoteWrite sends the buffered write requests (oldest first), recoverable errors are retried with exponential backoff
+func sendRemoteWrite(writer *fenecon.RemoteWriter, buffer *fenecon.RemoteWriteBuffer, notify <-chan struct{}) {
+	backoff := Opts.RemoteWrite.Backoff.Min
+
+	for {
+		name, data, err := buffer.Oldest()
+		switch {
+		case name == "":
+			// buffer is empty, wait for next write request
+			<-notify
+			continue
+		case err != nil:
+			logger.Error(`failed to read buffered remote write request, dropping`, slog.Any("error", err))
+			dropRemoteWrite(buffer, name)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), Opts.RemoteWrite.Timeout)
+		err = writer.Write(ctx, data)
+		cancel()
+
+		switch {
+		case err == nil:
+			if err := buffer.Remove(name); err != nil {
+				logger.Error(`failed to remove remote write request from buffer`, slog.Any("error", err))
+			}
+			remoteWriteBuffered.Set(float64(buffer.Len()))
+			remoteWriteLastSuccess.SetToCurrentTime()
+			backoff = Opts.RemoteWrite.Backoff.Min
+		case errors.Is(err, fenecon.ErrRemoteWriteRecoverable):
+			logger.Warn(`remote write failed, retrying`, slog.Any("error", err), slog.Duration("backoff", backoff), slog.Int("buffered", buffer.Len()))
+			remoteWriteFailures.Inc()
+			time.Sleep(backoff)
+			backoff = min(backoff*2, Opts.RemoteWrite.Backoff.Max)
+		default:
+			logger.Error(`remote write failed with not recoverable error, dropping request`, slog.Any("error", err))
+			remoteWriteFailures.Inc()
+			dropRemoteWrite(buffer, name)
+		}
+	}
+}
+
+func dropRemoteWrite(buffer *fenecon.RemoteWriteBuffer, name string) {
+	if err := buffer.Remove(name); err != nil {
+		logger.Error(`failed to remove remote write request from buffer`, slog.Any("error", err))
+	}
+	remoteWriteDropped.Inc()
+	remoteWriteBuffered.Set(float64(buffer.Len()))
+}
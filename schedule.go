@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+type (
+	scheduleResponse struct {
+		Target    string                 `json:"target"`
+		Component string                 `json:"component"`
+		Schedule  []fenecon.ScheduleSlot `json:"schedule"`
+	}
+)
+
+// serveSchedule serves the quarter-hourly schedule of the time of use tariff controller as JSON (componentJsonApi getSchedule)
+func serveSchedule(w http.ResponseWriter, r *http.Request) {
+	contextLogger := buildContextLoggerFromRequest(r)
+
+	// param: target, module, transport
+	val, err := paramsGetRequired(r.URL.Query(), "target")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target, err := resolveProbeTarget(val, r.URL.Query().Get("module"), r.URL.Query().Get("transport"))
+	if err != nil {
+		contextLogger.Warn("failed to resolve target", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// param: component
+	component := r.URL.Query().Get("component")
+	if component == "" {
+		component = fenecon.DefaultScheduleComponent
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(DefaultTimeout)*time.Second)
+	defer cancel()
+
+	prober, err := newFeneconProber(ctx, prometheus.NewRegistry(), contextLogger, target.module)
+	if err != nil {
+		contextLogger.Warn("failed to setup prober", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := prober.Schedule(target.target, component)
+	if err != nil {
+		contextLogger.Error("failed to fetch schedule", slog.Any("error", err))
+
+		statusCode := http.StatusBadGateway
+		if errors.Is(err, context.DeadlineExceeded) {
+			statusCode = http.StatusGatewayTimeout
+		}
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(scheduleResponse{
+		Target:    target.name,
+		Component: component,
+		Schedule:  schedule.Schedule,
+	}); err != nil {
+		contextLogger.Error(`failed to write schedule`, slog.Any("error", err))
+	}
+}
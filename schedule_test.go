@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestServeSchedule(t *testing.T) {
+	fake := newFakeOpenEMS()
+	fake.schedules = map[string][]map[string]interface{}{
+		"ctrlEssTimeOfUseTariff0": {
+			{"timestamp": "2024-10-16T12:00:00Z", "price": 120.5, "state": 1, "soc": 50},
+			{"timestamp": "2024-10-16T12:15:00Z", "price": 250.1, "state": 3},
+		},
+		"ctrlEssTimeOfUseTariff1": {
+			{"timestamp": "2024-10-16T12:00:00Z", "price": 99.9},
+		},
+	}
+	server := fake.start(t)
+	setupTest(t, fmt.Sprintf("targets:\n  - name: home\n    url: %v\n", server.URL), "--fenecon.request.retries=0")
+
+	tests := []struct {
+		name       string
+		params     url.Values
+		statusCode int
+
+		target    string
+		component string
+		slots     int
+	}{
+		{name: "default component", params: url.Values{"target": {"home"}}, statusCode: http.StatusOK, target: "home", component: "ctrlEssTimeOfUseTariff0", slots: 2},
+		{name: "component", params: url.Values{"target": {"home"}, "component": {"ctrlEssTimeOfUseTariff1"}}, statusCode: http.StatusOK, target: "home", component: "ctrlEssTimeOfUseTariff1", slots: 1},
+		{name: "configured target by url", params: url.Values{"target": {server.URL}}, statusCode: http.StatusOK, target: "home", component: "ctrlEssTimeOfUseTariff0", slots: 2},
+		{name: "unknown component", params: url.Values{"target": {"home"}, "component": {"ctrlEssTimeOfUseTariff2"}}, statusCode: http.StatusBadGateway},
+		{name: "missing target", params: url.Values{}, statusCode: http.StatusBadRequest},
+		{name: "unknown module", params: url.Values{"target": {"home"}, "module": {"unknown"}}, statusCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			serveSchedule(recorder, httptest.NewRequest(http.MethodGet, "/schedule?"+test.params.Encode(), nil))
+
+			if recorder.Code != test.statusCode {
+				t.Fatalf("expected status %v, got %v: %v", test.statusCode, recorder.Code, recorder.Body.String())
+			}
+			if test.statusCode != http.StatusOK {
+				return
+			}
+
+			response := scheduleResponse{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Target != test.target || response.Component != test.component || len(response.Schedule) != test.slots {
+				t.Errorf("expected %v/%v with %v slots, got %+v", test.target, test.component, test.slots, response)
+			}
+		})
+	}
+
+	// state names are added to the slots
+	recorder := httptest.NewRecorder()
+	serveSchedule(recorder, httptest.NewRequest(http.MethodGet, "/schedule?target=home", nil))
+	response := scheduleResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Schedule) != 2 || response.Schedule[0].StateName != "DELAY_DISCHARGE" || response.Schedule[1].StateName != "CHARGE_GRID" {
+		t.Errorf("unexpected state names in %+v", response.Schedule)
+	}
+}
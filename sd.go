@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/webdevops/fenecon-exporter/config"
+)
+
+type (
+	// serviceDiscoveryTargetGroup is a target group of Prometheus http_sd_config
+	serviceDiscoveryTargetGroup struct {
+		Targets []string          `json:"targets"`
+		Labels  map[string]string `json:"labels"`
+	}
+)
+
+// serviceDiscovery serves all configured targets as Prometheus http_sd_config,
+// targets are the exporter itself (address of the request) with the probe parameters, so no relabeling is needed
+func serviceDiscovery(w http.ResponseWriter, r *http.Request) {
+	conf := getConfigFile()
+
+	ret := []serviceDiscoveryTargetGroup{}
+	for _, target := range conf.Targets {
+		module := target.Module
+		if module == "" {
+			module = config.DefaultModuleName
+		}
+
+		labels := map[string]string{
+			"__metrics_path__": "/probe",
+			"__param_target":   target.Name,
+			"__param_module":   module,
+			"site":             target.Name,
+			// label module is set on every series (component), a target label module would be renamed to exported_module
+			"probe_module": module,
+		}
+
+		// edge id is only known for background polled targets
+		if pt := poller.Target(target.Name); pt != nil {
+			if edgeInfo := pt.EdgeInfo(); edgeInfo != nil && edgeInfo.Id != "" {
+				labels["edge_id"] = edgeInfo.Id
+			}
+		}
+
+		ret = append(ret, serviceDiscoveryTargetGroup{
+			Targets: []string{r.Host},
+			Labels:  labels,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ret); err != nil {
+		logger.Error(`failed to write service discovery`, slog.Any("error", err))
+	}
+}
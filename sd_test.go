@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServiceDiscovery(t *testing.T) {
+	setupTest(t, `
+modules:
+  websocket:
+    transport: websocket
+targets:
+  - name: home
+    url: http://192.168.1.10
+  - name: office
+    url: http://192.168.2.10
+    module: websocket
+`)
+
+	request := httptest.NewRequest("GET", "http://fenecon-exporter:8080/sd", nil)
+	response := httptest.NewRecorder()
+	serviceDiscovery(response, request)
+
+	if contentType := response.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected content type application/json, got %q", contentType)
+	}
+
+	groups := []serviceDiscoveryTargetGroup{}
+	if err := json.Unmarshal(response.Body.Bytes(), &groups); err != nil {
+		t.Fatalf("invalid http_sd output: %v", err)
+	}
+
+	expected := []map[string]string{
+		{"__metrics_path__": "/probe", "__param_target": "home", "__param_module": "default", "site": "home", "probe_module": "default"},
+		{"__metrics_path__": "/probe", "__param_target": "office", "__param_module": "websocket", "site": "office", "probe_module": "websocket"},
+	}
+
+	if len(groups) != len(expected) {
+		t.Fatalf("expected %v target groups, got %v", len(expected), groups)
+	}
+
+	for i, group := range groups {
+		if len(group.Targets) != 1 || group.Targets[0] != "fenecon-exporter:8080" {
+			t.Errorf("group %v: expected exporter as target, got %v", i, group.Targets)
+		}
+
+		// module is a label of every series and must not be a target label
+		if _, exists := group.Labels["module"]; exists {
+			t.Errorf("group %v: unexpected target label module", i)
+		}
+
+		if !maps.Equal(group.Labels, expected[i]) {
+			t.Errorf("group %v: expected labels %v, got %v", i, expected[i], group.Labels)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/webdevops/fenecon-exporter/config"
+	"github.com/webdevops/fenecon-exporter/fenecon"
+)
+
+type (
+	// probeTarget is a target with the resolved module settings
+	probeTarget struct {
+		// name of configured target or url
+		name   string
+		target fenecon.FeneconProberTarget
+		module *config.Module
+
+		// module settings differ from the configured target (module or transport passed)
+		overridden bool
+
+		// target is in the config file (configured or discovered), other urls are probed ad-hoc
+		configured bool
+	}
+)
+
+// resolveProbeTarget resolves the target (name or url of configured target or any url) and the module settings,
+// empty module and transport use the settings of the configured target or the default module
+func resolveProbeTarget(target, module, transport string) (*probeTarget, error) {
+	conf := getConfigFile()
+
+	ret := probeTarget{
+		name:   target,
+		target: fenecon.FeneconProberTarget{Target: target},
+	}
+
+	ret.overridden = module != "" || transport != ""
+
+	configTarget := conf.Target(target)
+	if configTarget != nil {
+		ret.configured = true
+		ret.name = configTarget.Name
+		ret.target.Target = configTarget.Url
+
+		// module of the configured target is no override (eg. passed by service discovery)
+		if module == configTarget.Module || (module == config.DefaultModuleName && configTarget.Module == "") {
+			ret.overridden = transport != ""
+		}
+
+		if module == "" {
+			module = configTarget.Module
+		}
+	}
+
+	moduleConfig, err := conf.Module(module)
+	if err != nil {
+		return nil, err
+	}
+
+	// copy module, target overrides must not modify the module
+	moduleCopy := *moduleConfig
+	ret.module = &moduleCopy
+
+	if configTarget != nil {
+		if configTarget.Auth != nil {
+			ret.module.Auth = *configTarget.Auth
+		}
+
+		if configTarget.Transport != "" {
+			ret.module.Transport = configTarget.Transport
+		}
+	}
+
+	if transport != "" {
+		ret.module.Transport = transport
+	}
+
+	return &ret, nil
+}
+
+// pollTargets returns all targets which should be polled in background (cli and config file)
+func pollTargets() []string {
+	ret := slices.Clone(Opts.Poll.Targets)
+
+	for _, target := range getConfigFile().Targets {
+		if target.Poll && !slices.Contains(ret, target.Name) {
+			ret = append(ret, target.Name)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestResolveProbeTarget(t *testing.T) {
+	setupTest(t, `
+modules:
+  websocket:
+    transport: websocket
+    auth:
+      username: x
+      password: module
+targets:
+  - name: home
+    url: http://home
+    module: websocket
+    auth:
+      username: x
+      password: target
+  - name: office
+    url: http://office
+    transport: websocket
+`)
+
+	tests := []struct {
+		name      string
+		target    string
+		module    string
+		transport string
+		err       bool
+
+		resolvedName string
+		url          string
+		password     string
+		transportOf  string
+		overridden   bool
+		configured   bool
+	}{
+		{name: "configured target", target: "home", resolvedName: "home", url: "http://home", password: "target", transportOf: "websocket", configured: true},
+		{name: "configured target by url", target: "http://home", resolvedName: "home", url: "http://home", password: "target", transportOf: "websocket", configured: true},
+		{name: "module of configured target", target: "home", module: "websocket", resolvedName: "home", url: "http://home", password: "target", transportOf: "websocket", configured: true},
+		{name: "other module", target: "home", module: "default", resolvedName: "home", url: "http://home", password: "target", transportOf: "rest", overridden: true, configured: true},
+		{name: "other transport", target: "home", transport: "rest", resolvedName: "home", url: "http://home", password: "target", transportOf: "rest", overridden: true, configured: true},
+		{name: "target transport override", target: "office", resolvedName: "office", url: "http://office", password: "user", transportOf: "websocket", configured: true},
+		{name: "default module of configured target", target: "office", module: "default", resolvedName: "office", url: "http://office", password: "user", transportOf: "websocket", configured: true},
+		{name: "ad-hoc target", target: "http://garage", resolvedName: "http://garage", url: "http://garage", password: "user", transportOf: "rest"},
+		{name: "ad-hoc target with module", target: "http://garage", module: "websocket", resolvedName: "http://garage", url: "http://garage", password: "module", transportOf: "websocket", overridden: true},
+		{name: "unknown module", target: "home", module: "unknown", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target, err := resolveProbeTarget(test.target, test.module, test.transport)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if target.name != test.resolvedName || target.target.Target != test.url {
+				t.Errorf("expected %v (%v), got %v (%v)", test.resolvedName, test.url, target.name, target.target.Target)
+			}
+			if target.module.Auth.Password != test.password || target.module.Transport != test.transportOf {
+				t.Errorf("expected password %q and transport %v, got %q and %v", test.password, test.transportOf, target.module.Auth.Password, target.module.Transport)
+			}
+			if target.overridden != test.overridden || target.configured != test.configured {
+				t.Errorf("expected overridden %v and configured %v, got %v and %v", test.overridden, test.configured, target.overridden, target.configured)
+			}
+		})
+	}
+
+	// target overrides must not modify the module
+	if module, _ := getConfigFile().Module("websocket"); module.Auth.Password != "module" {
+		t.Errorf("expected module password to be unchanged, got %q", module.Auth.Password)
+	}
+	if module, _ := getConfigFile().Module("default"); module.Transport != "rest" {
+		t.Errorf("expected default module transport to be unchanged, got %v", module.Transport)
+	}
+}
+
+func TestPollTargets(t *testing.T) {
+	setupTest(t, `
+targets:
+  - name: home
+    url: http://home
+    poll: true
+  - name: office
+    url: http://office
+    poll: true
+  - name: garage
+    url: http://garage
+`, "--poll.target=home", "--poll.target=http://other")
+
+	// cli targets first, config file targets without duplicates
+	if targets := pollTargets(); !slices.Equal(targets, []string{"home", "http://other", "office"}) {
+		t.Errorf("unexpected poll targets %v", targets)
+	}
+}